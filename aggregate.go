@@ -0,0 +1,110 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+)
+
+// AggregateOptions selects and groups the documents aggregated by Aggregate
+type AggregateOptions struct {
+	// Filter selects the documents to aggregate, nil selects every live JSON document.
+	// It is called without any lock held, so it may use the store.
+	Filter func(key string, value json.RawMessage) bool
+	// GroupBy is the path of the field grouping the documents, empty aggregates them all together
+	GroupBy string
+}
+
+// AggregateResult holds the aggregates of a field over a group of documents, following N1QL:
+// null and missing values are ignored, SUM adds up the numbers only, and MIN and MAX compare values
+// in JSON collation order, where numbers are json.Number.
+type AggregateResult struct {
+	// Group is the value of the GroupBy field shared by the group, nil without GroupBy or when missing
+	Group interface{}
+	// Count is the number of documents in the group where the field is neither null nor missing
+	Count int
+	Sum   float64
+	Min   interface{}
+	Max   interface{}
+}
+
+// add folds the value of the aggregated field of a document into the result
+func (r *AggregateResult) add(v interface{}) {
+	if v == nil {
+		return
+	}
+	r.Count++
+	if n, ok := v.(json.Number); ok {
+		f, _ := n.Float64()
+		r.Sum += f
+	}
+	if r.Min == nil || jsonCompare(v, r.Min) < 0 {
+		r.Min = v
+	}
+	if r.Max == nil || jsonCompare(v, r.Max) > 0 {
+		r.Max = v
+	}
+}
+
+// Aggregate computes COUNT, SUM, MIN and MAX of the field at path across the documents selected by opts.
+// Without GroupBy it returns a single result, otherwise one result per distinct value of the GroupBy field
+// in JSON collation order, with documents missing that field grouped under nil.
+func (crud *CRUD) Aggregate(path string, opts AggregateOptions) ([]AggregateResult, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpQuery); err != nil {
+		return nil, err
+	}
+
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	var groupSegs []pathSegment
+	if opts.GroupBy != "" {
+		if groupSegs, err = parsePath(opts.GroupBy); err != nil {
+			return nil, err
+		}
+	}
+
+	// groups are found by the canonical encoding of their value
+	groups := make(map[string]*AggregateResult)
+	if groupSegs == nil {
+		groups[canonicalJSON(nil)] = &AggregateResult{}
+	}
+	for _, doc := range crud.scan(nil) {
+		if opts.Filter != nil && !opts.Filter(doc.Key, doc.Value) {
+			continue
+		}
+		root, err := decodeJSON(doc.Value)
+		if err != nil {
+			continue
+		}
+
+		var group interface{}
+		if groupSegs != nil {
+			group, _ = getPath(root, groupSegs)
+		}
+		id := canonicalJSON(group)
+		res, ok := groups[id]
+		if !ok {
+			res = &AggregateResult{Group: group}
+			groups[id] = res
+		}
+		if v, ok := getPath(root, segs); ok {
+			res.add(v)
+		}
+	}
+
+	results := make([]AggregateResult, 0, len(groups))
+	for _, res := range groups {
+		results = append(results, *res)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return jsonCompare(results[i].Group, results[j].Group) < 0
+	})
+	return results, nil
+}
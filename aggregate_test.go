@@ -0,0 +1,53 @@
+package crud
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	client := New()
+	seedQuery(client)
+
+	act, err := client.Aggregate("age", AggregateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []AggregateResult{{Count: 3, Sum: 90, Min: json.Number("25"), Max: json.Number("35")}}
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatal("results mismatch")
+	}
+
+	act, _ = client.Aggregate("age", AggregateOptions{GroupBy: "city"})
+	exp = []AggregateResult{
+		{Group: "berlin"},
+		{Group: "london", Count: 1, Sum: 25, Min: json.Number("25"), Max: json.Number("25")},
+		{Group: "paris", Count: 2, Sum: 65, Min: json.Number("30"), Max: json.Number("35")},
+	}
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatal("results mismatch")
+	}
+
+	// string fields have a count, min and max but no sum
+	act, _ = client.Aggregate("name", AggregateOptions{
+		Filter: func(key string, value json.RawMessage) bool {
+			return strings.Contains(string(value), "paris")
+		},
+	})
+	exp = []AggregateResult{{Count: 2, Min: "alice", Max: "carol"}}
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatal("results mismatch")
+	}
+
+	// documents missing the group field are grouped under nil
+	act, _ = client.Aggregate("age", AggregateOptions{GroupBy: "nick"})
+	if len(act) != 1 || act[0].Group != nil || act[0].Count != 3 {
+		t.Fatal("results mismatch")
+	}
+
+	if _, err := client.Aggregate("a..b", AggregateOptions{}); !reflect.DeepEqual(err, ErrInvalidPath) {
+		t.Fatal("error mismatch")
+	}
+}
@@ -0,0 +1,184 @@
+package crud
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditOp describes the kind of operation recorded by an AuditEntry
+type AuditOp int
+
+const (
+	// AuditRead is recorded when a document is read
+	AuditRead AuditOp = iota + 1
+	// AuditInsert is recorded when a new document is stored
+	AuditInsert
+	// AuditUpdate is recorded when an existing document's value or expiry changes
+	AuditUpdate
+	// AuditRemove is recorded when a document is removed
+	AuditRemove
+	// AuditExpire is recorded when an expired document is purged
+	AuditExpire
+	// AuditLock is recorded when a document is locked by GetAndLock, after its read
+	AuditLock
+	// AuditEvict is recorded when a document is evicted to keep the store within its memory limit
+	AuditEvict
+)
+
+func (o AuditOp) String() string {
+	switch o {
+	case AuditRead:
+		return "read"
+	case AuditInsert:
+		return "insert"
+	case AuditUpdate:
+		return "update"
+	case AuditRemove:
+		return "remove"
+	case AuditExpire:
+		return "expire"
+	case AuditLock:
+		return "lock"
+	case AuditEvict:
+		return "evict"
+	}
+	return "unknown"
+}
+
+// auditOps maps the mutations published to watchers to the operations recorded in the audit log
+var auditOps = map[EventType]AuditOp{
+	EventInsert: AuditInsert,
+	EventUpdate: AuditUpdate,
+	EventRemove: AuditRemove,
+	EventExpire: AuditExpire,
+	EventEvict:  AuditEvict,
+}
+
+// AuditEntry records a single operation applied to the store
+type AuditEntry struct {
+	Op  AuditOp
+	Key string
+	// CasBefore is the CAS of the document before the operation, zero when it didn't exist
+	CasBefore uint64
+	// CasAfter is the CAS of the document after the operation, zero when it no longer exists
+	CasAfter uint64
+	Time     time.Time
+	// Label is the label of the handle which made the operation, see WithAuditLabel
+	Label string
+}
+
+// AuditFilter selects entries of the audit log. Zero fields match every entry.
+type AuditFilter struct {
+	// Ops restricts the entries to these operations
+	Ops []AuditOp
+	// KeyPrefix restricts the entries to keys starting with it
+	KeyPrefix string
+	// Label restricts the entries to those made by handles with this label
+	Label string
+	// Since and Until restrict the entries to those made at or after Since and before Until
+	Since time.Time
+	Until time.Time
+}
+
+// match reports whether e is selected by the filter
+func (f AuditFilter) match(e AuditEntry) bool {
+	if len(f.Ops) > 0 {
+		found := false
+		for _, op := range f.Ops {
+			found = found || op == e.Op
+		}
+		if !found {
+			return false
+		}
+	}
+	if !strings.HasPrefix(e.Key, f.KeyPrefix) || (f.Label != "" && e.Label != f.Label) {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	return f.Until.IsZero() || e.Time.Before(f.Until)
+}
+
+// WithAuditLog records every mutation and every document read by Get and its variants in an audit log
+// returned by AuditLog, keeping the latest limit entries. A limit of zero keeps every entry.
+// Failed operations, scans and queries aren't recorded.
+func WithAuditLog(limit int) Option {
+	return func(crud *CRUD) {
+		crud.audit = &auditLog{limit: limit}
+	}
+}
+
+// WithAuditLabel labels the entries of the audit log recorded for operations made through the handle,
+// so that the operations of different callers can be told apart
+func WithAuditLabel(label string) ClientOption {
+	return func(crud *CRUD) {
+		crud.label = label
+	}
+}
+
+// auditLog holds the recorded entries, oldest first
+type auditLog struct {
+	mu      sync.Mutex
+	limit   int
+	entries []AuditEntry
+}
+
+func (a *auditLog) record(e AuditEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.limit > 0 && len(a.entries) >= a.limit {
+		a.entries = append(a.entries[:0:0], a.entries[len(a.entries)-a.limit+1:]...)
+	}
+	a.entries = append(a.entries, e)
+}
+
+// filter returns the entries selected by f
+func (a *auditLog) filter(f AuditFilter) []AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var out []AuditEntry
+	for _, e := range a.entries {
+		if f.match(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (a *auditLog) clear() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = nil
+}
+
+// recordAudit adds an entry to the audit log when it is enabled
+func (crud *CRUD) recordAudit(op AuditOp, key string, casBefore, casAfter uint64) {
+	if crud.audit == nil {
+		return
+	}
+	crud.audit.record(AuditEntry{
+		Op:        op,
+		Key:       key,
+		CasBefore: casBefore,
+		CasAfter:  casAfter,
+		Time:      crud.now(),
+		Label:     crud.label,
+	})
+}
+
+// AuditLog returns the entries of the audit log, oldest first.
+// AuditLog returns nil unless audit logging is enabled.
+func (crud *CRUD) AuditLog() []AuditEntry {
+	return crud.FilterAuditLog(AuditFilter{})
+}
+
+// FilterAuditLog returns the entries of the audit log selected by f, oldest first.
+// FilterAuditLog returns nil unless audit logging is enabled.
+func (crud *CRUD) FilterAuditLog(f AuditFilter) []AuditEntry {
+	if crud.audit == nil {
+		return nil
+	}
+	return crud.audit.filter(f)
+}
@@ -0,0 +1,55 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	clock := newManualClock()
+	client := New(WithClock(clock), WithAuditLog(0))
+	svc := client.Client(WithAuditLabel("svc"))
+
+	cas, _ := svc.Insert("user::1", "val", 0)
+	clock.add(time.Minute)
+	_, _ = client.Get("user::1", new(string))
+	locked, _ := svc.GetAndLock("user::1", 5, new(string))
+	_, _ = svc.Remove("user::1", locked)
+	// failed operations aren't recorded
+	_, _ = svc.Remove("user::1", locked)
+	_, _ = client.Insert("order::1", "val", 0)
+
+	exp := []AuditEntry{
+		{Op: AuditInsert, Key: "user::1", CasBefore: 0, CasAfter: cas, Time: clock.Now().Add(-time.Minute), Label: "svc"},
+		{Op: AuditRead, Key: "user::1", CasBefore: 1, CasAfter: 1, Time: clock.Now()},
+		{Op: AuditRead, Key: "user::1", CasBefore: 1, CasAfter: 1, Time: clock.Now(), Label: "svc"},
+		{Op: AuditLock, Key: "user::1", CasBefore: 1, CasAfter: 2, Time: clock.Now(), Label: "svc"},
+		{Op: AuditRemove, Key: "user::1", CasBefore: 2, CasAfter: 0, Time: clock.Now(), Label: "svc"},
+		{Op: AuditInsert, Key: "order::1", CasBefore: 0, CasAfter: 1, Time: clock.Now()},
+	}
+	if !reflect.DeepEqual(client.AuditLog(), exp) {
+		t.Fatal("audit log mismatch")
+	}
+
+	f := AuditFilter{Ops: []AuditOp{AuditInsert, AuditRemove}, KeyPrefix: "user::", Label: "svc"}
+	if entries := client.FilterAuditLog(f); !reflect.DeepEqual(entries, []AuditEntry{exp[0], exp[4]}) {
+		t.Fatal("audit log mismatch")
+	}
+	if entries := client.FilterAuditLog(AuditFilter{Since: clock.Now()}); len(entries) != 5 {
+		t.Fatal("audit log mismatch")
+	}
+
+	// the latest entries are kept
+	client = New(WithAuditLog(2))
+	for _, key := range []string{"a", "b", "c"} {
+		_, _ = client.Insert(key, "val", 0)
+	}
+	if entries := client.AuditLog(); len(entries) != 2 || entries[0].Key != "b" {
+		t.Fatal("audit log mismatch")
+	}
+
+	if New().AuditLog() != nil {
+		t.Fatal("audit log mismatch")
+	}
+}
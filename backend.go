@@ -0,0 +1,317 @@
+package crud
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrCorruptRecord defines the error value returned when a Record can't be decoded
+var ErrCorruptRecord = errors.New("corrupt record")
+
+// Record is a document as persisted by a Backend
+type Record struct {
+	Key   string
+	Value []byte
+	Cas   uint64
+	// Expiry, Created and Updated are Unix times in nanoseconds, Expiry is zero when the document never expires
+	Expiry  int64
+	Created int64
+	Updated int64
+}
+
+// recordHeaderSize is the length of the fixed fields preceding the value of an encoded Record
+const recordHeaderSize = 32
+
+// MarshalBinary encodes the CAS, expiry, created and updated times of r as big endian integers followed by
+// its value, for backends storing records as bytes under their key. The key isn't encoded.
+func (r Record) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, recordHeaderSize, recordHeaderSize+len(r.Value))
+	binary.BigEndian.PutUint64(buf[0:], r.Cas)
+	binary.BigEndian.PutUint64(buf[8:], uint64(r.Expiry))
+	binary.BigEndian.PutUint64(buf[16:], uint64(r.Created))
+	binary.BigEndian.PutUint64(buf[24:], uint64(r.Updated))
+	return append(buf, r.Value...), nil
+}
+
+// UnmarshalBinary decodes data encoded by MarshalBinary into r, leaving its key unchanged.
+// The value is copied, so data may be reused.
+func (r *Record) UnmarshalBinary(data []byte) error {
+	if len(data) < recordHeaderSize {
+		return ErrCorruptRecord
+	}
+	r.Cas = binary.BigEndian.Uint64(data[0:])
+	r.Expiry = int64(binary.BigEndian.Uint64(data[8:]))
+	r.Created = int64(binary.BigEndian.Uint64(data[16:]))
+	r.Updated = int64(binary.BigEndian.Uint64(data[24:]))
+	r.Value = append([]byte{}, data[recordHeaderSize:]...)
+	return nil
+}
+
+// Backend persists the documents of a store, see WithBackend, so that they survive process restarts.
+// The store calls its methods one at a time.
+type Backend interface {
+	// Records calls fn with every persisted record, stopping at and returning the first error returned by fn
+	Records(fn func(Record) error) error
+	// Put persists r, replacing the record with the same key
+	Put(r Record) error
+	// Delete removes the record of key, if any
+	Delete(key string) error
+	// Clear removes every record
+	Clear() error
+	// Close releases the resources of the backend
+	Close() error
+}
+
+// RecordReader is implemented by Backends which can read the record of a single key. Given a memory limit,
+// a store whose Backend is a RecordReader keeps only some values in memory and reads the others from the
+// backend, see WithMemoryLimit.
+type RecordReader interface {
+	// Get returns the record of key, false when there is none
+	Get(key string) (Record, bool, error)
+}
+
+// errBackendClosed is returned reading a value ejected to a backend once CloseBackend was called
+var errBackendClosed = errors.New("backend closed")
+
+// backendState forwards mutations to a Backend, stopping at the first error
+type backendState struct {
+	mu     sync.Mutex
+	b      Backend
+	reader RecordReader
+	err    error
+	closed bool
+	// failure holds the first error met loading or persisting documents, which every later operation returns
+	failure atomic.Pointer[error]
+}
+
+func (s *backendState) do(op func(b Backend) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.err != nil {
+		return
+	}
+	if err := op(s.b); err != nil {
+		s.fail(err)
+	}
+}
+
+// fail records err as the error of the backend, with mu held or before the store is shared
+func (s *backendState) fail(err error) {
+	s.err = err
+	s.failure.Store(&err)
+}
+
+// get reads the record of key, an error reading it is recorded like one persisting a mutation
+func (s *backendState) get(key string) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return Record{}, false, errBackendClosed
+	}
+	r, ok, err := s.reader.Get(key)
+	if err != nil && s.err == nil {
+		s.fail(err)
+	}
+	return r, ok, err
+}
+
+// intact reports whether the backend holds every mutation applied to the store
+func (s *backendState) intact() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.closed && s.err == nil
+}
+
+// failed returns the error met loading or persisting documents, nil while there is none
+func (s *backendState) failed() error {
+	if err := s.failure.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// WithBackend persists every mutation applied to the store to b before the mutation returns, and loads
+// the documents persisted by b when the store is created. Documents are still held in memory, b makes
+// them outlive the process. Documents which expired while the store was closed are removed from b.
+//
+// Persisting stops at the first error returned by b. The mutation which met it stays applied in memory,
+// but every later operation of the store returns the error, as does CloseBackend, so that documents
+// aren't silently lost. Likewise when loading the documents fails, NewWithBackend returns the error and the
+// operations of a store created with WithBackend return it, so that it doesn't pass for an empty store.
+// Locks aren't persisted. Without a backend, documents only live in memory.
+// When b is a RecordReader and the store has a memory limit, values past the limit are left to b rather
+// than held in memory, see WithMemoryLimit.
+func WithBackend(b Backend) Option {
+	return func(crud *CRUD) {
+		crud.backend = &backendState{b: b}
+		crud.backend.reader, _ = b.(RecordReader)
+	}
+}
+
+// NewWithBackend creates a crud database with opts, persisting its documents to b, see WithBackend.
+// It returns the error met loading the documents persisted by b, if any.
+func NewWithBackend(b Backend, opts ...Option) (*CRUD, error) {
+	crud := New(append(opts, WithBackend(b))...)
+	if err := crud.backend.failed(); err != nil {
+		return nil, err
+	}
+	return crud, nil
+}
+
+// loadBackend restores the documents persisted by the backend. It is called by New once every option is applied.
+func (crud *CRUD) loadBackend() error {
+	if crud.tiered() {
+		return crud.loadTiered()
+	}
+	b := crud.backend.b
+	var saved []snapshotDoc
+	err := b.Records(func(r Record) error {
+		saved = append(saved, snapshotDoc{
+			Key:     r.Key,
+			Binary:  r.Value,
+			Cas:     r.Cas,
+			Expiry:  r.Expiry,
+			Created: r.Created,
+			Updated: r.Updated,
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the restored documents are already persisted
+	state := crud.backend
+	crud.backend = nil
+	defer func() {
+		crud.backend = state
+	}()
+	if err := crud.restore(saved); err != nil {
+		return fmt.Errorf("restoring backend records: %w", err)
+	}
+	now := crud.getTime()
+	for _, sd := range saved {
+		if sd.Expiry > 0 && sd.Expiry <= now {
+			if err := b.Delete(decodeKey(sd.Key, sd.KeyBase64)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadTiered restores the documents persisted by the backend, holding their values in memory up to the
+// memory limit and leaving the others to the backend
+func (crud *CRUD) loadTiered() error {
+	b := crud.backend.b
+	now := crud.getTime()
+	docs := make(map[string]*document)
+	var expired []string
+	var resident int64
+	err := b.Records(func(r Record) error {
+		if r.Expiry > 0 && r.Expiry <= now {
+			expired = append(expired, r.Key)
+			return nil
+		}
+		value := r.Value
+		if value == nil {
+			value = []byte{}
+		}
+		doc := &document{
+			Cas:      r.Cas,
+			TTL:      r.Expiry,
+			Value:    value,
+			Datatype: detectDatatype(value),
+			Checksum: checksum(value),
+			Created:  r.Created,
+			Updated:  r.Updated,
+		}
+		resident += int64(len(r.Key))
+		if resident+int64(len(value)) <= crud.eviction.limit {
+			resident += int64(len(value))
+		} else {
+			doc.Value = nil
+			doc.ejected = &ejectedValue{backend: crud.backend, key: r.Key, cas: r.Cas, size: len(value)}
+		}
+		docs[r.Key] = doc
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the restored documents are already persisted
+	unlock := crud.lockAll()
+	state := crud.backend
+	crud.backend = nil
+	crud.replaceDocs(docs)
+	crud.backend = state
+	unlock()
+	// the keys loaded after a value was kept may take the store over the limit
+	crud.evict()
+	for _, key := range expired {
+		if err := b.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CloseBackend stops persisting mutations and closes the backend given to WithBackend,
+// returning the first error met persisting a mutation or closing the backend.
+// It does nothing for stores without a backend.
+func (crud *CRUD) CloseBackend() error {
+	if crud.backend == nil {
+		return nil
+	}
+	crud.backend.mu.Lock()
+	defer crud.backend.mu.Unlock()
+	if crud.backend.closed {
+		return crud.backend.err
+	}
+	crud.backend.closed = true
+	if err := crud.backend.b.Close(); crud.backend.err == nil {
+		crud.backend.err = err
+	}
+	return crud.backend.err
+}
+
+// persistPut writes the document stored under key to the write-ahead log and the backend, when there are
+func (crud *CRUD) persistPut(key string, doc *document) {
+	if crud.wal != nil {
+		crud.wal.append(walEntry{Op: walPut, snapshotDoc: snapshotOf(key, doc)})
+	}
+	if crud.backend != nil {
+		r := Record{Key: key, Value: doc.value(), Cas: doc.Cas, Expiry: doc.TTL, Created: doc.Created, Updated: doc.Updated}
+		crud.backend.do(func(b Backend) error {
+			return b.Put(r)
+		})
+	}
+}
+
+// persistDelete records the removal of key in the write-ahead log and the backend, when there are
+func (crud *CRUD) persistDelete(key string) {
+	if crud.wal != nil {
+		e := walEntry{Op: walDel}
+		e.Key, e.KeyBase64 = encodeKey(key)
+		crud.wal.append(e)
+	}
+	if crud.backend != nil {
+		crud.backend.do(func(b Backend) error {
+			return b.Delete(key)
+		})
+	}
+}
+
+// persistClear records the removal of every document in the write-ahead log and the backend, when there are
+func (crud *CRUD) persistClear() {
+	if crud.wal != nil {
+		crud.wal.append(walEntry{Op: walFlush})
+	}
+	if crud.backend != nil {
+		crud.backend.do(Backend.Clear)
+	}
+}
@@ -0,0 +1,163 @@
+package crud
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// memBackend is a Backend holding records in a map
+type memBackend struct {
+	records map[string]Record
+	fail    error
+}
+
+func newMemBackend() *memBackend {
+	return &memBackend{records: make(map[string]Record)}
+}
+
+func (b *memBackend) Records(fn func(Record) error) error {
+	if b.fail != nil {
+		return b.fail
+	}
+	for _, r := range b.records {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *memBackend) Put(r Record) error {
+	b.records[r.Key] = r
+	return b.fail
+}
+
+func (b *memBackend) Delete(key string) error {
+	delete(b.records, key)
+	return b.fail
+}
+
+func (b *memBackend) Clear() error {
+	b.records = make(map[string]Record)
+	return b.fail
+}
+
+func (b *memBackend) Close() error {
+	return nil
+}
+
+func (b *memBackend) keys() []string {
+	keys := []string{}
+	for key := range b.records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestBackend(t *testing.T) {
+	clock := newManualClock()
+	b := newMemBackend()
+	client, err := NewWithBackend(b, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cas, _ := client.Insert("a", 1, 0)
+	_, _ = client.Replace("a", 2, cas, 0)
+	_, _ = client.InsertRaw("bin", []byte{0xff}, 0)
+	_, _ = client.Insert("ttl", 1, 10)
+	_, _ = client.Insert("removed", 1, 0)
+	_, _ = client.Remove("removed", 1)
+	if keys := b.keys(); !reflect.DeepEqual(keys, []string{"a", "bin", "ttl"}) {
+		t.Fatal("keys mismatch")
+	}
+	if r := b.records["a"]; string(r.Value) != "2" || r.Cas != 2 {
+		t.Fatal("record mismatch")
+	}
+	_ = client.CloseBackend()
+
+	// documents which expired while the store was closed are removed from the backend
+	clock.add(time.Minute)
+	restored, err := NewWithBackend(b, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := restored.Keys(); !reflect.DeepEqual(keys, []string{"a", "bin"}) {
+		t.Fatal("keys mismatch")
+	}
+	if keys := b.keys(); !reflect.DeepEqual(keys, []string{"a", "bin"}) {
+		t.Fatal("backend keys mismatch")
+	}
+	if meta, _ := restored.GetMeta("bin"); meta.Datatype != DatatypeBinary || meta.Cas != 1 {
+		t.Fatal("meta mismatch")
+	}
+
+	_ = restored.Flush()
+	if len(b.records) != 0 {
+		t.Fatal("flush mismatch")
+	}
+
+	// persisting stops at the first error, which later operations return
+	b.fail = errors.New("disk full")
+	_, _ = restored.Insert("x", 1, 0)
+	if _, err := restored.Insert("y", 1, 0); err != b.fail {
+		t.Fatal("error mismatch")
+	}
+	if _, err := restored.Get("x", new(int)); err != b.fail {
+		t.Fatal("error mismatch")
+	}
+	if err := restored.CloseBackend(); err != b.fail || len(b.records) != 1 {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestWithBackend(t *testing.T) {
+	b := newMemBackend()
+	_ = b.Put(Record{Key: "a", Value: []byte(`1`), Cas: 5})
+	_ = b.Put(Record{Key: "b", Value: []byte(`2`), Cas: 6})
+
+	client := NewSharded(4, WithBackend(b))
+	if keys := client.Keys(); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Fatal("keys mismatch")
+	}
+	if cas, _ := client.Upsert("a", 3, 0); cas != 6 || string(b.records["a"].Value) != "3" {
+		t.Fatal("record mismatch")
+	}
+}
+
+func TestWithBackendLoadError(t *testing.T) {
+	b := newMemBackend()
+	_ = b.Put(Record{Key: "a", Value: []byte(`1`), Cas: 5})
+	b.fail = errors.New("corrupt")
+
+	if _, err := NewWithBackend(b); err != b.fail {
+		t.Fatal("error mismatch")
+	}
+
+	// the store doesn't pass for an empty one
+	client := New(WithBackend(b))
+	if _, err := client.Get("a", new(int)); err != b.fail {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.Insert("b", 1, 0); err != b.fail {
+		t.Fatal("error mismatch")
+	}
+	if err := client.CloseBackend(); err != b.fail {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestRecordBinary(t *testing.T) {
+	r := Record{Key: "a", Value: []byte(`{"a":1}`), Cas: 1<<64 - 1, Expiry: 3, Created: 1, Updated: 2}
+	data, _ := r.MarshalBinary()
+	act := Record{Key: "a"}
+	if err := act.UnmarshalBinary(data); err != nil || !reflect.DeepEqual(act, r) {
+		t.Fatal("record mismatch")
+	}
+	if err := act.UnmarshalBinary(data[:8]); !errors.Is(err, ErrCorruptRecord) {
+		t.Fatal("error mismatch")
+	}
+}
@@ -0,0 +1,106 @@
+// Package badgerstore provides a crud.Backend persisting documents in a BadgerDB database:
+//
+//	b, err := badgerstore.Open(badger.DefaultOptions("crud-data"))
+//	...
+//	store := crud.New(crud.WithBackend(b))
+//
+// The backend is a crud.RecordReader, so that fixtures larger than memory can be served: given a memory
+// limit, the store holds the values it reads most in memory and reads the others from Badger:
+//
+//	store := crud.New(crud.WithBackend(b), crud.WithMemoryLimit(64<<20))
+//
+// Keys and document metadata are always held in memory.
+package badgerstore
+
+import (
+	"errors"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/jacygao/crud"
+)
+
+// Backend persists documents in a Badger database
+type Backend struct {
+	db *badger.DB
+}
+
+// Open opens the Badger database configured by opts, see badger.DefaultOptions
+func Open(opts badger.Options) (*Backend, error) {
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{db: db}, nil
+}
+
+// Records calls fn with every persisted record, in key order
+func (b *Backend) Records(fn func(crud.Record) error) error {
+	return b.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			r := crud.Record{Key: string(item.Key())}
+			// the value is only valid within Value, UnmarshalBinary copies it
+			err := item.Value(func(v []byte) error {
+				return r.UnmarshalBinary(v)
+			})
+			if err != nil {
+				return err
+			}
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get returns the record of key, false when there is none
+func (b *Backend) Get(key string) (crud.Record, bool, error) {
+	r := crud.Record{Key: key}
+	err := b.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(v []byte) error {
+			return r.UnmarshalBinary(v)
+		})
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return crud.Record{}, false, nil
+	}
+	if err != nil {
+		return crud.Record{}, false, err
+	}
+	return r, true, nil
+}
+
+// Put persists r
+func (b *Backend) Put(r crud.Record) error {
+	data, err := r.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(r.Key), data)
+	})
+}
+
+// Delete removes the record of key
+func (b *Backend) Delete(key string) error {
+	return b.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(key))
+	})
+}
+
+// Clear removes every record
+func (b *Backend) Clear() error {
+	return b.db.DropAll()
+}
+
+// Close closes the database
+func (b *Backend) Close() error {
+	return b.db.Close()
+}
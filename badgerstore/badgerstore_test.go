@@ -0,0 +1,113 @@
+package badgerstore
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/jacygao/crud"
+)
+
+func TestBackend(t *testing.T) {
+	dir := t.TempDir()
+	open := func() *crud.CRUD {
+		b, err := Open(badger.DefaultOptions(dir).WithLogger(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		store, err := crud.NewWithBackend(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}
+
+	store := open()
+	cas, _ := store.Insert("a", map[string]string{"name": "alice"}, 0)
+	cas, _ = store.Replace("a", map[string]string{"name": "alicia"}, cas, 0)
+	_, _ = store.InsertRaw("bin", []byte{0xff, 0x00}, 0)
+	_, _ = store.Insert("gone", 1, 0)
+	_, _ = store.Remove("gone", 1)
+	if err := store.CloseBackend(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the documents survive reopening the database
+	store = open()
+	if keys := store.Keys(); !reflect.DeepEqual(keys, []string{"a", "bin"}) {
+		t.Fatal("keys mismatch")
+	}
+	var act map[string]string
+	if got, err := store.Get("a", &act); err != nil || got != cas || act["name"] != "alicia" {
+		t.Fatal("value mismatch")
+	}
+	if raw, _, _ := store.GetRaw("bin"); !reflect.DeepEqual(raw, []byte{0xff, 0x00}) {
+		t.Fatal("value mismatch")
+	}
+
+	_ = store.Flush()
+	_ = store.CloseBackend()
+	store = open()
+	defer store.CloseBackend()
+	if store.Count() != 0 {
+		t.Fatal("count mismatch")
+	}
+}
+
+func TestBackendMemoryLimit(t *testing.T) {
+	dir := t.TempDir()
+	open := func() *crud.CRUD {
+		b, err := Open(badger.DefaultOptions(dir).WithLogger(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		store, err := crud.NewWithBackend(b, crud.WithMemoryLimit(200))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}
+	check := func(store *crud.CRUD) {
+		t.Helper()
+		for i := 0; i < 50; i++ {
+			var act string
+			if _, err := store.Get(fmt.Sprintf("k%02d", i), &act); err != nil || act != fmt.Sprintf("value-%02d", i) {
+				t.Fatal("value mismatch")
+			}
+		}
+		// the 50 keys take 150 bytes, leaving room for 5 values of 10 bytes
+		stats, _ := store.Stats()
+		if stats.Documents != 50 || stats.KeyBytes+stats.StoredValueBytes > 200 || stats.ValueBytes != 500 {
+			t.Fatalf("unexpected stats %+v", stats)
+		}
+	}
+
+	store := open()
+	for i := 0; i < 50; i++ {
+		_, _ = store.Insert(fmt.Sprintf("k%02d", i), fmt.Sprintf("value-%02d", i), 0)
+	}
+	check(store)
+	if err := store.CloseBackend(); err != nil {
+		t.Fatal(err)
+	}
+
+	store = open()
+	defer store.CloseBackend()
+	check(store)
+}
+
+func TestBackendGet(t *testing.T) {
+	b, err := Open(badger.DefaultOptions(t.TempDir()).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	_ = b.Put(crud.Record{Key: "a", Value: []byte("1"), Cas: 3})
+	if r, ok, err := b.Get("a"); err != nil || !ok || !reflect.DeepEqual(r, crud.Record{Key: "a", Value: []byte("1"), Cas: 3}) {
+		t.Fatal("record mismatch")
+	}
+	if _, ok, err := b.Get("missing"); err != nil || ok {
+		t.Fatal("record mismatch")
+	}
+}
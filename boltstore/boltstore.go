@@ -0,0 +1,86 @@
+// Package boltstore provides a crud.Backend persisting documents in a bbolt database file,
+// so that a crud store survives process restarts:
+//
+//	b, err := boltstore.Open("crud.db", nil)
+//	...
+//	store, err := crud.NewWithBackend(b)
+package boltstore
+
+import (
+	"github.com/jacygao/crud"
+	bolt "go.etcd.io/bbolt"
+)
+
+// bucket holds the records, keyed by document key
+var bucket = []byte("documents")
+
+// Backend persists documents in a bbolt database
+type Backend struct {
+	db *bolt.DB
+}
+
+// Open opens, creating it when it doesn't exist, the bbolt database at path.
+// opts may be nil, see bolt.Open.
+func Open(path string, opts *bolt.Options) (*Backend, error) {
+	db, err := bolt.Open(path, 0o600, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Backend{db: db}, nil
+}
+
+// Records calls fn with every persisted record, in key order
+func (b *Backend) Records(fn func(crud.Record) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+			// v is only valid during the transaction, UnmarshalBinary copies it
+			r := crud.Record{Key: string(k)}
+			if err := r.UnmarshalBinary(v); err != nil {
+				return err
+			}
+			return fn(r)
+		})
+	})
+}
+
+// Put persists r
+func (b *Backend) Put(r crud.Record) error {
+	data, err := r.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(r.Key), data)
+	})
+}
+
+// Delete removes the record of key
+func (b *Backend) Delete(key string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete([]byte(key))
+	})
+}
+
+// Clear removes every record
+func (b *Backend) Clear() error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(bucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(bucket)
+		return err
+	})
+}
+
+// Close closes the database
+func (b *Backend) Close() error {
+	return b.db.Close()
+}
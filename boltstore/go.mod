@@ -0,0 +1,15 @@
+module github.com/jacygao/crud/boltstore
+
+go 1.23
+
+require (
+	github.com/jacygao/crud v0.0.0-20261016124950-16962ca287be
+	go.etcd.io/bbolt v1.4.3
+)
+
+require (
+	github.com/google/btree v1.1.3 // indirect
+	github.com/klauspost/compress v1.17.11 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
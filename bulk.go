@@ -0,0 +1,68 @@
+package crud
+
+// MultiResult is the outcome for a single key of a bulk operation
+type MultiResult struct {
+	Key string
+	Cas uint64
+	Err error
+
+	value      []byte
+	transcoder Transcoder
+}
+
+// Content decodes the value retrieved by GetMulti into valuePtr. It returns the result's error, if any.
+func (r MultiResult) Content(valuePtr interface{}) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	return r.transcoder.Unmarshal(r.value, valuePtr)
+}
+
+// MultiDoc is a document written by UpsertMulti
+type MultiDoc struct {
+	Key    string
+	Value  interface{}
+	Expiry uint32
+}
+
+// MultiRemove identifies a document removed by RemoveMulti
+type MultiRemove struct {
+	Key string
+	Cas uint64
+}
+
+// GetMulti provides the Get Database Operation for several keys.
+// Each key is retrieved as a separate operation, so some may fail while others succeed;
+// the results are returned in the order of keys.
+func (crud *CRUD) GetMulti(keys []string) []MultiResult {
+	results := make([]MultiResult, len(keys))
+	for i, key := range keys {
+		value, cas, err := crud.GetRaw(key)
+		results[i] = MultiResult{Key: key, Cas: cas, Err: err, value: value, transcoder: crud.transcoder}
+	}
+	return results
+}
+
+// UpsertMulti provides the Upsert Database Operation for several documents.
+// Each document is written as a separate operation, so some may fail while others succeed;
+// the results are returned in the order of docs.
+func (crud *CRUD) UpsertMulti(docs []MultiDoc) []MultiResult {
+	results := make([]MultiResult, len(docs))
+	for i, doc := range docs {
+		cas, err := crud.Upsert(doc.Key, doc.Value, doc.Expiry)
+		results[i] = MultiResult{Key: doc.Key, Cas: cas, Err: err}
+	}
+	return results
+}
+
+// RemoveMulti provides the Remove Database Operation for several documents.
+// Each document is removed as a separate operation, so some may fail while others succeed;
+// the results are returned in the order of items.
+func (crud *CRUD) RemoveMulti(items []MultiRemove) []MultiResult {
+	results := make([]MultiResult, len(items))
+	for i, item := range items {
+		cas, err := crud.Remove(item.Key, item.Cas)
+		results[i] = MultiResult{Key: item.Key, Cas: cas, Err: err}
+	}
+	return results
+}
@@ -0,0 +1,66 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBulk(t *testing.T) {
+	client := New()
+
+	results := client.UpsertMulti([]MultiDoc{
+		{Key: "a", Value: "val-a"},
+		{Key: "b", Value: "val-b"},
+		{Key: "c", Value: "val-c"},
+	})
+	for _, res := range results {
+		if res.Err != nil || res.Cas != 1 {
+			t.Fatalf("upsert of %s failed", res.Key)
+		}
+	}
+
+	results = client.GetMulti([]string{"a", "missing", "c"})
+	if len(results) != 3 {
+		t.Fatal("result count mismatch")
+	}
+	var act string
+	if err := results[0].Content(&act); err != nil || act != "val-a" {
+		t.Fatal("value mismatch")
+	}
+	if !reflect.DeepEqual(results[1].Err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+	if err := results[1].Content(&act); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+	if results[2].Key != "c" || results[2].Cas != 1 {
+		t.Fatal("result order mismatch")
+	}
+
+	results = client.RemoveMulti([]MultiRemove{{Key: "a", Cas: 1}, {Key: "b", Cas: 5}})
+	if results[0].Err != nil {
+		t.Fatal(results[0].Err)
+	}
+	if !reflect.DeepEqual(results[1].Err, ErrCasMismatch) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.Get("b", &act); err != nil {
+		t.Fatal("failed removal affected the document")
+	}
+}
+
+func TestBulkPartialFailure(t *testing.T) {
+	client := New().Client(WithRateLimit(0.001, 2))
+
+	results := client.UpsertMulti([]MultiDoc{
+		{Key: "a", Value: 1},
+		{Key: "b", Value: 2},
+		{Key: "c", Value: 3},
+	})
+	if results[0].Err != nil || results[1].Err != nil {
+		t.Fatal("operations within the burst failed")
+	}
+	if !reflect.DeepEqual(results[2].Err, ErrRateLimited) {
+		t.Fatal("error mismatch")
+	}
+}
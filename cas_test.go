@@ -0,0 +1,77 @@
+package crud
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHLCCas(t *testing.T) {
+	clock := newManualClock()
+	client := New(WithClock(clock), WithHLCCas())
+	base := uint64(clock.Now().UnixNano()) &^ 0xFFFF
+
+	cas, _ := client.Insert("a", "val", 0)
+	if cas != base {
+		t.Fatal("cas mismatch")
+	}
+	// mutations within the same clock tick advance the logical counter, across keys
+	cas, _ = client.Upsert("a", "val2", 0)
+	if cas != base+1 {
+		t.Fatal("cas mismatch")
+	}
+	cas, _ = client.Insert("b", "val", 0)
+	if cas != base+2 {
+		t.Fatal("cas mismatch")
+	}
+
+	clock.add(time.Second)
+	cas, _ = client.GetAndLock("b", 5, new(string))
+	if cas != uint64(clock.Now().UnixNano())&^0xFFFF {
+		t.Fatal("cas mismatch")
+	}
+	if err := client.Unlock("b", cas); err != nil {
+		t.Fatal(err)
+	}
+
+	// a clock going backwards never repeats a CAS
+	clock.add(-time.Minute)
+	next, _ := client.Upsert("b", "val2", 0)
+	if next != cas+1 {
+		t.Fatal("cas mismatch")
+	}
+
+	var act string
+	if got, _ := client.Get("b", &act); got != next {
+		t.Fatal("cas mismatch")
+	}
+}
+
+func TestCasGenerator(t *testing.T) {
+	// a user supplied generator
+	client := New(WithCasGenerator(CasFunc(func(prev uint64, _ time.Time) uint64 {
+		return prev + 10
+	})))
+	cas, _ := client.Insert("key", "val", 0)
+	if cas != 10 {
+		t.Fatal("cas mismatch")
+	}
+	if cas, _ = client.Replace("key", "val2", cas, 0); cas != 20 {
+		t.Fatal("cas mismatch")
+	}
+
+	// random values are reproducible for the same seed
+	first := New(WithCasGenerator(RandomCas(7)))
+	second := New(WithCasGenerator(RandomCas(7)))
+	for _, key := range []string{"a", "b", "c"} {
+		a, _ := first.Insert(key, "val", 0)
+		b, _ := second.Insert(key, "val", 0)
+		if a != b || a == 0 || a == LockedCas {
+			t.Fatal("cas mismatch")
+		}
+	}
+	cas, _ = first.Upsert("a", "val2", 0)
+	var act string
+	if got, _ := first.Get("a", &act); got != cas {
+		t.Fatal("cas mismatch")
+	}
+}
@@ -0,0 +1,98 @@
+package crud
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrForeignCheckpoint defines the error value returned when RollbackTo is given a checkpoint of another store
+var ErrForeignCheckpoint = errors.New("checkpoint belongs to another store")
+
+// Checkpoint is a saved state of the documents of a store, see CRUD.Checkpoint
+type Checkpoint struct {
+	db   *db
+	docs map[string]document
+}
+
+// Checkpoint saves the current documents of the store, including their CAS values, expiries and locks,
+// so that RollbackTo can later restore them. A checkpoint may be rolled back to any number of times.
+func (crud *CRUD) Checkpoint() (*Checkpoint, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	unlock := crud.lockAll()
+	defer unlock()
+
+	cp := &Checkpoint{db: crud.db, docs: make(map[string]document)}
+	for _, s := range crud.shards {
+		for key, doc := range s.docs {
+			d := *doc
+			if d.ejected != nil {
+				// the backend is rewritten by RollbackTo
+				if d.Value, err = d.ejected.read(); err != nil {
+					return nil, err
+				}
+				d.ejected = nil
+			}
+			cp.docs[key] = d
+		}
+	}
+	return cp, nil
+}
+
+// RollbackTo restores the documents of the store to the state saved by cp, returning ErrForeignCheckpoint
+// when cp was taken from another store. Time isn't rewound, so documents whose expiry has passed since
+// stay expired. Like Flush, no events are published and the change log retained for ResumeFrom is discarded.
+func (crud *CRUD) RollbackTo(cp *Checkpoint) error {
+	if cp.db != crud.db {
+		return ErrForeignCheckpoint
+	}
+	release, err := crud.admitWrite(context.Background())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	unlock := crud.lockAll()
+	defer unlock()
+
+	docs := make(map[string]*document, len(cp.docs))
+	for key, saved := range cp.docs {
+		// the saved document is copied so that writes updating documents in place leave it intact
+		doc := saved
+		docs[key] = &doc
+	}
+	crud.replaceDocs(docs)
+	return nil
+}
+
+// replaceDocs replaces every document of the store with docs, rebuilding the indexes and discarding
+// the change log. It is called with every shard locked.
+func (crud *CRUD) replaceDocs(docs map[string]*document) {
+	for _, s := range crud.shards {
+		s.reset()
+	}
+	if crud.index != nil {
+		crud.index.clear()
+	}
+	crud.indexes.clear()
+	crud.persistClear()
+	crud.stored.Store(int64(len(docs)))
+	crud.memory.Store(0)
+	crud.resetEviction()
+	for key, doc := range docs {
+		doc = crud.pack(doc)
+		crud.memory.Add(memorySize(key, doc))
+		crud.noteAccess(key)
+		crud.shardFor(key).docs[key] = doc
+		if crud.index != nil {
+			crud.index.add(key)
+		}
+		crud.indexes.update(key, doc)
+		crud.persistPut(key, doc)
+	}
+	crud.feed.truncate()
+}
@@ -0,0 +1,50 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	client := NewSharded(4, WithKeyIndex())
+	_ = client.CreateIndex("byName", "name")
+	_, _ = client.Insert("a", map[string]string{"name": "alice"}, 0)
+	cas, _ := client.Insert("b", map[string]string{"name": "bob"}, 60)
+
+	cp, err := client.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, _ = client.Touch("b", cas, 0)
+		_, _ = client.Remove("a", 1)
+		_, _ = client.Insert("c", map[string]string{"name": "carol"}, 0)
+
+		if err := client.RollbackTo(cp); err != nil {
+			t.Fatal(err)
+		}
+		if keys := client.Keys(); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+			t.Fatal("keys mismatch")
+		}
+		// CAS values and expiries are restored
+		meta, err := client.GetMeta("b")
+		if err != nil || meta.Cas != cas || meta.Expiry.IsZero() {
+			t.Fatal("meta mismatch")
+		}
+		// indexes are rebuilt
+		if docs, _ := client.LookupIndex("byName", "alice"); len(docs) != 1 || docs[0].Key != "a" {
+			t.Fatal("index mismatch")
+		}
+		if docs, _ := client.LookupIndex("byName", "carol"); len(docs) != 0 {
+			t.Fatal("index mismatch")
+		}
+		if docs, _ := client.Range("", "", 0); len(docs) != 2 {
+			t.Fatal("range mismatch")
+		}
+	}
+
+	if err := New().RollbackTo(cp); !reflect.DeepEqual(err, ErrForeignCheckpoint) {
+		t.Fatal("error mismatch")
+	}
+}
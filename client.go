@@ -0,0 +1,75 @@
+package crud
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited defines the error value returned when a client handle exceeds its operation rate limit
+var ErrRateLimited = errors.New("rate limited")
+
+// ClientOption configures a client handle created by Client
+type ClientOption func(*CRUD)
+
+// WithRateLimit limits the handle to opsPerSec operations per second, allowing bursts of up to burst operations.
+// Operations over the limit fail with ErrRateLimited instead of blocking, as Couchbase Cloud does.
+// A burst lower than one is treated as one. Tokens refill with the store's clock, see WithClock and Advance.
+func WithRateLimit(opsPerSec float64, burst int) ClientOption {
+	return func(crud *CRUD) {
+		crud.limiter = newRateLimiter(opsPerSec, burst, crud.now)
+	}
+}
+
+// Client returns a new handle sharing the documents of crud. Options such as rate limits
+// apply only to operations made through the returned handle.
+func (crud *CRUD) Client(opts ...ClientOption) *CRUD {
+	client := &CRUD{db: crud.db}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return client
+}
+
+// rateLimiter is a token bucket refilled continuously at rate tokens per second
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newRateLimiter(opsPerSec float64, burst int, now func() time.Time) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:   opsPerSec,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    now,
+	}
+}
+
+// allow consumes a token if one is available
+func (l *rateLimiter) allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
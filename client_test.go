@@ -0,0 +1,43 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClientRateLimit(t *testing.T) {
+	store := New()
+	store.FreezeTime()
+	client := store.Client(WithRateLimit(2, 2))
+
+	if _, err := client.Insert("a", "val", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Insert("b", "val", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := client.Insert("c", "val", 0)
+	if !reflect.DeepEqual(err, ErrRateLimited) {
+		t.Fatal("error mismatch")
+	}
+
+	// the parent store and other handles are not throttled
+	if _, err := store.Insert("c", "val", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// half a second refills one token at 2 ops/sec
+	store.Advance(500 * time.Millisecond)
+	var act string
+	if _, err := client.Get("c", &act); err != nil {
+		t.Fatal(err)
+	}
+	if act != "val" {
+		t.Fatal("results mismatch")
+	}
+	if _, err := client.Get("c", &act); !reflect.DeepEqual(err, ErrRateLimited) {
+		t.Fatal("error mismatch")
+	}
+}
@@ -0,0 +1,90 @@
+package crud
+
+import "time"
+
+// Clock tells the store the current time, which decides when documents expire and locks are released
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the Clock reading the system time
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// WithClock makes the store read the current time from clock rather than the system time,
+// so tests can control expiry deterministically
+func WithClock(clock Clock) Option {
+	return func(crud *CRUD) {
+		crud.clock = clock
+	}
+}
+
+// timeShift is how FreezeTime and Advance alter the store's clock
+type timeShift struct {
+	frozen bool
+	// at is the time while frozen
+	at time.Time
+	// offset is added to the clock's time while not frozen
+	offset time.Duration
+}
+
+// now returns the current time of the store
+func (db *db) now() time.Time {
+	shift := db.shift.Load()
+	if shift == nil {
+		return db.clock.Now()
+	}
+	if shift.frozen {
+		return shift.at
+	}
+	return db.clock.Now().Add(shift.offset)
+}
+
+// getTime returns the current Unix time of the store in nanoseconds, the unit of document expiries and locks
+func (db *db) getTime() int64 {
+	return db.now().UnixNano()
+}
+
+// FreezeTime stops the store's clock at the current time, so that documents only expire and locks
+// are only released when Advance moves the time forward. It affects every handle of the store.
+func (crud *CRUD) FreezeTime() {
+	for {
+		prev := crud.shift.Load()
+		next := &timeShift{frozen: true, at: crud.now()}
+		if crud.shift.CompareAndSwap(prev, next) {
+			return
+		}
+	}
+}
+
+// UnfreezeTime restarts the store's clock from the time it was frozen at
+func (crud *CRUD) UnfreezeTime() {
+	for {
+		prev := crud.shift.Load()
+		if prev == nil || !prev.frozen {
+			return
+		}
+		next := &timeShift{offset: prev.at.Sub(crud.clock.Now())}
+		if crud.shift.CompareAndSwap(prev, next) {
+			return
+		}
+	}
+}
+
+// Advance moves the store's time forward by d, whether or not it is frozen, expiring the documents and
+// releasing the locks whose time has come without sleeping
+func (crud *CRUD) Advance(d time.Duration) {
+	for {
+		prev := crud.shift.Load()
+		next := &timeShift{offset: d}
+		if prev != nil {
+			next = &timeShift{frozen: prev.frozen, at: prev.at.Add(d), offset: prev.offset + d}
+		}
+		if crud.shift.CompareAndSwap(prev, next) {
+			return
+		}
+	}
+}
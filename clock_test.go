@@ -0,0 +1,106 @@
+package crud
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock is a Clock which only moves when told to
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{now: time.Unix(1700000000, 0)}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestWithClock(t *testing.T) {
+	clock := newManualClock()
+	client := New(WithClock(clock))
+	client.Insert("key", "val", 10)
+
+	_, expiry, _ := client.GetWithExpiry("key", new(string))
+	if !expiry.Equal(clock.Now().Add(10 * time.Second)) {
+		t.Fatal("expiry mismatch")
+	}
+
+	clock.add(9 * time.Second)
+	if _, err := client.Get("key", new(string)); err != nil {
+		t.Fatal(err)
+	}
+	clock.add(2 * time.Second)
+	if _, err := client.Get("key", new(string)); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+
+	// locks are released by the store's clock too
+	client.Insert("locked", "val", 0)
+	client.GetAndLock("locked", 5, new(string))
+	if _, err := client.Upsert("locked", "val", 0); !reflect.DeepEqual(err, ErrDocumentLocked) {
+		t.Fatal("error mismatch")
+	}
+	clock.add(5 * time.Second)
+	if _, err := client.Upsert("locked", "val", 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFreezeTime(t *testing.T) {
+	client := New()
+	client.FreezeTime()
+	frozen := client.now()
+	client.Insert("key", "val", 5)
+
+	time.Sleep(10 * time.Millisecond)
+	if !client.now().Equal(frozen) {
+		t.Fatal("time mismatch")
+	}
+
+	client.Advance(5*time.Second - time.Nanosecond)
+	if _, err := client.Get("key", new(string)); err != nil {
+		t.Fatal(err)
+	}
+	client.Advance(time.Nanosecond)
+	if _, err := client.Get("key", new(string)); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+	if !client.now().Equal(frozen.Add(5 * time.Second)) {
+		t.Fatal("time mismatch")
+	}
+
+	// handles share the store's time
+	if !client.Client().now().Equal(client.now()) {
+		t.Fatal("time mismatch")
+	}
+
+	// the clock restarts from the frozen time, keeping the advance
+	client.UnfreezeTime()
+	if d := client.now().Sub(frozen); d < 5*time.Second || d > 6*time.Second {
+		t.Fatal("time mismatch")
+	}
+}
+
+func TestAdvanceRunning(t *testing.T) {
+	clock := newManualClock()
+	client := New(WithClock(clock))
+	client.Advance(time.Hour)
+	clock.add(time.Second)
+	if !client.now().Equal(clock.Now().Add(time.Hour)) {
+		t.Fatal("time mismatch")
+	}
+}
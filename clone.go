@@ -0,0 +1,113 @@
+package crud
+
+import "time"
+
+// Clone returns a new store holding a deep copy of the documents of crud, including their CAS values,
+// expiries and locks, so that a prepared fixture can be duplicated instead of seeded again.
+// The clone has the same options and the same index, view and search index definitions. Its change feed,
+// revision history and audit log start empty, and hooks, watchers, webhooks, the reaper, the write-ahead
+// log and the backend aren't carried over.
+// Key and CAS generators are shared with crud, so keys generated by InsertAuto stay unique across clones.
+// The eviction policy of the clone is a new one of the same kind, which hasn't seen the accesses made to crud.
+func (crud *CRUD) Clone() *CRUD {
+	unlock := crud.lockAll()
+	defer unlock()
+
+	src := crud.db
+	db := &db{
+		shards:            newShards(len(src.shards)),
+		keygen:            src.keygen,
+		casgen:            src.casgen,
+		feed:              newChangeFeed(src.feed.limit),
+		queue:             newOpQueue(cap(src.queue.slots), src.queue.policy),
+		jitter:            src.jitter,
+		indexes:           src.indexes.definitions(),
+		views:             src.views.definitions(),
+		search:            src.search.definitions(),
+		hooks:             newHookSet(),
+		clock:             src.clock,
+		transcoder:        src.transcoder,
+		threshold:         src.threshold,
+		defaultTTL:        src.defaultTTL,
+		maxTTL:            src.maxTTL,
+		maxTTLPolicy:      src.maxTTLPolicy,
+		maxKeys:           src.maxKeys,
+		maxValueSize:      src.maxValueSize,
+		keyRules:          src.keyRules,
+		compression:       src.compression,
+		compressThreshold: src.compressThreshold,
+		internKeys:        src.internKeys,
+		latency:           src.latency,
+	}
+	clone := &CRUD{db: db}
+	db.shift.Store(src.shift.Load())
+	if src.heat != nil {
+		WithKeyHeat(time.Duration(src.heat.window) * time.Second)(clone)
+	}
+	if src.index != nil {
+		db.index = newKeyIndex()
+	}
+	if src.history != nil {
+		db.history = &historyLog{limit: src.history.limit, revs: make(map[string][]revision)}
+	}
+	if src.audit != nil {
+		db.audit = &auditLog{limit: src.audit.limit}
+	}
+	if src.eviction != nil {
+		db.eviction = &evictionState{limit: src.eviction.limit, policy: src.eviction.policy.New()}
+	}
+
+	var read int64
+	for i, s := range src.shards {
+		for key, doc := range s.docs {
+			cp := *doc
+			if cp.ejected != nil {
+				cp.Value, cp.ejected = cp.value(), nil
+				read += int64(len(cp.Value))
+			}
+			db.shards[i].docs[key] = &cp
+			if db.index != nil {
+				db.index.add(key)
+			}
+			db.indexes.update(key, &cp)
+			clone.noteAccess(key)
+		}
+	}
+	db.stored.Store(src.stored.Load())
+	db.memory.Store(src.memory.Load() + read)
+	return clone
+}
+
+// definitions returns an empty set of indexes with the same definitions
+func (s *indexSet) definitions() *indexSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := newIndexSet()
+	for name, ix := range s.indexes {
+		out.indexes[name] = newSecondaryIndex(ix.fields, ix.unique)
+	}
+	out.counted()
+	return out
+}
+
+// definitions returns a copy of the set of views
+func (v *viewSet) definitions() *viewSet {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	out := newViewSet()
+	for name, view := range v.views {
+		out.views[name] = view
+	}
+	return out
+}
+
+// definitions returns a copy of the set of search indexes
+func (s *searchSet) definitions() *searchSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := newSearchSet()
+	for name, paths := range s.indexes {
+		out.indexes[name] = paths
+	}
+	return out
+}
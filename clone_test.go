@@ -0,0 +1,47 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	client := NewSharded(4, WithKeyIndex())
+	_ = client.CreateIndex("byName", "name")
+	_, _ = client.Insert("a", map[string]string{"name": "alice"}, 0)
+	cas, _ := client.Insert("b", map[string]string{"name": "bob"}, 60)
+	_, _ = client.Replace("b", map[string]string{"name": "bobby"}, cas, 60)
+
+	clone := client.Clone()
+	meta, _ := clone.GetMeta("b")
+	orig, _ := client.GetMeta("b")
+	if !reflect.DeepEqual(meta, orig) {
+		t.Fatal("meta mismatch")
+	}
+
+	// the stores are independent
+	_, _ = clone.Remove("a", 1)
+	_, _ = clone.Touch("b", meta.Cas, 0)
+	_, _ = client.Insert("c", map[string]string{"name": "carol"}, 0)
+
+	if keys := client.Keys(); !reflect.DeepEqual(keys, []string{"a", "b", "c"}) {
+		t.Fatal("keys mismatch")
+	}
+	if keys := clone.Keys(); !reflect.DeepEqual(keys, []string{"b"}) {
+		t.Fatal("keys mismatch")
+	}
+	if meta, _ := client.GetMeta("b"); !reflect.DeepEqual(meta, orig) {
+		t.Fatal("meta mismatch")
+	}
+
+	// index definitions are carried over and hold the clone's documents
+	if docs, _ := clone.LookupIndex("byName", "bobby"); len(docs) != 1 || docs[0].Key != "b" {
+		t.Fatal("index mismatch")
+	}
+	if docs, _ := clone.LookupIndex("byName", "alice"); len(docs) != 0 {
+		t.Fatal("index mismatch")
+	}
+	if docs, _ := clone.Range("", "", 0); len(docs) != 1 {
+		t.Fatal("range mismatch")
+	}
+}
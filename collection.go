@@ -0,0 +1,75 @@
+package crud
+
+import "context"
+
+// Collection is a typed view of a store whose documents all hold values of type T.
+// It shares the documents and client options of the CRUD it was created from.
+type Collection[T any] struct {
+	crud *CRUD
+}
+
+// NewCollection returns a Collection of T values stored in crud
+func NewCollection[T any](crud *CRUD) *Collection[T] {
+	return &Collection[T]{crud: crud}
+}
+
+// Get provides the Get Database Operation, returning the decoded value
+func (c *Collection[T]) Get(key string) (T, uint64, error) {
+	return c.GetCtx(context.Background(), key)
+}
+
+// GetCtx is Get honouring the cancellation and deadline of ctx
+func (c *Collection[T]) GetCtx(ctx context.Context, key string) (T, uint64, error) {
+	var value T
+	cas, err := c.crud.GetCtx(ctx, key, &value)
+	if err != nil {
+		var zero T
+		return zero, 0, err
+	}
+	return value, cas, nil
+}
+
+// Insert provides the Insert Database Operation, see CRUD.Insert
+func (c *Collection[T]) Insert(key string, value T, expiry uint32) (uint64, error) {
+	return c.crud.Insert(key, value, expiry)
+}
+
+// InsertWith is Insert configured by WriteOptions
+func (c *Collection[T]) InsertWith(key string, value T, opts ...WriteOption) (uint64, error) {
+	return c.crud.InsertWith(key, value, opts...)
+}
+
+// Upsert provides the Upsert Database Operation, see CRUD.Upsert
+func (c *Collection[T]) Upsert(key string, value T, expiry uint32) (uint64, error) {
+	return c.crud.Upsert(key, value, expiry)
+}
+
+// UpsertWith is Upsert configured by WriteOptions
+func (c *Collection[T]) UpsertWith(key string, value T, opts ...WriteOption) (uint64, error) {
+	return c.crud.UpsertWith(key, value, opts...)
+}
+
+// Replace provides the Replace Database Operation, see CRUD.Replace
+func (c *Collection[T]) Replace(key string, value T, cas uint64, expiry uint32) (uint64, error) {
+	return c.crud.Replace(key, value, cas, expiry)
+}
+
+// ReplaceWith is Replace configured by WriteOptions
+func (c *Collection[T]) ReplaceWith(key string, value T, cas uint64, opts ...WriteOption) (uint64, error) {
+	return c.crud.ReplaceWith(key, value, cas, opts...)
+}
+
+// Remove provides the Remove Database Operation, see CRUD.Remove
+func (c *Collection[T]) Remove(key string, cas uint64) (uint64, error) {
+	return c.crud.Remove(key, cas)
+}
+
+// Touch provides the Touch Database Operation, see CRUD.Touch
+func (c *Collection[T]) Touch(key string, cas uint64, expiry uint32) (uint64, error) {
+	return c.crud.Touch(key, cas, expiry)
+}
+
+// TouchWith is Touch configured by WriteOptions
+func (c *Collection[T]) TouchWith(key string, cas uint64, opts ...WriteOption) (uint64, error) {
+	return c.crud.TouchWith(key, cas, opts...)
+}
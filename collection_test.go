@@ -0,0 +1,69 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testUser struct {
+	Name string
+	Age  int
+}
+
+func TestCollection(t *testing.T) {
+	users := NewCollection[testUser](New())
+
+	cases := []struct {
+		key  string
+		user testUser
+	}{
+		{"alice", testUser{Name: "Alice", Age: 30}},
+		{"bob", testUser{Name: "Bob", Age: 25}},
+	}
+	for _, c := range cases {
+		if _, err := users.Insert(c.key, c.user, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range cases {
+		act, cas, err := users.Get(c.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cas != 1 {
+			t.Fatal("cas mismatch")
+		}
+		if act != c.user {
+			t.Fatalf("value mismatch %+v", act)
+		}
+	}
+
+	cas, err := users.Replace("alice", testUser{Name: "Alice", Age: 31}, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act, _, _ := users.Get("alice"); act.Age != 31 {
+		t.Fatal("value mismatch")
+	}
+	if _, err := users.Remove("alice", cas); err != nil {
+		t.Fatal(err)
+	}
+
+	act, cas, err := users.Get("alice")
+	if !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+	if act != (testUser{}) || cas != 0 {
+		t.Fatal("expected the zero value for a missing key")
+	}
+}
+
+func TestCollectionSharesStore(t *testing.T) {
+	client := New()
+	_, _ = NewCollection[[]string](client).Upsert("tags", []string{"a", "b"}, 0)
+
+	var act []string
+	if _, err := client.Get("tags", &act); err != nil || !reflect.DeepEqual(act, []string{"a", "b"}) {
+		t.Fatal("value mismatch")
+	}
+}
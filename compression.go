@@ -0,0 +1,129 @@
+package crud
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/snappy"
+)
+
+// Compression is the algorithm compressing stored values, see WithCompression
+type Compression int
+
+const (
+	// CompressionNone stores values as they are
+	CompressionNone Compression = iota
+	// CompressionSnappy compresses values with snappy, which is fast but compresses less than gzip
+	CompressionSnappy
+	// CompressionGzip compresses values with gzip
+	CompressionGzip
+)
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionSnappy:
+		return "snappy"
+	case CompressionGzip:
+		return "gzip"
+	}
+	return "unknown"
+}
+
+// WithCompression compresses the stored values of threshold bytes or more with c, to cut the memory used by
+// large fixtures. Values are decompressed whenever they are read, so compression is invisible to readers,
+// hooks, watchers and persistence, which all see the values as written. Values which don't get smaller are
+// stored as they are. Stats reports the size of the values both as written and as stored, and memory limits
+// set by WithMemoryLimit count the stored size.
+func WithCompression(c Compression, threshold int) Option {
+	return func(crud *CRUD) {
+		crud.compression = c
+		crud.compressThreshold = threshold
+	}
+}
+
+// pack returns doc with its value compressed when the store compresses values and compressing shrinks it,
+// otherwise doc itself. The compressed document is a copy, so doc keeps its value.
+func (crud *CRUD) pack(doc *document) *document {
+	if crud.compression == CompressionNone || doc.packed != nil || len(doc.Value) < crud.compressThreshold {
+		return doc
+	}
+	packed := compress(crud.compression, doc.Value)
+	if len(packed) >= len(doc.Value) {
+		return doc
+	}
+	cp := *doc
+	cp.Value = nil
+	cp.packed, cp.codec, cp.rawSize = packed, crud.compression, len(doc.Value)
+	return &cp
+}
+
+// value returns the value of the document, decompressing it or reading it back from the backend if needed.
+// It is nil when an ejected value can't be read, see load.
+func (d *document) value() []byte {
+	if d.ejected != nil {
+		value, _ := d.ejected.read()
+		return value
+	}
+	if d.packed == nil {
+		return d.Value
+	}
+	value, err := decompress(d.codec, d.packed, d.rawSize)
+	if err != nil {
+		// packed values are only ever produced by compress
+		panic(fmt.Sprintf("crud: decompressing stored value: %v", err))
+	}
+	return value
+}
+
+// size returns the length of the value of the document, without decompressing it
+func (d *document) size() int {
+	if d.ejected != nil {
+		return d.ejected.size
+	}
+	if d.packed != nil {
+		return d.rawSize
+	}
+	return len(d.Value)
+}
+
+// storedSize returns the number of bytes held for the value of the document
+func (d *document) storedSize() int {
+	return len(d.Value) + len(d.packed)
+}
+
+func compress(c Compression, value []byte) []byte {
+	switch c {
+	case CompressionSnappy:
+		return snappy.Encode(nil, value)
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		// writes to a bytes.Buffer don't fail
+		_, _ = w.Write(value)
+		_ = w.Close()
+		return buf.Bytes()
+	}
+	return value
+}
+
+func decompress(c Compression, packed []byte, size int) ([]byte, error) {
+	switch c {
+	case CompressionSnappy:
+		return snappy.Decode(make([]byte, size), packed)
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(packed))
+		if err != nil {
+			return nil, err
+		}
+		buf := bytes.NewBuffer(make([]byte, 0, size))
+		if _, err := io.Copy(buf, r); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return packed, nil
+}
@@ -0,0 +1,96 @@
+package crud
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type compressible struct {
+	Name string `json:"name"`
+	Body string `json:"body"`
+}
+
+func TestCompression(t *testing.T) {
+	for _, c := range []Compression{CompressionSnappy, CompressionGzip} {
+		client := New(WithCompression(c, 64))
+		exp := compressible{Name: "doc", Body: strings.Repeat("lorem ipsum ", 100)}
+		cas, err := client.Insert("big", exp, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = client.Insert("small", "tiny", 0)
+
+		if client.shardFor("big").docs["big"].packed == nil {
+			t.Fatalf("%s: value wasn't compressed", c)
+		}
+		if client.shardFor("small").docs["small"].packed != nil {
+			t.Fatalf("%s: value under the threshold was compressed", c)
+		}
+
+		var act compressible
+		if _, err := client.Get("big", &act); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(act, exp) {
+			t.Fatalf("%s: value mismatch", c)
+		}
+		var projected compressible
+		if _, err := client.GetWithProjection("big", &projected, "name"); err != nil || projected.Name != "doc" {
+			t.Fatalf("%s: projection mismatch", c)
+		}
+
+		// in place updates keep the value
+		cas, _ = client.Touch("big", cas, 100)
+		if _, err := client.GetAndLock("big", 5, &act); err != nil || !reflect.DeepEqual(act, exp) {
+			t.Fatalf("%s: value mismatch", c)
+		}
+
+		stats, _ := client.Stats()
+		if stats.StoredValueBytes >= stats.ValueBytes {
+			t.Fatalf("%s: unexpected stats %+v", c, stats)
+		}
+		meta, _ := client.GetMeta("big")
+		if int64(meta.Size)+6 != stats.ValueBytes {
+			t.Fatalf("%s: size mismatch", c)
+		}
+	}
+}
+
+func TestCompressionAppend(t *testing.T) {
+	client := New(WithCompression(CompressionSnappy, 16))
+	var old []byte
+	client.OnUpdate(func(key string, oldValue, newValue []byte, cas uint64) {
+		old = oldValue
+	})
+	data := bytes.Repeat([]byte("a"), 100)
+	_, _ = client.InsertRaw("raw", data, 0)
+	_, _ = client.Append("raw", []byte("b"))
+	if !bytes.Equal(old, data) {
+		t.Fatal("old value mismatch")
+	}
+	act, _, _ := client.GetRaw("raw")
+	if !bytes.Equal(act, append(data, 'b')) {
+		t.Fatal("value mismatch")
+	}
+}
+
+func TestCompressionSnapshot(t *testing.T) {
+	client := New(WithCompression(CompressionGzip, 0))
+	exp := compressible{Name: "doc", Body: strings.Repeat("x", 1000)}
+	_, _ = client.Insert("big", exp, 0)
+
+	var buf bytes.Buffer
+	if err := client.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	restored := New()
+	if err := restored.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var act compressible
+	if _, err := restored.Get("big", &act); err != nil || !reflect.DeepEqual(act, exp) {
+		t.Fatal("value mismatch")
+	}
+}
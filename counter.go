@@ -0,0 +1,99 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// ErrInvalidCounter defines the error value returned when a counter operation targets a document
+// which doesn't hold a non-negative integer
+var ErrInvalidCounter = errors.New("document is not a counter")
+
+// Increment adds delta to the counter stored under key and returns its new value and CAS.
+// A missing counter is created holding initial, unless initial is negative in which case ErrKeyNotExist is returned.
+// expiry only applies when the counter is created. Counters wrap around at the maximum uint64.
+func (crud *CRUD) Increment(key string, delta uint64, initial int64, expiry uint32) (uint64, uint64, error) {
+	return crud.counter(key, delta, false, initial, expiry)
+}
+
+// Decrement subtracts delta from the counter stored under key and returns its new value and CAS.
+// Counters never go below zero. See Increment for the handling of missing counters and expiry.
+func (crud *CRUD) Decrement(key string, delta uint64, initial int64, expiry uint32) (uint64, uint64, error) {
+	return crud.counter(key, delta, true, initial, expiry)
+}
+
+func (crud *CRUD) counter(key string, delta uint64, decrement bool, initial int64, expiry uint32) (uint64, uint64, error) {
+	release, err := crud.admitWrite(context.Background())
+	if err != nil {
+		return 0, 0, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpCounter); err != nil {
+		return 0, 0, err
+	}
+
+	s := crud.shardFor(key)
+	s.lock()
+	defer s.mu.Unlock()
+
+	doc, err := crud.lookup(key)
+	if err == ErrKeyNotExist {
+		if initial < 0 {
+			return 0, 0, ErrKeyNotExist
+		}
+		if err := crud.validateKey(key); err != nil {
+			return 0, 0, err
+		}
+		ttl, err := crud.resolveExpiry(legacyExpiry(expiry))
+		if err != nil {
+			return 0, 0, err
+		}
+		data := strconv.AppendUint(nil, uint64(initial), 10)
+		unreserve, err := crud.reserveUnique(map[string][]byte{key: data})
+		if err != nil {
+			return 0, 0, err
+		}
+		defer unreserve()
+		releaseQuota, err := crud.reserveQuota(1)
+		if err != nil {
+			return 0, 0, err
+		}
+		defer releaseQuota()
+
+		doc = newDoc(data, ttl)
+		crud.put(key, doc, EventInsert)
+		return uint64(initial), doc.Cas, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if doc.locked(crud.getTime()) {
+		return 0, 0, ErrDocumentLocked
+	}
+
+	value, err := strconv.ParseUint(string(doc.value()), 10, 64)
+	if err != nil {
+		return 0, 0, ErrInvalidCounter
+	}
+	switch {
+	case !decrement:
+		value += delta
+	case delta > value:
+		value = 0
+	default:
+		value -= delta
+	}
+
+	data := strconv.AppendUint(nil, value, 10)
+	unreserve, err := crud.reserveUnique(map[string][]byte{key: data})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer unreserve()
+
+	doc = doc.set(data)
+	crud.put(key, doc, EventUpdate)
+	return value, doc.Cas, nil
+}
@@ -0,0 +1,89 @@
+package crud
+
+import (
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestIncrement(t *testing.T) {
+	client := New()
+
+	if _, _, err := client.Increment("hits", 1, -1, 0); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+
+	value, cas, err := client.Increment("hits", 1, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 10 || cas != 1 {
+		t.Fatal("initial value mismatch")
+	}
+
+	value, cas, err = client.Increment("hits", 5, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 15 || cas != 2 {
+		t.Fatal("value mismatch")
+	}
+
+	// counters are stored as numeric documents
+	var act int
+	if _, err := client.Get("hits", &act); err != nil || act != 15 {
+		t.Fatal("stored value mismatch")
+	}
+
+	_, _ = client.Upsert("max", uint64(math.MaxUint64), 0)
+	if value, _, _ := client.Increment("max", 2, 0, 0); value != 1 {
+		t.Fatal("counter did not wrap around")
+	}
+}
+
+func TestDecrement(t *testing.T) {
+	client := New()
+	_, _, _ = client.Increment("stock", 0, 3, 0)
+
+	if value, _, _ := client.Decrement("stock", 2, 0, 0); value != 1 {
+		t.Fatal("value mismatch")
+	}
+	if value, _, _ := client.Decrement("stock", 5, 0, 0); value != 0 {
+		t.Fatal("counter went below zero")
+	}
+}
+
+func TestCounterInvalid(t *testing.T) {
+	client := New()
+	_, _ = client.Insert("name", "alice", 0)
+	_, _ = client.Insert("negative", -1, 0)
+
+	if _, _, err := client.Increment("name", 1, 0, 0); !reflect.DeepEqual(err, ErrInvalidCounter) {
+		t.Fatal("error mismatch")
+	}
+	if _, _, err := client.Decrement("negative", 1, 0, 0); !reflect.DeepEqual(err, ErrInvalidCounter) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestCounterConcurrent(t *testing.T) {
+	client := New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _, _ = client.Increment("hits", 1, 1, 0)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var act int
+	if _, err := client.Get("hits", &act); err != nil || act != 1000 {
+		t.Fatalf("lost increments, counter is %d", act)
+	}
+}
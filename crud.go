@@ -3,8 +3,10 @@
 package crud
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,94 +24,521 @@ const ThirtyDaySeconds = 2592000
 
 // document encapsulates each of the documents with a CAS value
 // Regarding TTL:
-// - To set a value of 30 days or less : If you want an item to live for less than 30 days, you can provide a TTL in seconds
-//   or as Unix time. The maximum value you can specify in seconds is the number of seconds in a month, namely 30 x 24
-//   x 60 x 60. Couchbase Server removes the item the given number of seconds after it stores the item.
-// - To set a value over 30 days : If you want an item to live for more than 30 days, you must provide a TTL in Unix time.
+//   - To set a value of 30 days or less : If you want an item to live for less than 30 days, you can provide a TTL in seconds
+//     or as Unix time. The maximum value you can specify in seconds is the number of seconds in a month, namely 30 x 24
+//     x 60 x 60. Couchbase Server removes the item the given number of seconds after it stores the item.
+//   - To set a value over 30 days : If you want an item to live for more than 30 days, you must provide a TTL in Unix time.
 type document struct {
 	Cas uint64
-	// TTL of the document
+	// TTL is the Unix time in nanoseconds the document expires at, zero when it never expires
 	TTL int64
-	// Value contains the raw document data
+	// Value contains the raw document data. It is never modified in place, writers replace it.
+	// It is nil when the value is compressed, so it is read with value().
 	Value []byte
+	// packed holds the value compressed by codec, rawSize being its uncompressed length, see WithCompression
+	packed  []byte
+	codec   Compression
+	rawSize int
+	// ejected is set when the value was left to the backend to keep the store within its memory limit
+	ejected *ejectedValue
+	// Datatype records whether Value holds JSON or opaque binary data
+	Datatype Datatype
+	// Checksum is the content hash of Value
+	Checksum string
+	// LockedUntil is the Unix time in nanoseconds a lock taken by GetAndLock expires, zero when never locked
+	LockedUntil int64
+	// Created and Updated are the Unix times in nanoseconds the document was first stored and last mutated
+	Created int64
+	Updated int64
 }
 
-// getTime is a temporary function that should be replaced with an Exos time
-// implementation which allows for tweaking the time for unit tests and
-// offsets for QA
-func getTime() int64 {
-	return time.Now().UTC().Unix()
+// newDoc is a helper function for creating an initial document state.
+// ttl is the absolute expiry as returned by CRUD.resolveExpiry. The CAS is assigned by put.
+func newDoc(data []byte, ttl int64) *document {
+	return &document{
+		Value: data,
+		TTL:   ttl,
+	}
 }
 
-// newDoc is a helper function for creating an initial document state
-func newDoc(data []byte, ttl uint32) *document {
-
-	setTTL := int64(ttl)
+// expired reports whether the document's TTL has been reached at the given Unix time in nanoseconds
+func (d *document) expired(now int64) bool {
+	return d.TTL > 0 && d.TTL <= now
+}
 
-	// if the ttl value is larger than 0, but less than 30 days,  then assume it's a relative time
-	// and calculate it as such
-	if setTTL < ThirtyDaySeconds && setTTL > 0 {
-		setTTL = getTime() + setTTL
-	}
-	// else assume that it's a Unix timestamp and set it directly
+// locked reports whether the document is locked at the given Unix time in nanoseconds
+func (d *document) locked(now int64) bool {
+	return d.LockedUntil > now
+}
 
-	return &document{
-		Cas:   1,
-		Value: data,
-		TTL:   setTTL,
+// reportedCas returns the CAS revealed to readers: the real CAS of a locked document is only
+// revealed to the lock holder
+func (d *document) reportedCas(now int64) uint64 {
+	if d.locked(now) {
+		return LockedCas
 	}
+	return d.Cas
 }
 
-// Set updates the value and increments the CAS value
-func (d *document) set(value []byte) {
-	d.Cas++
-	d.Value = value
+// set returns a copy of the document holding value, which is given a new CAS when stored by put.
+// Writers store the copy so that the replaced document stays intact for hooks.
+func (d *document) set(value []byte) *document {
+	next := *d
+	next.Value = value
+	next.packed, next.codec, next.rawSize = nil, CompressionNone, 0
+	next.ejected = nil
+	return &next
 }
 
-// CRUD is a simple object for storing documents
+// CRUD is a simple object for storing documents.
+// Handles created with Client share the documents of the CRUD they were created from.
 type CRUD struct {
-	storage map[string]*document
+	*db
+	// limiter throttles operations made through this handle, nil when unlimited
+	limiter *rateLimiter
+	// reader is true for handles backing a Reader, which must never write to storage
+	reader bool
+	// label identifies the handle in the audit log, see WithAuditLabel
+	label string
+}
+
+// db holds the state shared by every handle of a store
+type db struct {
+	// shards partition the stored documents by key, see NewSharded
+	shards []*shard
+	keygen KeyGenerator
+	feed   *changeFeed
+	queue  *opQueue
+	jitter *ttlJitter
+	heat   *heatTracker
+	// index orders the stored keys for Range, nil unless WithKeyIndex is given
+	index *keyIndex
+	// indexes holds the secondary indexes created by CreateIndex
+	indexes *indexSet
+	// views holds the map/reduce views defined by DefineView
+	views *viewSet
+	// search holds the full-text indexes created by CreateSearchIndex
+	search *searchSet
+	// audit records the operations applied to the store, nil unless WithAuditLog is given
+	audit *auditLog
+	// history retains the revisions of each document, nil unless WithHistory is given
+	history *historyLog
+	// wal logs the mutations applied to the store, nil unless WithWAL is given or the store was created by NewFromWAL
+	wal *wal
+	// backend persists the documents of the store, nil unless WithBackend is given
+	backend *backendState
+	// readOnly rejects mutations while set, see SetReadOnly
+	readOnly atomic.Bool
+	// stored counts the documents held by the shards, including expired ones not yet purged
+	stored atomic.Int64
+	// maxKeys caps stored, zero when there is no cap, see WithMaxKeys. quotaMu serialises the
+	// writes storing new documents while there is a cap.
+	maxKeys int
+	quotaMu sync.Mutex
+	// maxValueSize caps the size of stored values, zero when there is no cap, see WithMaxValueSize
+	maxValueSize int
+	// keyRules constrains the keys of new documents, nil when every key is accepted, see WithKeyRules
+	keyRules *KeyRules
+	// counters counts the operations applied to the store, see Stats
+	counters opCounters
+	// memory is the total size of the keys and values of the stored documents, see WithMemoryLimit
+	memory atomic.Int64
+	// eviction evicts documents once memory exceeds its limit, nil unless WithMemoryLimit or
+	// WithEvictionPolicy is given
+	eviction *evictionState
+	// compression compresses stored values of compressThreshold bytes or more, see WithCompression
+	compression       Compression
+	compressThreshold int
+	// internKeys shares a single copy of each key, see WithKeyInterning
+	internKeys bool
+	// latency delays operations by kind, see WithLatency
+	latency map[Operation]latency
+	// hooks holds the callbacks registered by OnInsert, OnUpdate, OnRemove and OnExpire
+	hooks *hookSet
+	// reaping holds the background reaper started by StartReaper
+	reaping reaperState
+	// clock provides the current time, see WithClock
+	clock Clock
+	// shift holds the changes made to the clock by FreezeTime and Advance, nil when there are none
+	shift atomic.Pointer[timeShift]
+	// casgen generates the CAS values of mutations, see WithCasGenerator
+	casgen CasGenerator
+	// transcoder encodes and decodes document values
+	transcoder Transcoder
+	// threshold is the largest expiry value interpreted as relative seconds, larger values are Unix timestamps
+	threshold uint32
+	// defaultTTL is the expiry given to writes made without one, zero when they never expire
+	defaultTTL time.Duration
+	// maxTTL caps the expiry of writes according to maxTTLPolicy, zero when there is no cap
+	maxTTL       time.Duration
+	maxTTLPolicy MaxTTLPolicy
 }
 
+// Option configures a CRUD created by New
+type Option func(*CRUD)
+
 // New creates a crud database for the purposes of mocking a document store
-func New() *CRUD {
-	return &CRUD{make(map[string]*document)}
+func New(opts ...Option) *CRUD {
+	return newCRUD(1, opts)
+}
+
+// newCRUD creates a crud database with the given number of shards
+func newCRUD(shards int, opts []Option) *CRUD {
+	crud := &CRUD{db: &db{
+		shards:       newShards(shards),
+		maxValueSize: DefaultMaxValueSize,
+		keygen:       SequentialKeys(),
+		casgen:       SequentialCas(),
+		feed:         newChangeFeed(defaultChangeLogLimit),
+		queue:        newOpQueue(0, QueueBlock),
+		threshold:    ThirtyDaySeconds,
+		transcoder:   JSONTranscoder{},
+		clock:        systemClock{},
+		indexes:      newIndexSet(),
+		views:        newViewSet(),
+		search:       newSearchSet(),
+		hooks:        newHookSet(),
+	}}
+	for _, opt := range opts {
+		opt(crud)
+	}
+	if crud.backend != nil {
+		if err := crud.loadBackend(); err != nil {
+			crud.backend.fail(err)
+		}
+	}
+	return crud
+}
+
+// admit is called at the start of every operation and returns an error when the operation must be rejected.
+// The returned function must be called once the operation completes.
+func (crud *CRUD) admit(ctx context.Context) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if crud.backend != nil {
+		if err := crud.backend.failed(); err != nil {
+			return nil, err
+		}
+	}
+	if crud.limiter != nil && !crud.limiter.allow() {
+		return nil, ErrRateLimited
+	}
+	release, err := crud.queue.enter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		release()
+		// the operation's locks are released by now, so hooks may use the store
+		crud.hooks.run()
+	}, nil
+}
+
+// admitWrite is called instead of admit at the start of every operation mutating documents
+func (crud *CRUD) admitWrite(ctx context.Context) (func(), error) {
+	if crud.readOnly.Load() {
+		return nil, ErrReadOnly
+	}
+	release, err := crud.admit(ctx)
+	if err != nil || crud.eviction == nil {
+		return release, err
+	}
+	return func() {
+		// evict before release runs the hooks, so that OnEvict hooks run with the write's
+		crud.evict()
+		release()
+	}, nil
+}
+
+// put stores doc under key with a new CAS and publishes the mutation to watchers.
+// Every write to storage should go through put or del, with the write lock of the key's shard held.
+func (crud *CRUD) put(key string, doc *document, typ EventType) {
+	key = crud.intern(key)
+	docs := crud.shardFor(key).docs
+	prev, existed := docs[key]
+	// prev is doc itself for writes which update the stored document in place
+	var prevCas uint64
+	var prevSize int64
+	// avoid decompressing the previous value when there are no hooks
+	var old []byte
+	now := crud.getTime()
+	created := now
+	if existed {
+		prevCas, created = prev.Cas, prev.Created
+		prevSize = memorySize(key, prev)
+		if crud.hooks.registered.Load() {
+			old = prev.value()
+		}
+	}
+	value := doc.value()
+	if doc.ejected != nil {
+		doc.Value, doc.ejected = value, nil
+	}
+	doc.Datatype = detectDatatype(value)
+	doc.Checksum = checksum(value)
+	doc.Cas = crud.nextCas(prevCas)
+	doc.Created = created
+	doc.Updated = now
+	if !existed {
+		crud.stored.Add(1)
+		if crud.index != nil {
+			crud.index.add(key)
+		}
+	}
+	// the stored document may be a compressed copy of doc
+	stored := crud.pack(doc)
+	crud.memory.Add(memorySize(key, stored) - prevSize)
+	docs[key] = stored
+	crud.indexes.update(key, doc)
+	crud.recordWrite(key, len(value))
+	crud.noteAccess(key)
+	crud.counters.count(typ)
+	crud.recordHistory(typ, key, doc)
+	crud.recordAudit(auditOps[typ], key, prevCas, doc.Cas)
+	crud.persistPut(key, doc)
+	crud.feed.publish(typ, key, doc.Cas, value)
+	crud.hooks.fire(typ, key, old, value, doc.Cas)
+}
+
+// del removes key from storage and publishes the removal to watchers
+func (crud *CRUD) del(key string, typ EventType) {
+	s := crud.shardFor(key)
+	doc, ok := s.docs[key]
+	if !ok {
+		return
+	}
+	key = crud.intern(key)
+	// read before the backend drops an ejected value
+	var old []byte
+	if crud.hooks.registered.Load() {
+		old = doc.value()
+	}
+	delete(s.docs, key)
+	crud.stored.Add(-1)
+	crud.memory.Add(-memorySize(key, doc))
+	crud.noteRemoval(key)
+	if crud.index != nil {
+		crud.index.remove(key)
+	}
+	crud.indexes.remove(key)
+	if crud.heat != nil {
+		crud.heat.forget(key)
+	}
+	crud.counters.count(typ)
+	crud.recordHistory(typ, key, doc)
+	crud.recordAudit(auditOps[typ], key, doc.Cas, 0)
+	crud.persistDelete(key)
+	crud.feed.publish(typ, key, doc.Cas, nil)
+	if crud.hooks.registered.Load() {
+		crud.hooks.fire(typ, key, old, nil, doc.Cas)
+	}
+}
+
+// WriteOption configures a single write operation
+type WriteOption func(*writeOptions)
+
+type writeOptions struct {
+	expiry   expirySpec
+	checksum string
+	// preserveExpiry keeps the expiry of the document being updated
+	preserveExpiry bool
+}
+
+// precondition checks the conditions of the write against the current document, nil when there is none
+func (o writeOptions) precondition(doc *document) error {
+	if o.checksum != "" && (doc == nil || doc.Checksum != o.checksum) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+func newWriteOptions(opts []WriteOption) writeOptions {
+	var o writeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// errExpired is returned by peek for documents which are stored but expired
+var errExpired = errors.New("document expired")
+
+// peek returns the live document stored under key without modifying storage.
+// It must be called with at least the read lock of the key's shard held.
+func (crud *CRUD) peek(key string) (*document, error) {
+	doc, ok := crud.shardFor(key).docs[key]
+	if !ok {
+		return nil, ErrKeyNotExist
+	}
+
+	// Very basic TTL support
+	if doc.expired(crud.getTime()) {
+		return nil, errExpired
+	}
+	return doc, nil
+}
+
+// lookup returns the live document stored under key, purging it if it has expired and reading its value
+// back if it was ejected.
+// It must be called with the write lock of the key's shard held.
+func (crud *CRUD) lookup(key string) (*document, error) {
+	doc, err := crud.peek(key)
+	if err == errExpired {
+		crud.del(key, EventExpire)
+		return nil, ErrKeyNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := crud.materialize(key, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// fetch returns a copy of the live document stored under key, taking the read lock.
+// Expired documents are purged afterwards, unless this is a Reader handle, and ejected values read back.
+// Values are never modified in place, so the copy can be used once the lock is released.
+func (crud *CRUD) fetch(key string) (document, error) {
+	s := crud.shardFor(key)
+	s.mu.RLock()
+	var cp document
+	doc, err := crud.peek(key)
+	if err == nil {
+		cp = *doc
+	}
+	s.mu.RUnlock()
+
+	if err == errExpired {
+		if !crud.reader {
+			crud.purge(key)
+		}
+		return document{}, ErrKeyNotExist
+	}
+	if err != nil || cp.ejected == nil {
+		return cp, err
+	}
+	value, err := cp.ejected.read()
+	if err != nil {
+		return document{}, err
+	}
+	if crud.tiered() {
+		crud.warm(key, cp.Cas, value)
+	}
+	cp.Value, cp.ejected = value, nil
+	return cp, nil
+}
+
+// purge removes key if it is still expired
+func (crud *CRUD) purge(key string) {
+	s := crud.shardFor(key)
+	s.lock()
+	defer s.mu.Unlock()
+	if doc, ok := s.docs[key]; ok && doc.expired(crud.getTime()) {
+		crud.del(key, EventExpire)
+	}
 }
 
 // Get provides basic Get Database Operation.
 // It should be extended and wrapped with application level processes such as validation and serialisation.
 func (crud *CRUD) Get(key string, valuePtr interface{}) (uint64, error) {
-	doc, ok := crud.storage[key]
-	if !ok {
-		return 0, ErrKeyNotExist
+	return crud.GetCtx(context.Background(), key, valuePtr)
+}
+
+// GetCtx is Get honouring the cancellation and deadline of ctx
+func (crud *CRUD) GetCtx(ctx context.Context, key string, valuePtr interface{}) (uint64, error) {
+	release, err := crud.admit(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	if err := crud.delay(ctx, OpGet); err != nil {
+		return 0, err
 	}
 
-	// Very basic TTL support
-	if doc.TTL > 0 && doc.TTL < getTime() {
-		delete(crud.storage, key)
-		return 0, ErrKeyNotExist
+	doc, err := crud.fetch(key)
+	if err != nil {
+		return 0, err
 	}
 
-	if err := json.Unmarshal(doc.Value, valuePtr); err != nil {
+	if err := crud.transcoder.Unmarshal(doc.value(), valuePtr); err != nil {
 		return 0, err
 	}
+	crud.recordRead(key, doc.size(), doc.Cas)
 
-	return doc.Cas, nil
+	return doc.reportedCas(crud.getTime()), nil
 }
 
 // Insert provides basic Insert Database Operation. It should be extended and wrapped with application level processes such as validation and serialisation.
 func (crud *CRUD) Insert(key string, value interface{}, expiry uint32) (uint64, error) {
-	if doc, ok := crud.storage[key]; ok {
+	return crud.InsertWith(key, value, WithExpiry(expiry))
+}
+
+// InsertCtx is Insert honouring the cancellation and deadline of ctx
+func (crud *CRUD) InsertCtx(ctx context.Context, key string, value interface{}, expiry uint32) (uint64, error) {
+	return crud.insertWith(ctx, key, value, WithExpiry(expiry))
+}
+
+// InsertWith is Insert configured by WriteOptions
+func (crud *CRUD) InsertWith(key string, value interface{}, opts ...WriteOption) (uint64, error) {
+	return crud.insertWith(context.Background(), key, value, opts...)
+}
+
+func (crud *CRUD) insertWith(ctx context.Context, key string, value interface{}, opts ...WriteOption) (uint64, error) {
+	release, err := crud.admitWrite(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	if err := crud.delay(ctx, OpInsert); err != nil {
+		return 0, err
+	}
+
+	s := crud.shardFor(key)
+	s.lock()
+	defer s.mu.Unlock()
+	return crud.insert(key, value, newWriteOptions(opts))
+}
+
+// insert stores a new document, it must be called with the write lock of the key's shard held
+func (crud *CRUD) insert(key string, value interface{}, o writeOptions) (uint64, error) {
+	if err := crud.validateKey(key); err != nil {
+		return 0, err
+	}
+	if doc, ok := crud.shardFor(key).docs[key]; ok {
+		if doc.locked(crud.getTime()) {
+			return 0, ErrDocumentLocked
+		}
 		return doc.Cas, ErrKeyExist
 	}
 
-	data, err := json.Marshal(value)
+	if err := o.precondition(nil); err != nil {
+		return 0, err
+	}
+
+	ttl, err := crud.resolveExpiry(o.expiry)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := crud.encode(value)
+	if err != nil {
+		return 0, err
+	}
+	unreserve, err := crud.reserveUnique(map[string][]byte{key: data})
+	if err != nil {
+		return 0, err
+	}
+	defer unreserve()
+	releaseQuota, err := crud.reserveQuota(1)
 	if err != nil {
 		return 0, err
 	}
+	defer releaseQuota()
 
-	doc := newDoc(data, expiry)
-	crud.storage[key] = doc
+	doc := newDoc(data, ttl)
+	crud.put(key, doc, EventInsert)
 
 	return doc.Cas, nil
 }
@@ -117,50 +546,175 @@ func (crud *CRUD) Insert(key string, value interface{}, expiry uint32) (uint64,
 // Upsert provides basic Upsert Database Operation. It should be extended and wrapped with application level processes such as validation and serialisation.
 // Upsert will also attempt to flush cache of the key if the database operation is successful.
 func (crud *CRUD) Upsert(key string, value interface{}, expiry uint32) (uint64, error) {
-	data, err := json.Marshal(value)
+	return crud.UpsertWith(key, value, WithExpiry(expiry))
+}
+
+// UpsertCtx is Upsert honouring the cancellation and deadline of ctx
+func (crud *CRUD) UpsertCtx(ctx context.Context, key string, value interface{}, expiry uint32) (uint64, error) {
+	res, err := crud.upsertWith(ctx, key, value, WithExpiry(expiry))
+	return res.Cas, err
+}
+
+// UpsertWith is Upsert configured by WriteOptions.
+// Updates of an existing document keep its expiry unless WithAbsoluteExpiry or WithRelativeExpiry is given
+// without WithPreserveExpiry.
+func (crud *CRUD) UpsertWith(key string, value interface{}, opts ...WriteOption) (uint64, error) {
+	res, err := crud.UpsertWithResult(key, value, opts...)
+	return res.Cas, err
+}
+
+// UpsertResult describes the outcome of an upsert
+type UpsertResult struct {
+	Cas uint64
+	// Created is true when the upsert stored a new document and false when it replaced an existing one
+	Created bool
+}
+
+// UpsertWithResult is UpsertWith reporting whether the document was created or replaced
+func (crud *CRUD) UpsertWithResult(key string, value interface{}, opts ...WriteOption) (UpsertResult, error) {
+	return crud.upsertWith(context.Background(), key, value, opts...)
+}
+
+func (crud *CRUD) upsertWith(ctx context.Context, key string, value interface{}, opts ...WriteOption) (UpsertResult, error) {
+	release, err := crud.admitWrite(ctx)
 	if err != nil {
-		return 0, err
+		return UpsertResult{}, err
+	}
+	defer release()
+	if err := crud.delay(ctx, OpUpsert); err != nil {
+		return UpsertResult{}, err
 	}
 
-	if doc, ok := crud.storage[key]; ok {
-		doc.set(data)
-		return doc.Cas, nil
+	s := crud.shardFor(key)
+	s.lock()
+	defer s.mu.Unlock()
+
+	if err := crud.validateKey(key); err != nil {
+		return UpsertResult{}, err
 	}
+	o := newWriteOptions(opts)
 
-	doc := newDoc(data, expiry)
-	crud.storage[key] = doc
-	return doc.Cas, nil
+	data, err := crud.encode(value)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+
+	// an expired document is replaced by a new one rather than updated
+	doc, err := crud.lookup(key)
+	if err != nil && err != ErrKeyNotExist {
+		return UpsertResult{}, err
+	}
+	ok := doc != nil
+	if err := o.precondition(doc); err != nil {
+		return UpsertResult{}, err
+	}
+
+	if ok && doc.locked(crud.getTime()) {
+		return UpsertResult{}, ErrDocumentLocked
+	}
+
+	// an existing document keeps its expiry unless a new one is given explicitly, so only an expiry
+	// which is applied is resolved
+	var ttl int64
+	if !ok || (o.expiry.explicit() && !o.preserveExpiry) {
+		if ttl, err = crud.resolveExpiry(o.expiry); err != nil {
+			return UpsertResult{}, err
+		}
+	}
+	unreserve, err := crud.reserveUnique(map[string][]byte{key: data})
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	defer unreserve()
+
+	if ok {
+		doc = doc.set(data)
+		if o.expiry.explicit() && !o.preserveExpiry {
+			doc.TTL = ttl
+		}
+		crud.put(key, doc, EventUpdate)
+		return UpsertResult{Cas: doc.Cas}, nil
+	}
+
+	releaseQuota, err := crud.reserveQuota(1)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	defer releaseQuota()
+
+	doc = newDoc(data, ttl)
+	crud.put(key, doc, EventInsert)
+	return UpsertResult{Cas: doc.Cas, Created: true}, nil
 }
 
 // Replace provides basic Replace Database Operation. It should be extended and wrapped with application level processes such as validation and serialisation.
 // Replace will also attempt to flush cache of the key if the database operation is successful.
 func (crud *CRUD) Replace(key string, value interface{}, cas uint64, expiry uint32) (uint64, error) {
-	doc, ok := crud.storage[key]
-	if !ok {
-		return doc.Cas, ErrKeyNotExist
+	return crud.ReplaceWith(key, value, cas, WithExpiry(expiry))
+}
+
+// ReplaceCtx is Replace honouring the cancellation and deadline of ctx
+func (crud *CRUD) ReplaceCtx(ctx context.Context, key string, value interface{}, cas uint64, expiry uint32) (uint64, error) {
+	return crud.replaceWith(ctx, key, value, cas, WithExpiry(expiry))
+}
+
+// ReplaceWith is Replace configured by WriteOptions
+func (crud *CRUD) ReplaceWith(key string, value interface{}, cas uint64, opts ...WriteOption) (uint64, error) {
+	return crud.replaceWith(context.Background(), key, value, cas, opts...)
+}
+
+func (crud *CRUD) replaceWith(ctx context.Context, key string, value interface{}, cas uint64, opts ...WriteOption) (uint64, error) {
+	release, err := crud.admitWrite(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	if err := crud.delay(ctx, OpReplace); err != nil {
+		return 0, err
 	}
 
-	// Very basic TTL support
-	if doc.TTL > 0 && doc.TTL < getTime() {
-		delete(crud.storage, key)
-		return 0, ErrKeyNotExist
+	s := crud.shardFor(key)
+	s.lock()
+	defer s.mu.Unlock()
+
+	o := newWriteOptions(opts)
+	doc, err := crud.lookup(key)
+	if err != nil {
+		return 0, err
 	}
 
-	// Check that the Cas on the request is accurate
+	// Check that the Cas on the request is accurate, only the lock holder may replace a locked document
 	if doc.Cas != cas {
+		if doc.locked(crud.getTime()) {
+			return 0, ErrDocumentLocked
+		}
 		return 0, ErrCasMismatch
 	}
 
-	data, err := json.Marshal(value)
+	if err := o.precondition(doc); err != nil {
+		return 0, err
+	}
+
+	ttl, err := crud.resolveExpiry(o.expiry)
 	if err != nil {
 		return 0, err
 	}
 
-	doc = newDoc(data, expiry)
-	// Manually insert the CAS value also tracking this op
-	cas++
-	doc.Cas = cas
-	crud.storage[key] = doc
+	data, err := crud.encode(value)
+	if err != nil {
+		return 0, err
+	}
+	unreserve, err := crud.reserveUnique(map[string][]byte{key: data})
+	if err != nil {
+		return 0, err
+	}
+	defer unreserve()
+
+	if o.preserveExpiry {
+		ttl = doc.TTL
+	}
+	doc = newDoc(data, ttl)
+	crud.put(key, doc, EventUpdate)
 
 	return doc.Cas, nil
 }
@@ -168,25 +722,80 @@ func (crud *CRUD) Replace(key string, value interface{}, cas uint64, expiry uint
 // Remove provides basic Remove Database Operation. It should be extended and wrapped with application level processes such as validation and serialisation.
 // Remove will also attempt to flush cache of the key if the database operation is successful.
 func (crud *CRUD) Remove(key string, cas uint64) (uint64, error) {
-	if _, exists := crud.storage[key]; !exists {
+	return crud.RemoveCtx(context.Background(), key, cas)
+}
+
+// RemoveCtx is Remove honouring the cancellation and deadline of ctx
+func (crud *CRUD) RemoveCtx(ctx context.Context, key string, cas uint64) (uint64, error) {
+	release, err := crud.admitWrite(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	if err := crud.delay(ctx, OpRemove); err != nil {
+		return 0, err
+	}
+
+	s := crud.shardFor(key)
+	s.lock()
+	defer s.mu.Unlock()
+
+	doc, exists := s.docs[key]
+	if !exists {
 		return 0, ErrKeyNotExist
 	}
 
-	if crud.storage[key].Cas == cas {
+	if doc.Cas == cas {
 		// skip expired data check here and just delete it all the same
-		delete(crud.storage, key)
+		crud.del(key, EventRemove)
 
 		return cas, nil
 	}
 
+	// only the lock holder may remove a locked document
+	if doc.locked(crud.getTime()) {
+		return 0, ErrDocumentLocked
+	}
 	return 0, ErrCasMismatch
 }
 
 // Touch updates the document expiry time.  Chaning the expiry time will also change the document's CAS value
 func (crud *CRUD) Touch(key string, cas uint64, expiry uint32) (uint64, error) {
-	doc, exists := crud.storage[key]
+	return crud.TouchWith(key, cas, WithExpiry(expiry))
+}
+
+// TouchCtx is Touch honouring the cancellation and deadline of ctx
+func (crud *CRUD) TouchCtx(ctx context.Context, key string, cas uint64, expiry uint32) (uint64, error) {
+	return crud.touchWith(ctx, key, cas, WithExpiry(expiry))
+}
+
+// TouchWith is Touch configured by WriteOptions
+func (crud *CRUD) TouchWith(key string, cas uint64, opts ...WriteOption) (uint64, error) {
+	return crud.touchWith(context.Background(), key, cas, opts...)
+}
+
+func (crud *CRUD) touchWith(ctx context.Context, key string, cas uint64, opts ...WriteOption) (uint64, error) {
+	release, err := crud.admitWrite(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	if err := crud.delay(ctx, OpTouch); err != nil {
+		return 0, err
+	}
+
+	s := crud.shardFor(key)
+	s.lock()
+	defer s.mu.Unlock()
+
+	o := newWriteOptions(opts)
+	doc, exists := s.docs[key]
 	if !exists {
-		return doc.Cas, ErrKeyNotExist
+		return 0, ErrKeyNotExist
+	}
+
+	if doc.locked(crud.getTime()) {
+		return 0, ErrDocumentLocked
 	}
 
 	// Check that the Cas on the request is accurate
@@ -194,22 +803,22 @@ func (crud *CRUD) Touch(key string, cas uint64, expiry uint32) (uint64, error) {
 		return 0, ErrCasMismatch
 	}
 
-	// Update the expiry
-	newTTL := int64(expiry)
-
-	// if the ttl value is larger than 0, but less than 30 days,  then assume it's a relative time
-	// and calculate it as such
-	if newTTL < ThirtyDaySeconds && newTTL > 0 {
-		newTTL = getTime() + newTTL
+	if err := crud.materialize(key, doc); err != nil {
+		return 0, err
+	}
+	if err := o.precondition(doc); err != nil {
+		return 0, err
 	}
-	// else assume that it's a Unix timestamp and set it directly
-	doc.TTL = newTTL
 
-	// FIXME: Should the CAS value be incremented for this op?
-	doc.Cas++
+	// Update the expiry
+	ttl, err := crud.resolveExpiry(o.expiry)
+	if err != nil {
+		return 0, err
+	}
+	doc.TTL = ttl
 
-	// Update the document in the 'db'
-	crud.storage[key] = doc
+	// Update the document in the 'db', which also changes its CAS
+	crud.put(key, doc, EventUpdate)
 
 	return doc.Cas, nil
 }
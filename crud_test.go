@@ -1,7 +1,10 @@
 package crud
 
 import (
+	"context"
 	"reflect"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 )
@@ -117,7 +120,8 @@ func TestRemove(t *testing.T) {
 }
 
 func TestTouch(t *testing.T) {
-	client := New()
+	clock := newManualClock()
+	client := New(WithClock(clock))
 	cas, _ := client.Insert("key", "val", 0)
 
 	cas2, err := client.Touch("key", cas, 1)
@@ -128,7 +132,7 @@ func TestTouch(t *testing.T) {
 		t.Fatal("cas mismatch")
 	}
 
-	time.Sleep(time.Second * 2)
+	clock.add(time.Second * 2)
 
 	var act string
 	_, err = client.Get("key", &act)
@@ -136,3 +140,100 @@ func TestTouch(t *testing.T) {
 		t.Fatal("error mismatch")
 	}
 }
+
+func TestUpsertWithResult(t *testing.T) {
+	client := New()
+
+	res, err := client.UpsertWithResult("key", "val", WithExpiry(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Created || res.Cas != 1 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+
+	res, err = client.UpsertWithResult("key", "val2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Created || res.Cas != 2 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	client := New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := strconv.Itoa(i % 4)
+			for j := 0; j < 200; j++ {
+				cas, _ := client.Upsert(key, j, 1)
+				var act int
+				_, _ = client.Get(key, &act)
+				_, _ = client.Replace(key, j+1, cas, 0)
+				_, _ = client.Touch(key, cas+1, 0)
+				if j%50 == 0 {
+					_, _ = client.Remove(key, cas+2)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestContextVariants(t *testing.T) {
+	client := New()
+	ctx := context.Background()
+
+	cas, err := client.InsertCtx(ctx, "key", "val", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cas, err = client.UpsertCtx(ctx, "key", "val2", 0); err != nil {
+		t.Fatal(err)
+	}
+	if cas, err = client.ReplaceCtx(ctx, "key", "val3", cas, 0); err != nil {
+		t.Fatal(err)
+	}
+	if cas, err = client.TouchCtx(ctx, "key", cas, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	var act string
+	if _, err := client.GetCtx(ctx, "key", &act); err != nil || act != "val3" {
+		t.Fatal("value mismatch")
+	}
+	if _, err := client.RemoveCtx(ctx, "key", cas); err != nil {
+		t.Fatal(err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := client.InsertCtx(cancelled, "key", "val", 0); !reflect.DeepEqual(err, context.Canceled) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.GetCtx(cancelled, "key", &act); !reflect.DeepEqual(err, context.Canceled) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestContextDeadlineWhileQueued(t *testing.T) {
+	client := New()
+	client.Pause()
+	defer client.Resume()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var act string
+	if _, err := client.GetCtx(ctx, "key", &act); !reflect.DeepEqual(err, context.DeadlineExceeded) {
+		t.Fatal("error mismatch")
+	}
+	if client.QueueDepth() != 0 {
+		t.Fatal("abandoned operation left in the queue")
+	}
+}
@@ -0,0 +1,106 @@
+// Package crudtest provides test assertions about the documents held by a crud store,
+// given as any crud.ReadStore such as a *crud.CRUD or a *crud.Reader.
+// Each assertion reports a failure with t.Errorf, so that a test keeps running, and returns
+// whether it held.
+package crudtest
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/jacygao/crud"
+)
+
+// AssertExists checks that a live document is stored under key
+func AssertExists(t testing.TB, store crud.ReadStore, key string) bool {
+	t.Helper()
+	exists, _, err := store.Exists(key)
+	if err != nil {
+		t.Errorf("checking %q exists: %v", key, err)
+		return false
+	}
+	if !exists {
+		t.Errorf("expected document %q to exist", key)
+		return false
+	}
+	return true
+}
+
+// AssertNotExists checks that no live document is stored under key
+func AssertNotExists(t testing.TB, store crud.ReadStore, key string) bool {
+	t.Helper()
+	exists, _, err := store.Exists(key)
+	if err != nil {
+		t.Errorf("checking %q exists: %v", key, err)
+		return false
+	}
+	if exists {
+		t.Errorf("expected document %q not to exist", key)
+		return false
+	}
+	return true
+}
+
+// AssertValue checks that the document stored under key decodes to want. The document is decoded
+// into a new value of the type of want, which is then compared to want with reflect.DeepEqual.
+func AssertValue(t testing.TB, store crud.ReadStore, key string, want interface{}) bool {
+	t.Helper()
+	if want == nil {
+		t.Errorf("AssertValue needs a non-nil value for %q", key)
+		return false
+	}
+	got := reflect.New(reflect.TypeOf(want))
+	if _, err := store.Get(key, got.Interface()); err != nil {
+		t.Errorf("getting %q: %v", key, err)
+		return false
+	}
+	if !reflect.DeepEqual(got.Elem().Interface(), want) {
+		t.Errorf("document %q is %#v, expected %#v", key, got.Elem().Interface(), want)
+		return false
+	}
+	return true
+}
+
+// AssertCount checks that the store holds n live documents
+func AssertCount(t testing.TB, store crud.ReadStore, n int) bool {
+	t.Helper()
+	if count := store.Count(); count != n {
+		t.Errorf("store holds %d documents, expected %d", count, n)
+		return false
+	}
+	return true
+}
+
+// AssertCAS checks that the document stored under key has the given CAS, which is crud.LockedCas
+// for documents locked by GetAndLock
+func AssertCAS(t testing.TB, store crud.ReadStore, key string, cas uint64) bool {
+	t.Helper()
+	exists, got, err := store.Exists(key)
+	if err != nil {
+		t.Errorf("getting the CAS of %q: %v", key, err)
+		return false
+	}
+	if !exists {
+		t.Errorf("expected document %q to exist", key)
+		return false
+	}
+	if got != cas {
+		t.Errorf("document %q has CAS %d, expected %d", key, got, cas)
+		return false
+	}
+	return true
+}
+
+// AssertKeys checks that the keys of the live documents are exactly keys, in any order
+func AssertKeys(t testing.TB, store crud.ReadStore, keys ...string) bool {
+	t.Helper()
+	want := append([]string(nil), keys...)
+	sort.Strings(want)
+	got := store.Keys()
+	if len(got) != len(want) || (len(got) > 0 && !reflect.DeepEqual(got, want)) {
+		t.Errorf("store holds keys %q, expected %q", got, want)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package crudtest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jacygao/crud"
+)
+
+// recorder is a testing.TB collecting the failures reported by an assertion
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+type user struct {
+	Name string `json:"name"`
+}
+
+func TestAssertions(t *testing.T) {
+	store := crud.New()
+	cas, _ := store.Insert("user::1", user{Name: "jo"}, 0)
+
+	passing := []func(tb testing.TB) bool{
+		func(tb testing.TB) bool { return AssertExists(tb, store, "user::1") },
+		func(tb testing.TB) bool { return AssertNotExists(tb, store, "user::2") },
+		func(tb testing.TB) bool { return AssertValue(tb, store, "user::1", user{Name: "jo"}) },
+		func(tb testing.TB) bool { return AssertCount(tb, store, 1) },
+		func(tb testing.TB) bool { return AssertCAS(tb, store, "user::1", cas) },
+		func(tb testing.TB) bool { return AssertKeys(tb, store, "user::1") },
+	}
+	for i, assert := range passing {
+		r := &recorder{TB: t}
+		if !assert(r) || len(r.errors) != 0 {
+			t.Fatalf("assertion %d failed: %v", i, r.errors)
+		}
+	}
+
+	failing := []func(tb testing.TB) bool{
+		func(tb testing.TB) bool { return AssertExists(tb, store, "user::2") },
+		func(tb testing.TB) bool { return AssertNotExists(tb, store, "user::1") },
+		func(tb testing.TB) bool { return AssertValue(tb, store, "user::1", user{Name: "al"}) },
+		func(tb testing.TB) bool { return AssertValue(tb, store, "user::2", user{}) },
+		func(tb testing.TB) bool { return AssertCount(tb, store, 2) },
+		func(tb testing.TB) bool { return AssertCAS(tb, store, "user::1", cas+1) },
+		func(tb testing.TB) bool { return AssertKeys(tb, store) },
+	}
+	for i, assert := range failing {
+		r := &recorder{TB: t}
+		if assert(r) || len(r.errors) != 1 {
+			t.Fatalf("assertion %d mismatch: %v", i, r.errors)
+		}
+	}
+
+	r := &recorder{TB: t}
+	AssertValue(r, store, "user::1", user{Name: "al"})
+	if exp := `document "user::1" is crudtest.user{Name:"jo"}, expected crudtest.user{Name:"al"}`; r.errors[0] != exp {
+		t.Fatalf("message mismatch: %s", r.errors[0])
+	}
+}
@@ -0,0 +1,61 @@
+package crud
+
+import (
+	"bytes"
+	"context"
+)
+
+// DiffEntry describes a document which differs between two stores
+type DiffEntry struct {
+	Key string
+	// Before is the document in the store Diff was called on, nil for added documents
+	Before *Document
+	// After is the document in the other store, nil for removed documents
+	After *Document
+}
+
+// StoreDiff lists, in key order, the documents which differ between two stores
+type StoreDiff struct {
+	Added   []DiffEntry
+	Removed []DiffEntry
+	// Changed holds the documents whose value differs
+	Changed []DiffEntry
+}
+
+// Empty reports whether the stores hold the same documents
+func (d StoreDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares the live documents of crud with those of other, reporting the documents added, removed
+// and changed to turn crud into other. Taken against a Clone made beforehand, it lists what the code
+// under test did to the store. Documents are compared by their encoded value, CAS and expiry changes alone
+// aren't reported.
+func (crud *CRUD) Diff(other *CRUD) (StoreDiff, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return StoreDiff{}, err
+	}
+	defer release()
+
+	before, after := crud.scan(nil), other.scan(nil)
+	var diff StoreDiff
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case j == len(after) || (i < len(before) && before[i].Key < after[j].Key):
+			diff.Removed = append(diff.Removed, DiffEntry{Key: before[i].Key, Before: &before[i]})
+			i++
+		case i == len(before) || after[j].Key < before[i].Key:
+			diff.Added = append(diff.Added, DiffEntry{Key: after[j].Key, After: &after[j]})
+			j++
+		default:
+			if !bytes.Equal(before[i].Value, after[j].Value) {
+				diff.Changed = append(diff.Changed, DiffEntry{Key: before[i].Key, Before: &before[i], After: &after[j]})
+			}
+			i++
+			j++
+		}
+	}
+	return diff, nil
+}
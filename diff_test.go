@@ -0,0 +1,41 @@
+package crud
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	client := New()
+	_, _ = client.Insert("a", "val", 0)
+	cas, _ := client.Insert("b", "val", 0)
+	_, _ = client.Insert("c", "val", 0)
+	before := client.Clone()
+
+	if diff, err := before.Diff(client); err != nil || !diff.Empty() {
+		t.Fatal("diff mismatch")
+	}
+
+	_, _ = client.Replace("b", "new", cas, 0)
+	_, _ = client.Remove("c", 1)
+	_, _ = client.Insert("d", "val", 0)
+	// CAS and expiry changes alone aren't reported
+	_, _ = client.Touch("a", 1, 60)
+
+	diff, err := before.Diff(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0].Key != "d" || diff.Added[0].Before != nil {
+		t.Fatal("added mismatch")
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Key != "c" || diff.Removed[0].After != nil {
+		t.Fatal("removed mismatch")
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Key != "b" {
+		t.Fatal("changed mismatch")
+	}
+	var old, cur string
+	_ = diff.Changed[0].Before.Content(&old)
+	_ = diff.Changed[0].After.Content(&cur)
+	if old != "val" || cur != "new" || diff.Changed[0].After.Cas != cas+1 {
+		t.Fatal("changed mismatch")
+	}
+}
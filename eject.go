@@ -0,0 +1,88 @@
+package crud
+
+import "errors"
+
+// ErrValueUnavailable is returned reading a document whose value was ejected to the backend, see WithMemoryLimit,
+// once the backend no longer holds it, as happens to the documents of a SnapshotView overwritten since.
+var ErrValueUnavailable = errors.New("ejected value no longer held by the backend")
+
+// ejectedValue stands for the value of a document only held by the backend
+type ejectedValue struct {
+	backend *backendState
+	key     string
+	// cas is the CAS of the document the value was ejected from
+	cas  uint64
+	size int
+}
+
+// read reads the value back from the backend, which must still hold the version it was ejected from
+func (e *ejectedValue) read() ([]byte, error) {
+	r, ok, err := e.backend.get(e.key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || r.Cas != e.cas {
+		return nil, ErrValueUnavailable
+	}
+	if r.Value == nil {
+		return []byte{}, nil
+	}
+	return r.Value, nil
+}
+
+// load returns the value of the document like value, returning the error met reading an ejected value
+func (d *document) load() ([]byte, error) {
+	if d.ejected != nil {
+		return d.ejected.read()
+	}
+	return d.value(), nil
+}
+
+// tiered reports whether evictions eject values to the backend rather than removing documents
+func (crud *CRUD) tiered() bool {
+	return crud.backend != nil && crud.backend.reader != nil && crud.eviction != nil && crud.eviction.limit > 0
+}
+
+// eject leaves the value of the document stored under key to the backend, unless the backend may not hold it.
+// It must be called with the write lock of the key's shard held.
+func (crud *CRUD) eject(key string) {
+	doc, ok := crud.shardFor(key).docs[key]
+	if !ok || doc.ejected != nil || doc.storedSize() == 0 || !crud.backend.intact() {
+		return
+	}
+	crud.memory.Add(-int64(doc.storedSize()))
+	doc.ejected = &ejectedValue{backend: crud.backend, key: key, cas: doc.Cas, size: doc.size()}
+	doc.Value, doc.packed, doc.codec, doc.rawSize = nil, nil, CompressionNone, 0
+	crud.counters.count(EventEvict)
+}
+
+// materialize reads the ejected value of doc, stored under key, back into memory.
+// It must be called with the write lock of the key's shard held.
+func (crud *CRUD) materialize(key string, doc *document) error {
+	if doc.ejected == nil {
+		return nil
+	}
+	value, err := doc.ejected.read()
+	if err != nil {
+		return err
+	}
+	doc.Value, doc.ejected = value, nil
+	crud.memory.Add(int64(len(value)))
+	crud.noteAccess(key)
+	return nil
+}
+
+// warm holds value, read back for the document stored under key, in memory again unless the document changed
+// since, evicting other values if that takes the store over its memory limit. It must be called without any
+// shard lock held.
+func (crud *CRUD) warm(key string, cas uint64, value []byte) {
+	s := crud.shardFor(key)
+	s.lock()
+	if doc, ok := s.docs[key]; ok && doc.ejected != nil && doc.Cas == cas {
+		doc.Value, doc.ejected = value, nil
+		crud.memory.Add(int64(len(value)))
+		crud.noteAccess(key)
+	}
+	s.mu.Unlock()
+	crud.evict()
+}
@@ -0,0 +1,183 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// readerBackend is a memBackend which can read single records
+type readerBackend struct {
+	*memBackend
+	gets int
+}
+
+func (b *readerBackend) Get(key string) (Record, bool, error) {
+	b.gets++
+	r, ok := b.records[key]
+	return r, ok, b.fail
+}
+
+// ejected reports whether the value of the document stored under key is only held by the backend
+func ejected(crud *CRUD, key string) bool {
+	s := crud.shardFor(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	doc, ok := s.docs[key]
+	return ok && doc.ejected != nil
+}
+
+// checkResident fails unless the keys and values held in memory are within limit
+func checkResident(t *testing.T, crud *CRUD, limit int64) {
+	t.Helper()
+	stats, _ := crud.Stats()
+	if stats.KeyBytes+stats.StoredValueBytes > limit || crud.memory.Load() != stats.KeyBytes+stats.StoredValueBytes {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestEjectValues(t *testing.T) {
+	// each document takes 2 bytes of key and 12 bytes of encoded value
+	b := &readerBackend{memBackend: newMemBackend()}
+	client, err := NewWithBackend(b, WithMemoryLimit(50))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, _ := client.Watch()
+	defer sub.Close()
+	for i := 0; i < 10; i++ {
+		_, _ = client.Insert(fmt.Sprintf("k%d", i), fmt.Sprintf("value-%04d", i), 0)
+	}
+	checkResident(t, client, 50)
+	if client.Count() != 10 {
+		t.Fatal("count mismatch")
+	}
+	if !ejected(client, "k0") || ejected(client, "k9") {
+		t.Fatal("ejection mismatch")
+	}
+	for i := 0; i < 10; i++ {
+		e := nextEvent(t, sub)
+		if e.Type != EventInsert {
+			t.Fatalf("unexpected event %v", e.Type)
+		}
+	}
+
+	// reading an ejected value holds it in memory again
+	var value string
+	if _, err := client.Get("k0", &value); err != nil || value != "value-0000" {
+		t.Fatal("value mismatch")
+	}
+	if ejected(client, "k0") || b.gets != 1 {
+		t.Fatal("k0 wasn't read back")
+	}
+	checkResident(t, client, 50)
+
+	// writes keep the value of ejected documents
+	var old []string
+	client.OnUpdate(func(key string, oldValue, newValue []byte, cas uint64) {
+		old = append(old, string(oldValue))
+	})
+	client.OnRemove(func(key string, oldValue, newValue []byte, cas uint64) {
+		old = append(old, string(oldValue))
+	})
+	meta, _ := client.GetMeta("k1")
+	if _, err := client.Touch("k1", meta.Cas, 100); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get("k1", &value); err != nil || value != "value-0001" {
+		t.Fatal("value mismatch")
+	}
+	meta, _ = client.GetMeta("k2")
+	if _, err := client.Replace("k2", "replaced", meta.Cas, 0); err != nil {
+		t.Fatal(err)
+	}
+	meta, _ = client.GetMeta("k3")
+	if _, err := client.Remove("k3", meta.Cas); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(old, []string{`"value-0001"`, `"value-0002"`, `"value-0003"`}) {
+		t.Fatalf("unexpected old values %v", old)
+	}
+	if _, ok := b.records["k3"]; ok {
+		t.Fatal("k3 wasn't removed from the backend")
+	}
+	checkResident(t, client, 50)
+
+	// loading from the backend holds values up to the limit
+	reopened, err := NewWithBackend(b, WithMemoryLimit(50))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResident(t, reopened, 50)
+	for _, key := range []string{"k0", "k4", "k9"} {
+		want := "value-000" + key[1:]
+		if _, err := reopened.Get(key, &value); err != nil || value != want {
+			t.Fatal("value mismatch")
+		}
+	}
+	if reopened.Count() != 9 {
+		t.Fatal("count mismatch")
+	}
+}
+
+func TestEjectValuesSnapshotView(t *testing.T) {
+	b := &readerBackend{memBackend: newMemBackend()}
+	client, _ := NewWithBackend(b, WithMemoryLimit(25))
+	cas, _ := client.Insert("a", "value-a", 0)
+	_, _ = client.Insert("b", "value-b", 0)
+	_, _ = client.Insert("c", "value-c", 0)
+	if !ejected(client, "a") {
+		t.Fatal("a wasn't ejected")
+	}
+
+	view := client.SnapshotView()
+	var value string
+	if _, err := view.Get("a", &value); err != nil || value != "value-a" {
+		t.Fatal("value mismatch")
+	}
+	_, _ = client.Replace("a", "changed", cas, 0)
+	if _, err := view.Get("a", &value); !reflect.DeepEqual(err, ErrValueUnavailable) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestEjectValuesBackendError(t *testing.T) {
+	b := &readerBackend{memBackend: newMemBackend()}
+	client, _ := NewWithBackend(b, WithMemoryLimit(25))
+	_, _ = client.Insert("a", "value-a", 0)
+	_, _ = client.Insert("b", "value-b", 0)
+	_, _ = client.Insert("c", "value-c", 0)
+
+	b.fail = fmt.Errorf("read failed")
+	if _, err := client.Get("a", new(string)); !reflect.DeepEqual(err, b.fail) {
+		t.Fatal("error mismatch")
+	}
+	// the value is lost to the store, so later operations fail too
+	if _, err := client.Get("c", new(string)); !reflect.DeepEqual(err, b.fail) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestEjectValuesCheckpoint(t *testing.T) {
+	b := &readerBackend{memBackend: newMemBackend()}
+	client, _ := NewWithBackend(b, WithMemoryLimit(25))
+	cas, _ := client.Insert("a", "value-a", 0)
+	_, _ = client.Insert("b", "value-b", 0)
+	_, _ = client.Insert("c", "value-c", 0)
+	cp, err := client.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = client.Replace("a", "changed", cas, 0)
+	if err := client.RollbackTo(cp); err != nil {
+		t.Fatal(err)
+	}
+	checkResident(t, client, 25)
+	var value string
+	if _, err := client.Get("a", &value); err != nil || value != "value-a" {
+		t.Fatal("value mismatch")
+	}
+	if r := b.records["a"]; string(r.Value) != `"value-a"` {
+		t.Fatal("record mismatch")
+	}
+}
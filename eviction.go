@@ -0,0 +1,323 @@
+package crud
+
+import (
+	"container/list"
+	"math/rand"
+	"sync"
+)
+
+// WithMemoryLimit caps the memory used by the documents of the store, counted as the total size in bytes of
+// their keys and stored values, at limit. Once a write takes the store over the limit, documents chosen by the
+// eviction policy, see WithEvictionPolicy, are evicted until it is back under it, which publishes an EventEvict
+// for each of them. Locked documents, and the document just written, can be evicted like any other.
+// A limit of zero or less means no limit.
+//
+// When the backend given to WithBackend is a RecordReader, only the values of the documents chosen are evicted:
+// they are left to the backend and read back, then held in memory again, when the document is next read or
+// written. Keys and metadata stay in memory, so no event is published. Loading the store from such a backend
+// holds values in memory up to the limit. Checkpoint and Clone read every evicted value back.
+func WithMemoryLimit(limit int64) Option {
+	return func(crud *CRUD) {
+		crud.evictionState().limit = limit
+	}
+}
+
+// WithEvictionPolicy sets the policy choosing the documents evicted once the store exceeds the limit set by
+// WithMemoryLimit, which is NewLRUPolicy by default. The policy must not be shared with other stores.
+func WithEvictionPolicy(policy EvictionPolicy) Option {
+	return func(crud *CRUD) {
+		crud.evictionState().policy = policy
+	}
+}
+
+// EvictionPolicy chooses the documents evicted to keep a store within its memory limit.
+// It is notified of the keys stored, read and removed, and must be safe for concurrent use.
+type EvictionPolicy interface {
+	// Accessed is called when the document of key is stored or read
+	Accessed(key string)
+	// Removed is called when the document of key is removed, including when it is evicted
+	Removed(key string)
+	// Victim returns the key of the document to evict next, false when there is none to evict
+	Victim() (string, bool)
+	// Reset forgets every key, it is called when the store is flushed or restored
+	Reset()
+	// New returns an empty policy of the same kind, used by Clone
+	New() EvictionPolicy
+}
+
+// evictionState holds the memory limit of a store and its eviction policy
+type evictionState struct {
+	limit  int64
+	policy EvictionPolicy
+	// mu serialises evictions
+	mu sync.Mutex
+}
+
+// evictionState returns the eviction state of the store, creating it with an LRU policy if needed
+func (crud *CRUD) evictionState() *evictionState {
+	if crud.eviction == nil {
+		crud.eviction = &evictionState{policy: NewLRUPolicy()}
+	}
+	return crud.eviction
+}
+
+// memorySize is the memory accounted to the document stored under key
+func memorySize(key string, doc *document) int64 {
+	return int64(len(key) + doc.storedSize())
+}
+
+// noteAccess notifies the eviction policy, if any, that key was accessed
+func (crud *CRUD) noteAccess(key string) {
+	if crud.eviction != nil {
+		crud.eviction.policy.Accessed(key)
+	}
+}
+
+// noteRemoval notifies the eviction policy, if any, that key was removed
+func (crud *CRUD) noteRemoval(key string) {
+	if crud.eviction != nil {
+		crud.eviction.policy.Removed(key)
+	}
+}
+
+// resetEviction notifies the eviction policy, if any, that every document was removed
+func (crud *CRUD) resetEviction() {
+	if crud.eviction != nil {
+		crud.eviction.policy.Reset()
+	}
+}
+
+// evict removes the documents, or ejects the values, chosen by the eviction policy until the store is within its memory limit.
+// It must be called without any shard lock held, admitWrite calls it once a write is done.
+func (crud *CRUD) evict() {
+	e := crud.eviction
+	if e == nil || e.limit <= 0 || crud.memory.Load() <= e.limit {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for crud.memory.Load() > e.limit {
+		key, ok := e.policy.Victim()
+		if !ok {
+			return
+		}
+		s := crud.shardFor(key)
+		s.lock()
+		if crud.tiered() {
+			crud.eject(key)
+		} else {
+			crud.del(key, EventEvict)
+		}
+		s.mu.Unlock()
+		// the document may have gone before the shard was locked
+		e.policy.Removed(key)
+	}
+}
+
+// lruPolicy orders keys from the least to the most recently accessed
+type lruPolicy struct {
+	mu    sync.Mutex
+	order *list.List
+	elems map[string]*list.Element
+}
+
+// NewLRUPolicy returns an EvictionPolicy evicting the least recently stored or read documents first
+func NewLRUPolicy() EvictionPolicy {
+	return &lruPolicy{order: list.New(), elems: make(map[string]*list.Element)}
+}
+
+func (p *lruPolicy) Accessed(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.elems[key]; ok {
+		p.order.MoveToBack(e)
+		return
+	}
+	p.elems[key] = p.order.PushBack(key)
+}
+
+func (p *lruPolicy) Removed(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.elems[key]; ok {
+		p.order.Remove(e)
+		delete(p.elems, key)
+	}
+}
+
+func (p *lruPolicy) Victim() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e := p.order.Front()
+	if e == nil {
+		return "", false
+	}
+	return e.Value.(string), true
+}
+
+func (p *lruPolicy) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.order.Init()
+	p.elems = make(map[string]*list.Element)
+}
+
+func (p *lruPolicy) New() EvictionPolicy {
+	return NewLRUPolicy()
+}
+
+// keySet is a set of keys which can be picked from by position
+type keySet struct {
+	keys []string
+	pos  map[string]int
+}
+
+func newKeySet() keySet {
+	return keySet{pos: make(map[string]int)}
+}
+
+// add adds key, returning false when it is already in the set
+func (s *keySet) add(key string) bool {
+	if _, ok := s.pos[key]; ok {
+		return false
+	}
+	s.pos[key] = len(s.keys)
+	s.keys = append(s.keys, key)
+	return true
+}
+
+// remove removes key by moving the last key in its place, returning the position it had or -1
+func (s *keySet) remove(key string) int {
+	i, ok := s.pos[key]
+	if !ok {
+		return -1
+	}
+	last := len(s.keys) - 1
+	s.keys[i] = s.keys[last]
+	s.pos[s.keys[i]] = i
+	s.keys = s.keys[:last]
+	delete(s.pos, key)
+	return i
+}
+
+// nruPolicy is a clock: a hand sweeps the keys, clearing their referenced bit, and stops at the first key
+// which wasn't referenced since the hand last passed it
+type nruPolicy struct {
+	mu         sync.Mutex
+	set        keySet
+	referenced map[string]bool
+	hand       int
+}
+
+// NewNRUPolicy returns an EvictionPolicy evicting documents which weren't stored or read recently, using the
+// clock algorithm. It approximates NewLRUPolicy, without ordering the keys on every access.
+func NewNRUPolicy() EvictionPolicy {
+	return &nruPolicy{set: newKeySet(), referenced: make(map[string]bool)}
+}
+
+func (p *nruPolicy) Accessed(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.set.add(key)
+	p.referenced[key] = true
+}
+
+func (p *nruPolicy) Removed(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.set.remove(key)
+	delete(p.referenced, key)
+}
+
+func (p *nruPolicy) Victim() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	keys := p.set.keys
+	if len(keys) == 0 {
+		return "", false
+	}
+	// every key is found unreferenced within two sweeps
+	for {
+		if p.hand >= len(keys) {
+			p.hand = 0
+		}
+		key := keys[p.hand]
+		if !p.referenced[key] {
+			return key, true
+		}
+		p.referenced[key] = false
+		p.hand++
+	}
+}
+
+func (p *nruPolicy) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.set = newKeySet()
+	p.referenced = make(map[string]bool)
+	p.hand = 0
+}
+
+func (p *nruPolicy) New() EvictionPolicy {
+	return NewNRUPolicy()
+}
+
+// randomPolicy picks victims uniformly at random
+type randomPolicy struct {
+	mu   sync.Mutex
+	seed int64
+	rnd  *rand.Rand
+	set  keySet
+}
+
+// NewRandomPolicy returns an EvictionPolicy evicting documents picked at random, ignoring their accesses.
+// Victims are picked by a generator seeded with seed, so a sequence of writes evicts the same documents.
+func NewRandomPolicy(seed int64) EvictionPolicy {
+	return &randomPolicy{seed: seed, rnd: rand.New(rand.NewSource(seed)), set: newKeySet()}
+}
+
+func (p *randomPolicy) Accessed(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.set.add(key)
+}
+
+func (p *randomPolicy) Removed(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.set.remove(key)
+}
+
+func (p *randomPolicy) Victim() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.set.keys) == 0 {
+		return "", false
+	}
+	return p.set.keys[p.rnd.Intn(len(p.set.keys))], true
+}
+
+func (p *randomPolicy) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.set = newKeySet()
+}
+
+func (p *randomPolicy) New() EvictionPolicy {
+	return NewRandomPolicy(p.seed)
+}
+
+// noEviction never evicts
+type noEviction struct{}
+
+// NoEviction returns an EvictionPolicy which never evicts, so that the store grows past its memory limit.
+// It simulates a cache without eviction, whose usage can be watched with Stats.
+func NoEviction() EvictionPolicy {
+	return noEviction{}
+}
+
+func (noEviction) Accessed(string)        {}
+func (noEviction) Removed(string)         {}
+func (noEviction) Victim() (string, bool) { return "", false }
+func (noEviction) Reset()                 {}
+func (noEviction) New() EvictionPolicy    { return noEviction{} }
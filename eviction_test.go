@@ -0,0 +1,151 @@
+package crud
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestMemoryLimit(t *testing.T) {
+	// each document takes 1 byte of key and 3 bytes of encoded value
+	client := New(WithMemoryLimit(12))
+	sub, _ := client.Watch()
+	defer sub.Close()
+
+	_, _ = client.Insert("a", "x", 0)
+	_, _ = client.Insert("b", "x", 0)
+	_, _ = client.Insert("c", "x", 0)
+	// reading a makes b the least recently used
+	_, _ = client.Get("a", new(string))
+	_, _ = client.Insert("d", "x", 0)
+
+	if _, err := client.Get("b", new(string)); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if ok, _, _ := client.Exists(key); !ok {
+			t.Fatalf("%s was evicted", key)
+		}
+	}
+
+	var evicted []string
+	for i := 0; i < 5; i++ {
+		if e := nextEvent(t, sub); e.Type == EventEvict {
+			evicted = append(evicted, e.Key)
+		}
+	}
+	if !reflect.DeepEqual(evicted, []string{"b"}) {
+		t.Fatalf("unexpected evictions %v", evicted)
+	}
+
+	stats, _ := client.Stats()
+	if stats.Evictions != 1 || stats.KeyBytes+stats.ValueBytes != 12 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestMemoryLimitUpdate(t *testing.T) {
+	client := New(WithMemoryLimit(9))
+	var evicted []string
+	client.OnEvict(func(key string, oldValue, newValue []byte, cas uint64) {
+		evicted = append(evicted, key)
+	})
+
+	_, _ = client.Insert("a", "x", 0)
+	_, _ = client.Insert("b", "y", 0)
+	// growing b takes the store over the limit
+	_, _ = client.Upsert("b", "yyy", 0)
+	if !reflect.DeepEqual(evicted, []string{"a"}) {
+		t.Fatalf("unexpected evictions %v", evicted)
+	}
+
+	// a document larger than the limit is evicted once written
+	_, _ = client.Upsert("c", "zzzzzzzzzz", 0)
+	if client.Count() != 0 || !reflect.DeepEqual(evicted, []string{"a", "b", "c"}) {
+		t.Fatal("count mismatch")
+	}
+}
+
+func TestMemoryLimitFlush(t *testing.T) {
+	client := New(WithMemoryLimit(8))
+	_, _ = client.Insert("a", "x", 0)
+	_, _ = client.Insert("b", "x", 0)
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = client.Insert("c", "x", 0)
+	_, _ = client.Insert("d", "x", 0)
+	if client.Count() != 2 {
+		t.Fatal("count mismatch")
+	}
+}
+
+func TestEvictionPolicies(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		policy  EvictionPolicy
+		evicted string
+	}{
+		{"lru", NewLRUPolicy(), "b"},
+		// the hand clears every referenced bit, then stops at a
+		{"nru", NewNRUPolicy(), "a"},
+		{"none", NoEviction(), ""},
+	} {
+		client := New(WithEvictionPolicy(c.policy), WithMemoryLimit(12))
+		_, _ = client.Insert("a", "x", 0)
+		_, _ = client.Insert("b", "x", 0)
+		_, _ = client.Insert("c", "x", 0)
+		_, _ = client.Get("a", new(string))
+		_, _ = client.Insert("d", "x", 0)
+
+		var gone []string
+		for _, key := range []string{"a", "b", "c", "d"} {
+			if ok, _, _ := client.Exists(key); !ok {
+				gone = append(gone, key)
+			}
+		}
+		if c.evicted == "" {
+			if len(gone) != 0 {
+				t.Fatalf("%s: unexpected evictions %v", c.name, gone)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(gone, []string{c.evicted}) {
+			t.Fatalf("%s: unexpected evictions %v", c.name, gone)
+		}
+	}
+}
+
+func TestRandomPolicy(t *testing.T) {
+	evicted := func() []string {
+		client := New(WithMemoryLimit(20), WithEvictionPolicy(NewRandomPolicy(1)))
+		var keys []string
+		client.OnEvict(func(key string, oldValue, newValue []byte, cas uint64) {
+			keys = append(keys, key)
+		})
+		_ = client.Seeder().Keys("%d").Values(func(i int, rnd *rand.Rand) interface{} { return "x" }).Insert(10)
+		return keys
+	}
+
+	first := evicted()
+	if len(first) != 5 {
+		t.Fatalf("unexpected evictions %v", first)
+	}
+	if !reflect.DeepEqual(evicted(), first) {
+		t.Fatal("evictions mismatch")
+	}
+}
+
+func TestClonePolicy(t *testing.T) {
+	client := New(WithMemoryLimit(8))
+	_, _ = client.Insert("a", "x", 0)
+	clone := client.Clone()
+	_, _ = clone.Insert("b", "x", 0)
+	_, _ = clone.Insert("c", "x", 0)
+	if clone.Count() != 2 || client.Count() != 1 {
+		t.Fatal("count mismatch")
+	}
+	if ok, _, _ := clone.Exists("a"); ok {
+		t.Fatal("a wasn't evicted")
+	}
+}
@@ -0,0 +1,256 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// ErrInvalidExpiry defines the error value returned when a write requests an expiry in the past
+// or too far in the future to be represented
+var ErrInvalidExpiry = errors.New("invalid expiry")
+
+// ErrMaxTTLExceeded defines the error value returned when a write requests an expiry beyond the store's
+// maximum TTL and the store is configured with MaxTTLReject
+var ErrMaxTTLExceeded = errors.New("expiry exceeds maximum TTL")
+
+// MaxTTLPolicy controls what happens to writes requesting an expiry beyond the store's maximum TTL
+type MaxTTLPolicy int
+
+const (
+	// MaxTTLClamp shortens the expiry to the maximum TTL
+	MaxTTLClamp MaxTTLPolicy = iota
+	// MaxTTLReject fails the write with ErrMaxTTLExceeded
+	MaxTTLReject
+)
+
+// WithMaxTTL caps the expiry of every write to max from now, mirroring the bucket-level max TTL of Couchbase.
+// Expiries beyond the cap are clamped or rejected according to policy. Like Couchbase, documents written
+// without an expiry are given the maximum TTL under either policy. A max of zero means no cap.
+func WithMaxTTL(max time.Duration, policy MaxTTLPolicy) Option {
+	return func(crud *CRUD) {
+		crud.maxTTL = max
+		crud.maxTTLPolicy = policy
+	}
+}
+
+// WithDefaultTTL expires documents written without an expiry after ttl, mirroring the bucket-level
+// default TTL of Couchbase. Jitter applies to it as to any relative expiry, and it is clamped to the
+// maximum TTL whatever the MaxTTLPolicy.
+// A ttl of zero leaves such documents to never expire.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(crud *CRUD) {
+		crud.defaultTTL = ttl
+	}
+}
+
+// WithExpiryThreshold sets the largest expiry value which is interpreted as a number of seconds relative
+// to now; larger values are interpreted as Unix timestamps. The default is ThirtyDaySeconds, matching Couchbase.
+// A threshold of zero treats every value as a Unix timestamp and math.MaxUint32 treats every value as relative.
+func WithExpiryThreshold(threshold uint32) Option {
+	return func(crud *CRUD) {
+		crud.threshold = threshold
+	}
+}
+
+// expiryKind describes how an expirySpec should be interpreted
+type expiryKind int
+
+const (
+	// expiryLegacy interprets a uint32 according to the store's expiry threshold
+	expiryLegacy expiryKind = iota
+	// expiryAbsolute expires the document at a point in time
+	expiryAbsolute
+	// expiryRelative expires the document after a duration
+	expiryRelative
+)
+
+// expirySpec holds the expiry requested by a write operation
+type expirySpec struct {
+	kind     expiryKind
+	legacy   uint32
+	at       time.Time
+	duration time.Duration
+}
+
+// explicit reports whether the expiry was given with WithAbsoluteExpiry or WithRelativeExpiry
+func (e expirySpec) explicit() bool {
+	return e.kind != expiryLegacy
+}
+
+func legacyExpiry(expiry uint32) expirySpec {
+	return expirySpec{kind: expiryLegacy, legacy: expiry}
+}
+
+// WithExpiry sets the expiry of a write using the uint32 convention of Insert and friends:
+// zero never expires, values up to the store's expiry threshold are relative seconds and
+// larger values are Unix timestamps.
+func WithExpiry(expiry uint32) WriteOption {
+	return func(o *writeOptions) {
+		o.expiry = legacyExpiry(expiry)
+	}
+}
+
+// WithAbsoluteExpiry expires the document at t, bypassing the expiry threshold heuristic.
+// A zero time never expires.
+func WithAbsoluteExpiry(t time.Time) WriteOption {
+	return func(o *writeOptions) {
+		o.expiry = expirySpec{kind: expiryAbsolute, at: t}
+	}
+}
+
+// WithRelativeExpiry expires the document after d, bypassing the expiry threshold heuristic.
+// Sub-second durations are honoured. A zero duration never expires.
+func WithRelativeExpiry(d time.Duration) WriteOption {
+	return func(o *writeOptions) {
+		o.expiry = expirySpec{kind: expiryRelative, duration: d}
+	}
+}
+
+// WithPreserveExpiry keeps the expiry of the document updated by Replace or Upsert, ignoring any expiry
+// given with it, like the preserve_expiry flag of Couchbase. Documents created by Upsert use the expiry given.
+func WithPreserveExpiry() WriteOption {
+	return func(o *writeOptions) {
+		o.preserveExpiry = true
+	}
+}
+
+// resolveExpiry converts a requested expiry into the absolute Unix time stored on the document.
+// It returns ErrInvalidExpiry for absolute times in the past, negative durations and expiries
+// beyond the range of the 32 bit expiry field used by Couchbase, and applies the store's default and maximum TTL.
+func (crud *CRUD) resolveExpiry(e expirySpec) (int64, error) {
+	ttl, err := crud.requestedExpiry(e)
+	if err != nil {
+		return 0, err
+	}
+	// the default TTL isn't requested by the write, so it is clamped rather than rejected
+	implicit := ttl == 0
+	if implicit && crud.defaultTTL > 0 {
+		if ttl, err = crud.relativeExpiry(crud.defaultTTL); err != nil {
+			return 0, err
+		}
+	}
+	if crud.maxTTL <= 0 {
+		return ttl, nil
+	}
+
+	limit := crud.now().Add(crud.maxTTL).UnixNano()
+	switch {
+	case ttl == 0:
+		return limit, nil
+	case ttl <= limit:
+		return ttl, nil
+	case crud.maxTTLPolicy == MaxTTLReject && !implicit:
+		return 0, ErrMaxTTLExceeded
+	}
+	return limit, nil
+}
+
+// requestedExpiry converts a requested expiry into an absolute Unix time, ignoring the maximum TTL
+func (crud *CRUD) requestedExpiry(e expirySpec) (int64, error) {
+	switch e.kind {
+	case expiryAbsolute:
+		if e.at.IsZero() {
+			return 0, nil
+		}
+		return crud.validAbsolute(e.at)
+	case expiryRelative:
+		if e.duration < 0 {
+			return 0, ErrInvalidExpiry
+		}
+		if e.duration == 0 {
+			return 0, nil
+		}
+		return crud.relativeExpiry(e.duration)
+	}
+
+	ttl := int64(e.legacy)
+	if ttl == 0 {
+		return 0, nil
+	}
+
+	// if the ttl value is larger than 0, but no more than the threshold (30 days by default), then assume
+	// it's a relative time and calculate it as such
+	if ttl <= int64(crud.threshold) {
+		return crud.relativeExpiry(time.Duration(ttl) * time.Second)
+	}
+	// else assume that it's a Unix timestamp and set it directly
+
+	return crud.validAbsolute(time.Unix(ttl, 0))
+}
+
+// validAbsolute checks that an absolute expiry is neither in the past nor out of range,
+// returning it in Unix nanoseconds
+func (crud *CRUD) validAbsolute(at time.Time) (int64, error) {
+	if at.Before(crud.now()) || at.Unix() > math.MaxUint32 {
+		return 0, ErrInvalidExpiry
+	}
+	return at.UnixNano(), nil
+}
+
+// relativeExpiry returns the Unix time in nanoseconds ttl from now, applying any configured jitter
+func (crud *CRUD) relativeExpiry(ttl time.Duration) (int64, error) {
+	if ttl > math.MaxUint32*time.Second {
+		return 0, ErrInvalidExpiry
+	}
+	if crud.jitter != nil {
+		ttl = crud.jitter.apply(ttl)
+	}
+	return crud.validAbsolute(crud.now().Add(ttl))
+}
+
+// GetWithExpiry provides the Get Database Operation, also returning the absolute expiry stored for the document.
+// The expiry is the zero time when the document never expires.
+func (crud *CRUD) GetWithExpiry(key string, valuePtr interface{}) (uint64, time.Time, error) {
+	meta, err := crud.GetWithMeta(key, valuePtr)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return meta.Cas, meta.Expiry, nil
+}
+
+// ExpiredKeys returns, in lexicographic order, the keys of documents whose TTL has passed
+// but which have not yet been purged from the store. Expired documents are purged lazily
+// when they are next accessed, so these keys are logically expired but still physically stored.
+func (crud *CRUD) ExpiredKeys() []string {
+	now := crud.getTime()
+	var keys []string
+	crud.rangeDocs(func(key string, doc *document) {
+		if doc.expired(now) {
+			keys = append(keys, key)
+		}
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+// PurgeExpired removes every expired document still stored and returns how many were removed.
+// Each removal publishes an EventExpire and fires the OnExpire hooks, like the lazy purge made
+// when an expired document is accessed.
+func (crud *CRUD) PurgeExpired() (int, error) {
+	release, err := crud.admitWrite(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	return crud.purgeExpired(), nil
+}
+
+// purgeExpired removes every expired document, locking one shard at a time
+func (crud *CRUD) purgeExpired() int {
+	purged := 0
+	for _, s := range crud.shards {
+		s.lock()
+		now := crud.getTime()
+		for key, doc := range s.docs {
+			if doc.expired(now) {
+				crud.del(key, EventExpire)
+				purged++
+			}
+		}
+		s.mu.Unlock()
+	}
+	return purged
+}
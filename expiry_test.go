@@ -0,0 +1,371 @@
+package crud
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// expiresIn returns the time left before the document stored under key expires
+func expiresIn(client *CRUD, key string) time.Duration {
+	return time.Duration(stored(client, key).TTL - client.getTime())
+}
+
+// expireKey moves the expiry of key into the past without purging the document.
+// Secondary indexes are updated as they would be had the document been written with that expiry.
+func expireKey(client *CRUD, key string) {
+	doc := stored(client, key)
+	doc.TTL = client.getTime() - 1
+	client.indexes.update(key, doc)
+}
+
+func TestExpiredKeys(t *testing.T) {
+	client := New()
+	_, _ = client.Insert("b", "val", 0)
+	_, _ = client.Insert("a", "val", 0)
+	_, _ = client.Insert("live", "val", 0)
+	expireKey(client, "a")
+	expireKey(client, "b")
+
+	if keys := client.ExpiredKeys(); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Fatalf("unexpected expired keys %v", keys)
+	}
+
+	// reading an expired document purges it
+	var act string
+	_, err := client.Get("a", &act)
+	if !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+
+	if keys := client.ExpiredKeys(); !reflect.DeepEqual(keys, []string{"b"}) {
+		t.Fatalf("unexpected expired keys %v", keys)
+	}
+}
+
+func TestExpiryThreshold(t *testing.T) {
+	// a backend which treats every value as relative seconds
+	client := New(WithExpiryThreshold(math.MaxUint32))
+	_, _ = client.Insert("key", "val", ThirtyDaySeconds*2)
+
+	if ttl := expiresIn(client, "key"); ttl < ThirtyDaySeconds*2*time.Second-time.Second || ttl > ThirtyDaySeconds*2*time.Second {
+		t.Fatalf("expected relative expiry, got ttl %s", ttl)
+	}
+
+	// a backend which treats every value as a Unix timestamp
+	client = New(WithExpiryThreshold(0))
+	at := uint32(client.now().Unix() + 60)
+	_, _ = client.Insert("key", "val", at)
+	if stored(client, "key").TTL != time.Unix(int64(at), 0).UnixNano() {
+		t.Fatal("expected absolute expiry")
+	}
+
+	// the threshold itself is relative, the next value absolute
+	client = New()
+	client.FreezeTime()
+	_, _ = client.Insert("rel", "val", ThirtyDaySeconds)
+	if expiresIn(client, "rel") != ThirtyDaySeconds*time.Second {
+		t.Fatal("expected relative expiry")
+	}
+	if _, err := client.Insert("abs", "val", ThirtyDaySeconds+1); !reflect.DeepEqual(err, ErrInvalidExpiry) {
+		t.Fatal("expected absolute expiry")
+	}
+}
+
+func TestExplicitExpiry(t *testing.T) {
+	client := New()
+	at := time.Now().Add(time.Hour)
+
+	_, err := client.InsertWith("abs", "val", WithAbsoluteExpiry(at))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored(client, "abs").TTL != at.UnixNano() {
+		t.Fatal("absolute expiry mismatch")
+	}
+
+	// longer than the 30 day threshold but still relative
+	cas, err := client.InsertWith("rel", "val", WithRelativeExpiry(60*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := expiresIn(client, "rel"); ttl < 60*24*time.Hour-time.Second || ttl > 60*24*time.Hour {
+		t.Fatalf("relative expiry mismatch, ttl %s", ttl)
+	}
+
+	_, err = client.TouchWith("rel", cas, WithAbsoluteExpiry(at))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored(client, "rel").TTL != at.UnixNano() {
+		t.Fatal("touch expiry mismatch")
+	}
+
+	_, err = client.UpsertWith("rel", "val2", WithRelativeExpiry(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored(client, "rel").TTL != 0 {
+		t.Fatal("upsert expiry mismatch")
+	}
+
+	_, err = client.ReplaceWith("abs", "val2", 1, WithRelativeExpiry(1500*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := expiresIn(client, "abs"); ttl < time.Second || ttl > 1500*time.Millisecond {
+		t.Fatalf("replace expiry mismatch, ttl %s", ttl)
+	}
+}
+
+func TestInvalidExpiry(t *testing.T) {
+	client := New()
+	past := time.Now().Add(-time.Hour)
+
+	cases := map[string]WriteOption{
+		"absolute in the past":  WithAbsoluteExpiry(past),
+		"legacy in the past":    WithExpiry(uint32(past.Unix())),
+		"negative duration":     WithRelativeExpiry(-time.Second),
+		"beyond 32 bit expiry":  WithAbsoluteExpiry(time.Unix(math.MaxUint32+1, 0)),
+		"relative out of range": WithRelativeExpiry(200 * 365 * 24 * time.Hour),
+	}
+	for name, opt := range cases {
+		_, err := client.InsertWith("key", "val", opt)
+		if !reflect.DeepEqual(err, ErrInvalidExpiry) {
+			t.Fatalf("%s: error mismatch %v", name, err)
+		}
+	}
+
+	// rejected writes leave the store untouched
+	if stored(client, "key") != nil {
+		t.Fatal("invalid write was stored")
+	}
+
+	cas, _ := client.Insert("key", "val", 0)
+	_, err := client.TouchWith("key", cas, WithAbsoluteExpiry(past))
+	if !reflect.DeepEqual(err, ErrInvalidExpiry) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestGetWithExpiry(t *testing.T) {
+	client := New()
+	at := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	cas, _ := client.InsertWith("key", "val", WithAbsoluteExpiry(at))
+	var act string
+	actCas, expiry, err := client.GetWithExpiry("key", &act)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actCas != cas || act != "val" {
+		t.Fatal("results mismatch")
+	}
+	if !expiry.Equal(at) {
+		t.Fatalf("expiry mismatch %v", expiry)
+	}
+
+	// touching the document changes the persisted expiry
+	cas, _ = client.TouchWith("key", cas, WithAbsoluteExpiry(at.Add(time.Hour)))
+	if _, expiry, _ = client.GetWithExpiry("key", &act); !expiry.Equal(at.Add(time.Hour)) {
+		t.Fatal("touched expiry mismatch")
+	}
+
+	_, _ = client.Touch("key", cas, 0)
+	if _, expiry, _ = client.GetWithExpiry("key", &act); !expiry.IsZero() {
+		t.Fatal("expected no expiry")
+	}
+}
+
+func TestExpiryCallbacks(t *testing.T) {
+	client := New()
+	var expired []string
+	client.OnExpire(func(key string, oldValue, newValue []byte, cas uint64) {
+		expired = append(expired, key+"="+string(oldValue))
+	})
+
+	for _, key := range []string{"a", "b", "c", "live"} {
+		client.Insert(key, key, 0)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		expireKey(client, key)
+	}
+
+	// a lazy purge on access fires the callback
+	var v string
+	if _, err := client.Get("a", &v); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+	if !reflect.DeepEqual(expired, []string{`a="a"`}) {
+		t.Fatal("callbacks mismatch")
+	}
+
+	purged, err := client.PurgeExpired()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 2 || client.ExpiredKeys() != nil {
+		t.Fatal("purge mismatch")
+	}
+	sort.Strings(expired)
+	if !reflect.DeepEqual(expired, []string{`a="a"`, `b="b"`, `c="c"`}) {
+		t.Fatal("callbacks mismatch")
+	}
+	if client.Count() != 1 {
+		t.Fatal("count mismatch")
+	}
+}
+
+func TestSubSecondExpiry(t *testing.T) {
+	clock := newManualClock()
+	client := New(WithClock(clock))
+	_, _ = client.InsertWith("key", "val", WithRelativeExpiry(100*time.Millisecond))
+
+	_, expiry, _ := client.GetWithExpiry("key", new(string))
+	if !expiry.Equal(clock.Now().Add(100 * time.Millisecond)) {
+		t.Fatal("expiry mismatch")
+	}
+
+	clock.add(99 * time.Millisecond)
+	if _, err := client.Get("key", new(string)); err != nil {
+		t.Fatal(err)
+	}
+	clock.add(time.Millisecond)
+	if _, err := client.Get("key", new(string)); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestMaxTTL(t *testing.T) {
+	clock := newManualClock()
+	client := New(WithClock(clock), WithMaxTTL(time.Hour, MaxTTLClamp))
+	limit := clock.Now().Add(time.Hour).UnixNano()
+
+	_, _ = client.Insert("short", "val", 60)
+	if expiresIn(client, "short") != time.Minute {
+		t.Fatal("expiry mismatch")
+	}
+	cas, _ := client.InsertWith("long", "val", WithRelativeExpiry(2*time.Hour))
+	if stored(client, "long").TTL != limit {
+		t.Fatal("expiry mismatch")
+	}
+	// documents without an expiry are given the maximum TTL
+	_, _ = client.Insert("none", "val", 0)
+	if stored(client, "none").TTL != limit {
+		t.Fatal("expiry mismatch")
+	}
+	if _, err := client.Touch("long", cas, 0); err != nil || stored(client, "long").TTL != limit {
+		t.Fatal("expiry mismatch")
+	}
+
+	client = New(WithClock(clock), WithMaxTTL(time.Hour, MaxTTLReject))
+	if _, err := client.Insert("long", "val", 7200); !reflect.DeepEqual(err, ErrMaxTTLExceeded) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.InsertWith("exact", "val", WithRelativeExpiry(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = client.Insert("none", "val", 0)
+	if expiresIn(client, "none") != time.Hour {
+		t.Fatal("expiry mismatch")
+	}
+}
+
+func TestDefaultTTL(t *testing.T) {
+	clock := newManualClock()
+	client := New(WithClock(clock), WithDefaultTTL(7*24*time.Hour))
+
+	_, _ = client.Insert("default", "val", 0)
+	if expiresIn(client, "default") != 7*24*time.Hour {
+		t.Fatal("expiry mismatch")
+	}
+	_, _ = client.UpsertWith("explicit", "val", WithRelativeExpiry(time.Minute))
+	if expiresIn(client, "explicit") != time.Minute {
+		t.Fatal("expiry mismatch")
+	}
+
+	// the maximum TTL caps the default
+	client = New(WithClock(clock), WithDefaultTTL(7*24*time.Hour), WithMaxTTL(time.Hour, MaxTTLReject))
+	if _, err := client.Insert("default", "val", 0); err != nil || expiresIn(client, "default") != time.Hour {
+		t.Fatal("expiry mismatch")
+	}
+}
+
+func TestPreserveExpiry(t *testing.T) {
+	clock := newManualClock()
+	client := New(WithClock(clock))
+	cas, _ := client.Insert("key", "val", 60)
+
+	cas, err := client.ReplaceWith("key", "val2", cas, WithPreserveExpiry())
+	if err != nil || expiresIn(client, "key") != time.Minute {
+		t.Fatal("expiry mismatch")
+	}
+	if _, err := client.Replace("key", "val3", cas, 0); err != nil || stored(client, "key").TTL != 0 {
+		t.Fatal("expiry mismatch")
+	}
+
+	_, _ = client.Upsert("up", "val", 60)
+	if _, err := client.UpsertWith("up", "val2", WithRelativeExpiry(time.Hour), WithPreserveExpiry()); err != nil {
+		t.Fatal(err)
+	}
+	if expiresIn(client, "up") != time.Minute {
+		t.Fatal("expiry mismatch")
+	}
+
+	// new documents use the expiry given
+	if _, err := client.UpsertWith("new", "val", WithRelativeExpiry(time.Hour), WithPreserveExpiry()); err != nil {
+		t.Fatal(err)
+	}
+	if expiresIn(client, "new") != time.Hour {
+		t.Fatal("expiry mismatch")
+	}
+
+	txn := client.Begin()
+	_ = txn.ReplaceWith("up", "val3", 2, WithPreserveExpiry())
+	if err := txn.Commit(); err != nil || expiresIn(client, "up") != time.Minute {
+		t.Fatal("expiry mismatch")
+	}
+}
+
+func TestUpsertExpired(t *testing.T) {
+	client := New()
+	_, _ = client.Insert("key", "val", 60)
+	expireKey(client, "key")
+
+	// the expired document is replaced rather than updated, so it doesn't keep the past expiry
+	res, err := client.UpsertWithResult("key", "val2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Created || res.Cas != 1 {
+		t.Fatal("upsert mismatch")
+	}
+	var act string
+	if _, err := client.Get("key", &act); err != nil || act != "val2" {
+		t.Fatal("value mismatch")
+	}
+}
+
+func TestUpsertIgnoredExpiry(t *testing.T) {
+	client := New(WithMaxTTL(time.Minute, MaxTTLReject))
+	client.FreezeTime()
+	_, _ = client.Insert("key", "val", 30)
+
+	// the legacy expiry of an existing document is ignored, so it can't fail the upsert
+	if _, err := client.Upsert("key", "val2", 120); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Upsert("key", "val3", ThirtyDaySeconds+1); err != nil {
+		t.Fatal(err)
+	}
+	if expiresIn(client, "key") != 30*time.Second {
+		t.Fatal("expiry mismatch")
+	}
+
+	// it still applies to new documents
+	if _, err := client.Upsert("new", "val", 120); !reflect.DeepEqual(err, ErrMaxTTLExceeded) {
+		t.Fatal("error mismatch")
+	}
+}
@@ -0,0 +1,202 @@
+package crud
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidFixture defines the error value returned when a fixture file can't be loaded or a document can't be dumped
+var ErrInvalidFixture = errors.New("invalid fixture")
+
+// fixture is an entry of a fixture file
+type fixture struct {
+	Key string `json:"key" yaml:"key"`
+	// KeyBase64 holds the base64 encoding of keys which aren't valid UTF-8 in place of Key
+	KeyBase64 string      `json:"key_base64,omitempty" yaml:"key_base64,omitempty"`
+	Value     interface{} `json:"value" yaml:"value"`
+	// TTL is the number of seconds the document lives for, zero when it never expires
+	TTL int64 `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+}
+
+// key returns the key of the entry
+func (f fixture) key() (string, error) {
+	if f.KeyBase64 == "" {
+		return f.Key, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(f.KeyBase64)
+	return string(key), err
+}
+
+// fixtureFormat returns the format of a fixture file from its extension, "json" or "yaml", or "" when it isn't one
+func fixtureFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+	return ""
+}
+
+// LoadFixtures seeds the store from a fixture file, or from every .json, .yaml and .yml file of a directory
+// in name order. A fixture file holds a list of entries with a key, a value and an optional ttl in seconds:
+//
+//	[{"key": "user::1", "value": {"name": "jo"}, "ttl": 3600}]
+//
+// Keys which aren't valid UTF-8 are base64 encoded under key_base64 instead of key.
+// Entries are upserted, so fixtures can override documents already stored. Every file is parsed before
+// any document is written, so a malformed file leaves the store untouched.
+func (crud *CRUD) LoadFixtures(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		files = files[:0]
+		for _, e := range entries {
+			if !e.IsDir() && fixtureFormat(e.Name()) != "" {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+
+	var fixtures []fixture
+	for _, file := range files {
+		parsed, err := readFixtures(file)
+		if err != nil {
+			return err
+		}
+		fixtures = append(fixtures, parsed...)
+	}
+	for _, f := range fixtures {
+		key, _ := f.key()
+		if _, err := crud.UpsertWith(key, f.Value, WithRelativeExpiry(time.Duration(f.TTL)*time.Second)); err != nil {
+			return fmt.Errorf("loading fixture %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// readFixtures parses a fixture file
+func readFixtures(file string) ([]fixture, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures []fixture
+	switch fixtureFormat(file) {
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&fixtures)
+	case "yaml":
+		err = yaml.Unmarshal(data, &fixtures)
+	default:
+		return nil, fmt.Errorf("%w: %s is neither JSON nor YAML", ErrInvalidFixture, file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidFixture, file, err)
+	}
+	for i, f := range fixtures {
+		if key, err := f.key(); err != nil || key == "" || f.TTL < 0 {
+			return nil, fmt.Errorf("%w: %s: entry %d needs a key and a ttl of zero or more", ErrInvalidFixture, file, i)
+		}
+	}
+	return fixtures, nil
+}
+
+// DumpFixtures writes the live documents of the store, in key order, to a fixture file which LoadFixtures
+// can load. The file is YAML when path ends in .yaml or .yml and JSON otherwise. TTLs are rounded up to
+// whole seconds. Documents which aren't JSON can't be dumped and fail with ErrInvalidFixture.
+func (crud *CRUD) DumpFixtures(path string) error {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	type stored struct {
+		key   string
+		value []byte
+		ttl   int64
+	}
+	now := crud.getTime()
+	var docs []stored
+	crud.rangeDocs(func(key string, doc *document) {
+		if !doc.expired(now) {
+			docs = append(docs, stored{key: key, value: doc.value(), ttl: doc.TTL})
+		}
+	})
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].key < docs[j].key
+	})
+
+	yamlFormat := fixtureFormat(path) == "yaml"
+	fixtures := make([]fixture, len(docs))
+	for i, doc := range docs {
+		value, err := decodeJSON(doc.value)
+		if err != nil {
+			return fmt.Errorf("%w: document %q isn't JSON", ErrInvalidFixture, doc.key)
+		}
+		if yamlFormat {
+			value = plainNumbers(value)
+		}
+		key, keyBase64 := encodeKey(doc.key)
+		fixtures[i] = fixture{Key: key, KeyBase64: base64.StdEncoding.EncodeToString(keyBase64), Value: value}
+		if doc.ttl > 0 {
+			fixtures[i].TTL = int64((time.Duration(doc.ttl-now) + time.Second - 1) / time.Second)
+		}
+	}
+
+	var data []byte
+	if yamlFormat {
+		data, err = yaml.Marshal(fixtures)
+	} else {
+		data, err = json.MarshalIndent(fixtures, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// plainNumbers replaces the json.Number values of a decoded JSON value with int64 or float64,
+// which YAML encodes as numbers rather than strings
+func plainNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case []interface{}:
+		for i := range v {
+			v[i] = plainNumbers(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = plainNumbers(v[key])
+		}
+	}
+	return v
+}
@@ -0,0 +1,78 @@
+package crud
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadFixtures(t *testing.T) {
+	client := New()
+	if err := client.LoadFixtures("testdata/fixtures"); err != nil {
+		t.Fatal(err)
+	}
+	if keys := client.Keys(); !reflect.DeepEqual(keys, []string{"order::1", "order::2", "user::1", "user::2"}) {
+		t.Fatalf("keys mismatch %v", keys)
+	}
+
+	var order struct {
+		User  string   `json:"user"`
+		Items []string `json:"items"`
+		Total float64  `json:"total"`
+	}
+	if _, err := client.Get("order::1", &order); err != nil || order.Total != 12.5 || len(order.Items) != 2 {
+		t.Fatal("value mismatch")
+	}
+	// large numbers keep their precision
+	var user struct {
+		Age uint64 `json:"age"`
+	}
+	if _, err := client.Get("user::2", &user); err != nil || user.Age != 9007199254740993 {
+		t.Fatal("value mismatch")
+	}
+	if ttl := expiresIn(client, "user::2"); ttl < time.Hour-time.Second || ttl > time.Hour {
+		t.Fatal("expiry mismatch")
+	}
+	if stored(client, "user::1").TTL != 0 {
+		t.Fatal("expiry mismatch")
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.json")
+	_ = os.WriteFile(bad, []byte(`[{"value": 1}]`), 0o644)
+	if err := client.LoadFixtures(bad); !errors.Is(err, ErrInvalidFixture) {
+		t.Fatal("error mismatch")
+	}
+	if err := client.LoadFixtures("testdata/fixtures/README.txt"); !errors.Is(err, ErrInvalidFixture) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestDumpFixtures(t *testing.T) {
+	client := New()
+	_ = client.LoadFixtures("testdata/fixtures")
+
+	for _, name := range []string{"dump.json", "dump.yaml"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := client.DumpFixtures(path); err != nil {
+			t.Fatal(err)
+		}
+		loaded := New()
+		if err := loaded.LoadFixtures(path); err != nil {
+			t.Fatal(err)
+		}
+		if diff, _ := client.Diff(loaded); !diff.Empty() {
+			t.Fatalf("%s round trip mismatch", name)
+		}
+		if ttl := expiresIn(loaded, "order::2"); ttl < 59*time.Second || ttl > time.Minute {
+			t.Fatal("expiry mismatch")
+		}
+	}
+
+	_, _ = client.InsertRaw("bin", []byte{0xff}, 0)
+	if err := client.DumpFixtures(filepath.Join(t.TempDir(), "dump.json")); !errors.Is(err, ErrInvalidFixture) {
+		t.Fatal("error mismatch")
+	}
+}
@@ -1,3 +1,10 @@
 module github.com/jacygao/crud
 
-go 1.14
+go 1.23
+
+require github.com/google/btree v1.1.3
+
+require (
+	github.com/klauspost/compress v1.17.11
+	gopkg.in/yaml.v3 v3.0.1
+)
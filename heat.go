@@ -0,0 +1,174 @@
+package crud
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// HeatMetric selects the measure TopKeys ranks keys by
+type HeatMetric int
+
+const (
+	// ByReads ranks keys by the number of successful reads
+	ByReads HeatMetric = iota
+	// ByWrites ranks keys by the number of mutations
+	ByWrites
+	// ByBytes ranks keys by the number of value bytes read and written
+	ByBytes
+)
+
+// KeyHeat reports the activity of a key over the tracking window
+type KeyHeat struct {
+	Key    string
+	Reads  int64
+	Writes int64
+	Bytes  int64
+}
+
+func (h KeyHeat) metric(by HeatMetric) int64 {
+	switch by {
+	case ByWrites:
+		return h.Writes
+	case ByBytes:
+		return h.Bytes
+	}
+	return h.Reads
+}
+
+// WithKeyHeat enables tracking of per-key activity over a sliding window, reported by TopKeys.
+// The window has a resolution of one second. The activity of a key is discarded once it is removed,
+// expires or is evicted, and Flush discards all of it.
+func WithKeyHeat(window time.Duration) Option {
+	return func(crud *CRUD) {
+		crud.heat = &heatTracker{
+			window: int64(window / time.Second),
+			keys:   make(map[string][]heatBucket),
+			now: func() int64 {
+				return crud.now().Unix()
+			},
+		}
+	}
+}
+
+// heatBucket aggregates the activity of a key during one second
+type heatBucket struct {
+	sec    int64
+	reads  int64
+	writes int64
+	bytes  int64
+}
+
+// heatTracker records per-key activity in one second buckets
+type heatTracker struct {
+	mu     sync.Mutex
+	window int64
+	keys   map[string][]heatBucket
+	now    func() int64
+}
+
+// record adds activity for key to the bucket of the current second
+func (h *heatTracker) record(key string, reads, writes, bytes int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	buckets := h.keys[key]
+	if n := len(buckets); n == 0 || buckets[n-1].sec != now {
+		buckets = append(h.prune(buckets, now), heatBucket{sec: now})
+	}
+	last := &buckets[len(buckets)-1]
+	last.reads += reads
+	last.writes += writes
+	last.bytes += bytes
+	h.keys[key] = buckets
+}
+
+// prune drops buckets which have fallen out of the window
+func (h *heatTracker) prune(buckets []heatBucket, now int64) []heatBucket {
+	i := 0
+	for i < len(buckets) && buckets[i].sec <= now-h.window {
+		i++
+	}
+	return buckets[i:]
+}
+
+// top returns the n keys with the highest metric within the window
+func (h *heatTracker) top(n int, by HeatMetric) []KeyHeat {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	var report []KeyHeat
+	for key, buckets := range h.keys {
+		buckets = h.prune(buckets, now)
+		if len(buckets) == 0 {
+			delete(h.keys, key)
+			continue
+		}
+		h.keys[key] = buckets
+
+		heat := KeyHeat{Key: key}
+		for _, b := range buckets {
+			heat.Reads += b.reads
+			heat.Writes += b.writes
+			heat.Bytes += b.bytes
+		}
+		if heat.metric(by) > 0 {
+			report = append(report, heat)
+		}
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		if mi, mj := report[i].metric(by), report[j].metric(by); mi != mj {
+			return mi > mj
+		}
+		return report[i].Key < report[j].Key
+	})
+	if n >= 0 && len(report) > n {
+		report = report[:n]
+	}
+	return report
+}
+
+// forget discards the activity recorded for key, once it is removed from the store
+func (h *heatTracker) forget(key string) {
+	h.mu.Lock()
+	delete(h.keys, key)
+	h.mu.Unlock()
+}
+
+// reset discards all recorded activity
+func (h *heatTracker) reset() {
+	h.mu.Lock()
+	h.keys = make(map[string][]heatBucket)
+	h.mu.Unlock()
+}
+
+// recordRead notes a successful read of key for heat tracking and the audit log, when they are enabled
+func (crud *CRUD) recordRead(key string, size int, cas uint64) {
+	key = crud.intern(key)
+	if crud.heat != nil {
+		crud.heat.record(key, 1, 0, int64(size))
+	}
+	crud.counters.reads.Add(1)
+	crud.noteAccess(key)
+	crud.recordAudit(AuditRead, key, cas, cas)
+}
+
+// recordWrite notes a mutation of key when heat tracking is enabled
+func (crud *CRUD) recordWrite(key string, size int) {
+	if crud.heat != nil {
+		crud.heat.record(key, 0, 1, int64(size))
+	}
+}
+
+// TopKeys returns up to n of the hottest keys over the window configured by WithKeyHeat,
+// ranked by the given metric. Ties are broken by key. Keys with no activity for the metric are omitted.
+// TopKeys returns nil unless heat tracking is enabled.
+func (crud *CRUD) TopKeys(n int, by HeatMetric) []KeyHeat {
+	if crud.heat == nil {
+		return nil
+	}
+	return crud.heat.top(n, by)
+}
@@ -0,0 +1,87 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTopKeys(t *testing.T) {
+	client := New(WithKeyHeat(time.Minute))
+	_, _ = client.Insert("hot", "value", 0)
+	_, _ = client.Insert("warm", "v", 0)
+	_, _ = client.Upsert("warm", "v", 0)
+	_, _ = client.Upsert("warm", "v", 0)
+
+	var act string
+	for i := 0; i < 3; i++ {
+		_, _ = client.Get("hot", &act)
+	}
+	_, _ = client.Get("warm", &act)
+
+	reads := client.TopKeys(10, ByReads)
+	exp := []KeyHeat{
+		{Key: "hot", Reads: 3, Writes: 1, Bytes: 28},
+		{Key: "warm", Reads: 1, Writes: 3, Bytes: 12},
+	}
+	if !reflect.DeepEqual(reads, exp) {
+		t.Fatalf("unexpected report %+v", reads)
+	}
+
+	writes := client.TopKeys(1, ByWrites)
+	if len(writes) != 1 || writes[0].Key != "warm" {
+		t.Fatalf("unexpected report %+v", writes)
+	}
+
+	bytes := client.TopKeys(1, ByBytes)
+	if len(bytes) != 1 || bytes[0].Key != "hot" {
+		t.Fatalf("unexpected report %+v", bytes)
+	}
+}
+
+func TestTopKeysWindow(t *testing.T) {
+	client := New(WithKeyHeat(10 * time.Second))
+	now := int64(1000)
+	client.heat.now = func() int64 { return now }
+
+	_, _ = client.Insert("old", "val", 0)
+	now += 5
+	_, _ = client.Insert("new", "val", 0)
+	now += 6
+
+	report := client.TopKeys(10, ByWrites)
+	if len(report) != 1 || report[0].Key != "new" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestTopKeysForgetsRemovedKeys(t *testing.T) {
+	client := New(WithKeyHeat(time.Minute))
+	cas, _ := client.Insert("removed", "val", 0)
+	_, _ = client.Insert("expired", "val", 1)
+	_, _ = client.Insert("kept", "val", 0)
+	_, _ = client.Remove("removed", cas)
+	client.Advance(2 * time.Second)
+	client.Get("expired", new(string))
+
+	report := client.TopKeys(10, ByWrites)
+	if len(report) != 1 || report[0].Key != "kept" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if len(client.heat.keys) != 1 {
+		t.Fatal("heat entries mismatch")
+	}
+
+	client.Flush()
+	if len(client.heat.keys) != 0 {
+		t.Fatal("heat entries mismatch")
+	}
+}
+
+func TestTopKeysDisabled(t *testing.T) {
+	client := New()
+	_, _ = client.Insert("key", "val", 0)
+	if report := client.TopKeys(10, ByWrites); report != nil {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
@@ -0,0 +1,209 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrHistoryDisabled defines the error value returned when revisions are requested from a store created without WithHistory
+	ErrHistoryDisabled = errors.New("history not enabled")
+	// ErrRevisionNotFound defines the error value returned when no retained revision of a document has the requested CAS
+	ErrRevisionNotFound = errors.New("revision not found")
+)
+
+// WithHistory retains up to limit revisions of each document, including its current one, for ListRevisions,
+// GetRevision, GetAsOf and History. Removals and expiries are retained as deleted revisions. A limit of zero retains none.
+func WithHistory(limit int) Option {
+	return func(crud *CRUD) {
+		if limit > 0 {
+			crud.history = &historyLog{limit: limit, revs: make(map[string][]revision)}
+		}
+	}
+}
+
+// Revision is a version of a document retained by WithHistory
+type Revision struct {
+	// Cas is the CAS of the version, deleted revisions carry the CAS of the document they removed
+	Cas uint64
+	// Value holds the bytes produced by the transcoder, nil for deleted revisions
+	Value json.RawMessage
+	// Time is when the revision was written
+	Time time.Time
+	// Deleted is true for revisions recording the removal or expiry of the document
+	Deleted bool
+
+	transcoder Transcoder
+}
+
+// Content decodes the revision's value into valuePtr
+func (r Revision) Content(valuePtr interface{}) error {
+	return r.transcoder.Unmarshal(r.Value, valuePtr)
+}
+
+// revision is a retained version of a document
+type revision struct {
+	op    EventType
+	cas   uint64
+	value []byte
+	at    int64
+	// ttl is the expiry of the version, see document.TTL
+	ttl     int64
+	deleted bool
+}
+
+// historyLog holds the retained revisions of each document, oldest first
+type historyLog struct {
+	mu    sync.RWMutex
+	limit int
+	revs  map[string][]revision
+}
+
+// record retains a revision of key, dropping its oldest revision once the limit is reached.
+// It is called with the key's shard locked so the revisions of a key are recorded in order.
+func (h *historyLog) record(key string, rev revision) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	revs := h.revs[key]
+	if len(revs) >= h.limit {
+		revs = append(revs[:0:0], revs[len(revs)-h.limit+1:]...)
+	}
+	h.revs[key] = append(revs, rev)
+}
+
+// list returns a copy of the revisions of key
+func (h *historyLog) list(key string) []revision {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]revision(nil), h.revs[key]...)
+}
+
+func (h *historyLog) clear() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.revs = make(map[string][]revision)
+}
+
+// recordHistory retains the doc stored by a mutation of type typ, or a deleted revision when doc was removed
+func (crud *CRUD) recordHistory(typ EventType, key string, doc *document) {
+	if crud.history == nil {
+		return
+	}
+	rev := revision{op: typ, cas: doc.Cas, value: doc.value(), at: doc.Updated, ttl: doc.TTL}
+	if typ == EventRemove || typ == EventExpire || typ == EventEvict {
+		rev = revision{op: typ, cas: doc.Cas, at: crud.getTime(), deleted: true}
+	}
+	crud.history.record(key, rev)
+}
+
+// ListRevisions returns the retained revisions of the document stored under key, oldest first.
+// Revisions outlive the document, so removed documents still have their history listed.
+func (crud *CRUD) ListRevisions(key string) ([]Revision, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if crud.history == nil {
+		return nil, ErrHistoryDisabled
+	}
+
+	revs := crud.history.list(key)
+	out := make([]Revision, len(revs))
+	for i, rev := range revs {
+		out[i] = Revision{
+			Cas:        rev.cas,
+			Value:      json.RawMessage(rev.value),
+			Time:       time.Unix(0, rev.at),
+			Deleted:    rev.deleted,
+			transcoder: crud.transcoder,
+		}
+	}
+	return out, nil
+}
+
+// GetRevision decodes the retained revision of the document stored under key with the given CAS into valuePtr.
+// When a key was removed and stored again and several revisions share the CAS, the latest is used.
+func (crud *CRUD) GetRevision(key string, cas uint64, valuePtr interface{}) error {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return err
+	}
+	defer release()
+	if crud.history == nil {
+		return ErrHistoryDisabled
+	}
+
+	revs := crud.history.list(key)
+	for i := len(revs) - 1; i >= 0; i-- {
+		if !revs[i].deleted && revs[i].cas == cas {
+			return crud.transcoder.Unmarshal(revs[i].value, valuePtr)
+		}
+	}
+	return ErrRevisionNotFound
+}
+
+// GetAsOf decodes the value the document stored under key had at t into valuePtr, returning the CAS it had then.
+// It returns ErrKeyNotExist when the document didn't exist or had expired at t, and ErrRevisionNotFound when
+// t precedes the revisions retained by WithHistory.
+func (crud *CRUD) GetAsOf(key string, t time.Time, valuePtr interface{}) (uint64, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	if crud.history == nil {
+		return 0, ErrHistoryDisabled
+	}
+
+	at := t.UnixNano()
+	revs := crud.history.list(key)
+	for i := len(revs) - 1; i >= 0; i-- {
+		rev := revs[i]
+		if rev.at > at {
+			continue
+		}
+		if rev.deleted || (rev.ttl > 0 && rev.ttl <= at) {
+			return 0, ErrKeyNotExist
+		}
+		return rev.cas, crud.transcoder.Unmarshal(rev.value, valuePtr)
+	}
+	// earlier revisions may have been dropped
+	if len(revs) >= crud.history.limit {
+		return 0, ErrRevisionNotFound
+	}
+	return 0, ErrKeyNotExist
+}
+
+// HistoryEntry describes a mutation applied to a document
+type HistoryEntry struct {
+	Op EventType
+	// Cas is the CAS the mutation gave the document, or the CAS of the document it removed
+	Cas uint64
+	// Size is the length of the stored value in bytes, zero for removals and expiries
+	Size int
+	Time time.Time
+}
+
+// History returns the mutations applied to the document stored under key, oldest first, as far back as
+// the revisions retained by WithHistory go.
+func (crud *CRUD) History(key string) ([]HistoryEntry, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if crud.history == nil {
+		return nil, ErrHistoryDisabled
+	}
+
+	revs := crud.history.list(key)
+	out := make([]HistoryEntry, len(revs))
+	for i, rev := range revs {
+		out[i] = HistoryEntry{Op: rev.op, Cas: rev.cas, Size: len(rev.value), Time: time.Unix(0, rev.at)}
+	}
+	return out, nil
+}
@@ -0,0 +1,116 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	client := New(WithHistory(3))
+	cas, _ := client.Insert("key", "v1", 0)
+	cas, _ = client.Replace("key", "v2", cas, 0)
+	cas, _ = client.Replace("key", "v3", cas, 0)
+
+	var act string
+	if err := client.GetRevision("key", 2, &act); err != nil || act != "v2" {
+		t.Fatal("revision mismatch")
+	}
+
+	// the oldest revision is dropped once the limit is reached
+	_, _ = client.Remove("key", cas)
+	if err := client.GetRevision("key", 1, &act); !reflect.DeepEqual(err, ErrRevisionNotFound) {
+		t.Fatal("error mismatch")
+	}
+
+	revs, err := client.ListRevisions("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 3 {
+		t.Fatal("revision count mismatch")
+	}
+	if err := revs[1].Content(&act); err != nil || act != "v3" || revs[1].Cas != 3 {
+		t.Fatal("revision mismatch")
+	}
+	if !revs[2].Deleted || revs[2].Value != nil {
+		t.Fatal("revision mismatch")
+	}
+	if revs[1].Time.After(revs[2].Time) {
+		t.Fatal("time mismatch")
+	}
+
+	// removed documents keep their history until the store is flushed
+	_ = client.Flush()
+	if revs, _ := client.ListRevisions("key"); len(revs) != 0 {
+		t.Fatal("revision count mismatch")
+	}
+
+	if _, err := New().ListRevisions("key"); !reflect.DeepEqual(err, ErrHistoryDisabled) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestGetAsOf(t *testing.T) {
+	clock := newManualClock()
+	client := New(WithClock(clock), WithHistory(3))
+	start := clock.Now()
+
+	cas, _ := client.Insert("key", "v1", 0)
+	clock.add(time.Minute)
+	cas, _ = client.Replace("key", "v2", cas, 90)
+	clock.add(2 * time.Minute)
+
+	var act string
+	if _, err := client.GetAsOf("key", start.Add(-time.Second), &act); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+	if cas, err := client.GetAsOf("key", start.Add(30*time.Second), &act); err != nil || act != "v1" || cas != 1 {
+		t.Fatal("value mismatch")
+	}
+	if cas, err := client.GetAsOf("key", start.Add(time.Minute), &act); err != nil || act != "v2" || cas != 2 {
+		t.Fatal("value mismatch")
+	}
+	// the second revision expired before being purged
+	if _, err := client.GetAsOf("key", clock.Now(), &act); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+
+	_, _ = client.Upsert("key", "v3", 0)
+	_, _ = client.Upsert("key", "v4", 0)
+	if _, err := client.GetAsOf("key", start, &act); !reflect.DeepEqual(err, ErrRevisionNotFound) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestHistoryEntries(t *testing.T) {
+	clock := newManualClock()
+	client := New(WithClock(clock), WithHistory(10))
+	start := clock.Now()
+
+	cas, _ := client.Insert("key", "v1", 0)
+	clock.add(time.Second)
+	cas, _ = client.Touch("key", cas, 1)
+	clock.add(2 * time.Second)
+	_, _ = client.Upsert("key", "value", 0)
+
+	exp := []HistoryEntry{
+		{Op: EventInsert, Cas: 1, Size: 4, Time: start},
+		{Op: EventUpdate, Cas: 2, Size: 4, Time: start.Add(time.Second)},
+		// the touched document expired before the upsert
+		{Op: EventExpire, Cas: 2, Time: start.Add(3 * time.Second)},
+		{Op: EventInsert, Cas: 1, Size: 7, Time: start.Add(3 * time.Second)},
+	}
+	entries, err := client.History("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(exp) {
+		t.Fatal("history mismatch")
+	}
+	for i := range exp {
+		if entries[i].Op != exp[i].Op || entries[i].Cas != exp[i].Cas || entries[i].Size != exp[i].Size || !entries[i].Time.Equal(exp[i].Time) {
+			t.Fatalf("history entry %d mismatch", i)
+		}
+	}
+}
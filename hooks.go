@@ -0,0 +1,125 @@
+package crud
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Hook is a callback fired after a mutation with the document's key, its value before and after
+// the mutation and its resulting CAS. oldValue is nil for inserts and newValue is nil for removals
+// and expiries. The values are the bytes produced by the transcoder and must not be modified.
+//
+// Hooks run once the operation making the mutation has released its locks, so they may use the store, and
+// they run one at a time in the order they were fired. The operation runs them before it returns unless
+// another goroutine is already running hooks, in which case that goroutine runs them and the operation
+// may return first. Mutations made by a hook fire their own hooks after it returns.
+type Hook func(key string, oldValue, newValue []byte, cas uint64)
+
+// hookCall is a fired hook waiting to run
+type hookCall struct {
+	hook     Hook
+	key      string
+	oldValue []byte
+	newValue []byte
+	cas      uint64
+}
+
+// hookSet holds the registered hooks and the calls fired by mutations which haven't run yet
+type hookSet struct {
+	// registered is set once any hook is registered, so stores without hooks skip the lock
+	registered atomic.Bool
+	mu         sync.Mutex
+	hooks      map[EventType][]Hook
+	pending    []hookCall
+	// running is true while a goroutine is running the pending calls
+	running bool
+}
+
+func newHookSet() *hookSet {
+	return &hookSet{hooks: make(map[EventType][]Hook)}
+}
+
+func (h *hookSet) register(typ EventType, hook Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hooks[typ] = append(h.hooks[typ], hook)
+	h.registered.Store(true)
+}
+
+// fire queues the hooks registered for a mutation. It is called with the key's shard locked.
+func (h *hookSet) fire(typ EventType, key string, oldValue, newValue []byte, cas uint64) {
+	if !h.registered.Load() {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, hook := range h.hooks[typ] {
+		h.pending = append(h.pending, hookCall{hook: hook, key: key, oldValue: oldValue, newValue: newValue, cas: cas})
+	}
+}
+
+// run calls the pending hooks in the order they were fired. When another goroutine is already running them,
+// including a hook of this goroutine using the store, that goroutine runs the new calls as well.
+func (h *hookSet) run() {
+	if !h.registered.Load() {
+		return
+	}
+	h.mu.Lock()
+	if h.running {
+		h.mu.Unlock()
+		return
+	}
+	h.running = true
+	defer func() {
+		h.running = false
+		h.mu.Unlock()
+	}()
+
+	for len(h.pending) > 0 {
+		batch := h.pending
+		h.pending = nil
+		h.mu.Unlock()
+		h.call(batch)
+		h.mu.Lock()
+	}
+}
+
+// call runs a batch of hooks, retaking the lock if one panics so that run's cleanup holds it
+func (h *hookSet) call(batch []hookCall) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.mu.Lock()
+			panic(r)
+		}
+	}()
+	for _, c := range batch {
+		c.hook(c.key, c.oldValue, c.newValue, c.cas)
+	}
+}
+
+// OnInsert registers a hook fired whenever a new document is stored
+func (crud *CRUD) OnInsert(hook Hook) {
+	crud.hooks.register(EventInsert, hook)
+}
+
+// OnUpdate registers a hook fired whenever an existing document's value or expiry changes
+func (crud *CRUD) OnUpdate(hook Hook) {
+	crud.hooks.register(EventUpdate, hook)
+}
+
+// OnRemove registers a hook fired whenever a document is removed
+func (crud *CRUD) OnRemove(hook Hook) {
+	crud.hooks.register(EventRemove, hook)
+}
+
+// OnExpire registers a hook fired whenever an expired document is purged, either lazily when it is
+// accessed or by PurgeExpired. oldValue holds the expired value.
+func (crud *CRUD) OnExpire(hook Hook) {
+	crud.hooks.register(EventExpire, hook)
+}
+
+// OnEvict registers a hook fired whenever a document is evicted to keep the store within the limit set by
+// WithMemoryLimit. oldValue holds the evicted value.
+func (crud *CRUD) OnEvict(hook Hook) {
+	crud.hooks.register(EventEvict, hook)
+}
@@ -0,0 +1,87 @@
+package crud
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// hookRecord is a hook call captured by recordHooks
+type hookRecord struct {
+	typ      EventType
+	key      string
+	old, new string
+	cas      uint64
+}
+
+// recordHooks registers hooks for every mutation type which record their calls
+func recordHooks(client *CRUD) func() []hookRecord {
+	var (
+		mu      sync.Mutex
+		records []hookRecord
+	)
+	for typ, register := range map[EventType]func(Hook){
+		EventInsert: client.OnInsert,
+		EventUpdate: client.OnUpdate,
+		EventRemove: client.OnRemove,
+		EventExpire: client.OnExpire,
+	} {
+		typ := typ
+		register(func(key string, oldValue, newValue []byte, cas uint64) {
+			mu.Lock()
+			defer mu.Unlock()
+			records = append(records, hookRecord{typ, key, string(oldValue), string(newValue), cas})
+		})
+	}
+	return func() []hookRecord {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]hookRecord(nil), records...)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	client := New()
+	records := recordHooks(client)
+
+	client.Insert("key", "a", 0)
+	cas, _ := client.Upsert("key", "b", 0)
+	client.Append("raw", []byte("x"))
+	client.UpsertRaw("raw", []byte("x"), 0)
+	client.Append("raw", []byte("y"))
+	client.Remove("key", cas)
+
+	exp := []hookRecord{
+		{EventInsert, "key", "", `"a"`, 1},
+		{EventUpdate, "key", `"a"`, `"b"`, 2},
+		{EventInsert, "raw", "", "x", 1},
+		{EventUpdate, "raw", "x", "xy", 2},
+		{EventRemove, "key", `"b"`, "", 2},
+	}
+	if act := records(); !reflect.DeepEqual(act, exp) {
+		t.Fatalf("hooks mismatch: %v", act)
+	}
+
+	expireKey(client, "raw")
+	client.Get("raw", nil)
+	if act := records(); !reflect.DeepEqual(act[len(act)-1], hookRecord{EventExpire, "raw", "xy", "", 2}) {
+		t.Fatal("hooks mismatch")
+	}
+}
+
+func TestHooksUseStore(t *testing.T) {
+	client := New()
+
+	// a hook writing to the store runs after the operation released its locks
+	client.OnInsert(func(key string, oldValue, newValue []byte, cas uint64) {
+		if key == "order" {
+			client.Insert("audit", string(newValue), 0)
+		}
+	})
+	client.Insert("order", "o", 0)
+
+	var act string
+	if _, err := client.Get("audit", &act); err != nil || act != `"o"` {
+		t.Fatal("value mismatch")
+	}
+}
@@ -0,0 +1,432 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/btree"
+)
+
+var (
+	// ErrIndexExists defines the error value returned when creating an index with a name already in use
+	ErrIndexExists = errors.New("index already exists")
+	// ErrIndexNotFound defines the error value returned when an index doesn't exist
+	ErrIndexNotFound = errors.New("index not found")
+	// ErrUniqueViolation defines the error value returned when a write would give two live documents
+	// the same values in a unique index
+	ErrUniqueViolation = errors.New("unique constraint violation")
+	// ErrInvalidIndexLookup defines the error value returned when a lookup has more values than the index has fields
+	ErrInvalidIndexLookup = errors.New("more lookup values than indexed fields")
+)
+
+// indexEntry is an entry of a secondary index, ordered by tuple and then by document key
+type indexEntry struct {
+	tuple string
+	key   string
+}
+
+func (e indexEntry) less(o indexEntry) bool {
+	if e.tuple != o.tuple {
+		return e.tuple < o.tuple
+	}
+	return e.key < o.key
+}
+
+// secondaryIndex maps the values of one or more document fields to the keys of the documents holding them.
+// The field values of a document form a tuple, encoded as the canonical JSON of each value followed by
+// a NUL separator so that the encoding of leading values is a prefix of the whole tuple.
+type secondaryIndex struct {
+	fields [][]pathSegment
+	unique bool
+	// entries orders the documents by tuple
+	entries *btree.BTreeG[indexEntry]
+	// tuples holds the tuple each key is indexed under
+	tuples map[string]string
+	// ttls holds the expiry of each indexed document, so unique checks can ignore expired documents
+	ttls map[string]int64
+}
+
+func newSecondaryIndex(fields [][]pathSegment, unique bool) *secondaryIndex {
+	return &secondaryIndex{
+		fields:  fields,
+		unique:  unique,
+		entries: btree.NewG(32, indexEntry.less),
+		tuples:  make(map[string]string),
+		ttls:    make(map[string]int64),
+	}
+}
+
+// encodeTuple encodes the leading field values of a tuple
+func encodeTuple(values []interface{}) string {
+	var b strings.Builder
+	for _, v := range values {
+		writeCanonical(&b, v)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// tuple returns the encoded tuple of the index fields in root, false when a field is missing
+func (ix *secondaryIndex) tuple(root interface{}) (string, bool) {
+	values := make([]interface{}, len(ix.fields))
+	for i, segs := range ix.fields {
+		value, ok := getPath(root, segs)
+		if !ok {
+			return "", false
+		}
+		values[i] = value
+	}
+	return encodeTuple(values), true
+}
+
+// add indexes key, expiring at ttl, under the values of the index fields in root, replacing any previous entry.
+// Documents missing any of the fields are not indexed.
+func (ix *secondaryIndex) add(key string, root interface{}, ttl int64) {
+	ix.remove(key)
+	tuple, ok := ix.tuple(root)
+	if !ok {
+		return
+	}
+	ix.entries.ReplaceOrInsert(indexEntry{tuple: tuple, key: key})
+	ix.tuples[key] = tuple
+	ix.ttls[key] = ttl
+}
+
+func (ix *secondaryIndex) remove(key string) {
+	tuple, ok := ix.tuples[key]
+	if !ok {
+		return
+	}
+	delete(ix.tuples, key)
+	delete(ix.ttls, key)
+	ix.entries.Delete(indexEntry{tuple: tuple, key: key})
+}
+
+// lookup returns the keys whose tuple starts with prefix, ordered by tuple and then by key
+func (ix *secondaryIndex) lookup(prefix string) []string {
+	var keys []string
+	ix.entries.AscendGreaterOrEqual(indexEntry{tuple: prefix}, func(e indexEntry) bool {
+		if !strings.HasPrefix(e.tuple, prefix) {
+			return false
+		}
+		keys = append(keys, e.key)
+		return true
+	})
+	return keys
+}
+
+// live reports whether the indexed document stored under key hasn't expired at now
+func (ix *secondaryIndex) live(key string, now int64) bool {
+	ttl := ix.ttls[key]
+	return ttl == 0 || ttl > now
+}
+
+// indexSet holds the secondary indexes of a store
+type indexSet struct {
+	mu      sync.RWMutex
+	indexes map[string]*secondaryIndex
+	// unique serialises writes between their unique check and storing the document, see reserveUnique
+	unique sync.Mutex
+	// defined and uniques count the indexes and the unique ones, so that writes skip mu when there are none
+	defined atomic.Int32
+	uniques atomic.Int32
+}
+
+func newIndexSet() *indexSet {
+	return &indexSet{indexes: make(map[string]*secondaryIndex)}
+}
+
+// counted updates the number of indexes after they changed. It must be called with mu held.
+func (s *indexSet) counted() {
+	uniques := 0
+	for _, ix := range s.indexes {
+		if ix.unique {
+			uniques++
+		}
+	}
+	s.defined.Store(int32(len(s.indexes)))
+	s.uniques.Store(int32(uniques))
+}
+
+// update reindexes key after its document changed. It must be called with the write lock of the key's shard held.
+func (s *indexSet) update(key string, doc *document) {
+	// an index can't be created meanwhile, as creating one waits for the shard locks held by the caller
+	if s.defined.Load() == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	root, err := decodeJSON(doc.value())
+	for _, ix := range s.indexes {
+		if err != nil {
+			// documents which aren't JSON have no fields to index
+			ix.remove(key)
+			continue
+		}
+		ix.add(key, root, doc.TTL)
+	}
+}
+
+// remove drops key from every index. It must be called with the write lock of the key's shard held.
+func (s *indexSet) remove(key string) {
+	if s.defined.Load() == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ix := range s.indexes {
+		ix.remove(key)
+	}
+}
+
+// clear drops every entry while keeping the index definitions
+func (s *indexSet) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, ix := range s.indexes {
+		s.indexes[name] = newSecondaryIndex(ix.fields, ix.unique)
+	}
+}
+
+// CreateIndex creates a secondary index named name over the JSON field at fieldPath, which is either a
+// sub-document path or a JSON pointer as accepted by QueryField. Existing documents are indexed immediately
+// and the index is kept up to date by every write. Documents without the field are not indexed.
+func (crud *CRUD) CreateIndex(name, fieldPath string) error {
+	return crud.createIndex(name, false, fieldPath)
+}
+
+// CreateCompositeIndex creates a secondary index named name over several JSON fields, see CreateIndex.
+// Documents are ordered by the tuple of their field values, in the order of fieldPaths, and are only
+// indexed when they have every field.
+func (crud *CRUD) CreateCompositeIndex(name string, fieldPaths ...string) error {
+	return crud.createIndex(name, false, fieldPaths...)
+}
+
+// CreateUniqueIndex creates a secondary index named name over one or more JSON fields, see CreateCompositeIndex,
+// which also constrains live documents to have distinct field values. Writes which would store a duplicate
+// fail with ErrUniqueViolation, as does creating the index when existing documents already hold duplicates.
+func (crud *CRUD) CreateUniqueIndex(name string, fieldPaths ...string) error {
+	return crud.createIndex(name, true, fieldPaths...)
+}
+
+func (crud *CRUD) createIndex(name string, unique bool, fieldPaths ...string) error {
+	if len(fieldPaths) == 0 {
+		return ErrInvalidPath
+	}
+	fields := make([][]pathSegment, len(fieldPaths))
+	for i, path := range fieldPaths {
+		segs, err := parseAnyPath(path)
+		if err != nil {
+			return err
+		}
+		fields[i] = segs
+	}
+
+	// block writers so that no document is missed while the index is built
+	unlock := crud.lockAll()
+	defer unlock()
+
+	crud.indexes.mu.Lock()
+	defer crud.indexes.mu.Unlock()
+	if _, ok := crud.indexes.indexes[name]; ok {
+		return ErrIndexExists
+	}
+
+	now := crud.getTime()
+	ix := newSecondaryIndex(fields, unique)
+	for _, s := range crud.shards {
+		for key, doc := range s.docs {
+			root, err := decodeJSON(doc.value())
+			if err != nil {
+				continue
+			}
+			if unique && !doc.expired(now) {
+				if tuple, ok := ix.tuple(root); ok {
+					for _, other := range ix.lookup(tuple) {
+						if ix.live(other, now) {
+							return ErrUniqueViolation
+						}
+					}
+				}
+			}
+			ix.add(key, root, doc.TTL)
+		}
+	}
+	crud.indexes.indexes[name] = ix
+	crud.indexes.counted()
+	return nil
+}
+
+// DropIndex removes the secondary index named name
+func (crud *CRUD) DropIndex(name string) error {
+	crud.indexes.mu.Lock()
+	defer crud.indexes.mu.Unlock()
+	if _, ok := crud.indexes.indexes[name]; !ok {
+		return ErrIndexNotFound
+	}
+	delete(crud.indexes.indexes, name)
+	crud.indexes.counted()
+	return nil
+}
+
+// LookupIndex returns, in key order, the live documents whose indexed field equals value
+// in the secondary index named name. value is compared in its JSON encoding, as by QueryField.
+// For a composite index value is matched against the first field, see LookupCompositeIndex.
+func (crud *CRUD) LookupIndex(name string, value interface{}) ([]Document, error) {
+	return crud.LookupCompositeIndex(name, value)
+}
+
+// LookupCompositeIndex returns the live documents whose leading indexed fields equal values in the
+// secondary index named name. Fewer values than indexed fields match on the leading fields only.
+// Documents are returned ordered by their tuple of field values and then by key.
+func (crud *CRUD) LookupCompositeIndex(name string, values ...interface{}) ([]Document, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	want := make([]interface{}, len(values))
+	for i, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if want[i], err = decodeJSON(data); err != nil {
+			return nil, err
+		}
+	}
+
+	// the index lock is released before the shards are locked, as writers take them the other way around
+	crud.indexes.mu.RLock()
+	ix, ok := crud.indexes.indexes[name]
+	if !ok {
+		crud.indexes.mu.RUnlock()
+		return nil, ErrIndexNotFound
+	}
+	if len(values) > len(ix.fields) {
+		crud.indexes.mu.RUnlock()
+		return nil, ErrInvalidIndexLookup
+	}
+	keys := ix.lookup(encodeTuple(want))
+	crud.indexes.mu.RUnlock()
+
+	var docs []Document
+	for _, key := range keys {
+		if doc, ok := crud.indexed(key); ok {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// canonicalJSON encodes a decoded JSON value so that equal values, as reported by jsonEqual, have equal encodings
+func canonicalJSON(v interface{}) string {
+	var b strings.Builder
+	writeCanonical(&b, v)
+	return b.String()
+}
+
+func writeCanonical(b *strings.Builder, v interface{}) {
+	switch v := v.(type) {
+	case json.Number:
+		if r, ok := new(big.Rat).SetString(string(v)); ok {
+			b.WriteString(r.RatString())
+			return
+		}
+		b.WriteString(string(v))
+	case string:
+		b.WriteString(strconv.Quote(v))
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case nil:
+		b.WriteString("null")
+	case []interface{}:
+		b.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeCanonical(b, e)
+		}
+		b.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.Quote(k))
+			b.WriteByte(':')
+			writeCanonical(b, v[k])
+		}
+		b.WriteByte('}')
+	}
+}
+
+// hasUnique reports whether any unique index exists
+func (s *indexSet) hasUnique() bool {
+	return s.uniques.Load() > 0
+}
+
+// reserveUnique checks that storing changes, the new values of documents by key with nil for removals,
+// leaves no two live documents with the same values in a unique index. It must be called with the write locks
+// of the changed keys' shards held, before any of them is modified. On success the returned function must be
+// called once the changes are stored; unique checks of other writes wait until then.
+func (crud *CRUD) reserveUnique(changes map[string][]byte) (func(), error) {
+	s := crud.indexes
+	// a unique index can't be created meanwhile, as creating one waits for the shard locks held by the caller
+	if !s.hasUnique() {
+		return func() {}, nil
+	}
+	s.unique.Lock()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := crud.getTime()
+	for _, ix := range s.indexes {
+		if !ix.unique {
+			continue
+		}
+		claimed := make(map[string]string)
+		for key, value := range changes {
+			if value == nil {
+				continue
+			}
+			root, err := decodeJSON(value)
+			if err != nil {
+				continue
+			}
+			tuple, ok := ix.tuple(root)
+			if !ok {
+				continue
+			}
+			if _, dup := claimed[tuple]; dup {
+				s.unique.Unlock()
+				return nil, ErrUniqueViolation
+			}
+			claimed[tuple] = key
+			for _, other := range ix.lookup(tuple) {
+				if _, changed := changes[other]; !changed && ix.live(other, now) {
+					s.unique.Unlock()
+					return nil, ErrUniqueViolation
+				}
+			}
+		}
+	}
+	return s.unique.Unlock, nil
+}
@@ -0,0 +1,235 @@
+package crud
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSecondaryIndex(t *testing.T) {
+	client := NewSharded(4)
+	_, _ = client.InsertRaw("alice", []byte(`{"city":"Sydney","age":30}`), 0)
+	_, _ = client.InsertRaw("bob", []byte(`{"city":"Perth","age":30.0}`), 0)
+
+	if err := client.CreateIndex("by_city", "city"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CreateIndex("by_age", "/age"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CreateIndex("by_city", "city"); !reflect.DeepEqual(err, ErrIndexExists) {
+		t.Fatal("error mismatch")
+	}
+
+	lookup := func(name string, value interface{}) []string {
+		t.Helper()
+		docs, err := client.LookupIndex(name, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return docKeys(docs)
+	}
+
+	// existing documents are indexed on creation
+	if keys := lookup("by_city", "Sydney"); !reflect.DeepEqual(keys, []string{"alice"}) {
+		t.Fatalf("keys mismatch %v", keys)
+	}
+	if keys := lookup("by_age", 30); !reflect.DeepEqual(keys, []string{"alice", "bob"}) {
+		t.Fatalf("keys mismatch %v", keys)
+	}
+
+	// writes keep the index up to date
+	_, _ = client.InsertRaw("carol", []byte(`{"city":"Sydney"}`), 0)
+	_, _ = client.UpsertRaw("alice", []byte(`{"city":"Perth"}`), 0)
+	_, _ = client.Replace("bob", map[string]string{"city": "Darwin"}, 1, 0)
+	if keys := lookup("by_city", "Sydney"); !reflect.DeepEqual(keys, []string{"carol"}) {
+		t.Fatalf("keys mismatch %v", keys)
+	}
+	if keys := lookup("by_city", "Perth"); !reflect.DeepEqual(keys, []string{"alice"}) {
+		t.Fatalf("keys mismatch %v", keys)
+	}
+	if keys := lookup("by_age", 30); len(keys) != 0 {
+		t.Fatalf("removed field still indexed %v", keys)
+	}
+
+	_, _ = client.Remove("carol", 1)
+	_, _ = client.UpsertRaw("alice", []byte{0xff}, 0)
+	if keys := lookup("by_city", "Sydney"); len(keys) != 0 {
+		t.Fatal("removed document still indexed")
+	}
+	if keys := lookup("by_city", "Perth"); len(keys) != 0 {
+		t.Fatal("binary document still indexed")
+	}
+
+	if err := client.DropIndex("by_city"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.LookupIndex("by_city", "Darwin"); !reflect.DeepEqual(err, ErrIndexNotFound) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestSecondaryIndexSkipsExpired(t *testing.T) {
+	client := New()
+	_ = client.CreateIndex("by_city", "city")
+	_, _ = client.InsertRaw("alice", []byte(`{"city":"Sydney"}`), 0)
+	expireKey(client, "alice")
+
+	if docs, _ := client.LookupIndex("by_city", "Sydney"); len(docs) != 0 {
+		t.Fatal("expired document returned")
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	a, _ := decodeJSON([]byte(`{"b":[1.0,"x"],"a":null}`))
+	b, _ := decodeJSON([]byte(`{"a":null,"b":[1,"x"]}`))
+	if canonicalJSON(a) != canonicalJSON(b) {
+		t.Fatal("equal values encoded differently")
+	}
+	if !jsonEqual(a, b) {
+		t.Fatal("equal values compared unequal")
+	}
+}
+
+func TestCompositeIndex(t *testing.T) {
+	client := New()
+	orders := map[string]string{
+		"o1": `{"tenant":"acme","status":"open","total":5}`,
+		"o2": `{"tenant":"acme","status":"closed","total":7}`,
+		"o3": `{"tenant":"initech","status":"open","total":1}`,
+		"o4": `{"tenant":"acme","status":"open","total":3}`,
+		"o5": `{"tenant":"acme"}`,
+	}
+	for key, value := range orders {
+		_, _ = client.InsertRaw(key, []byte(value), 0)
+	}
+	if err := client.CreateCompositeIndex("by_tenant_status", "tenant", "status"); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := client.LookupCompositeIndex("by_tenant_status", "acme", "open")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := docKeys(docs); !reflect.DeepEqual(keys, []string{"o1", "o4"}) {
+		t.Fatalf("keys mismatch %v", keys)
+	}
+
+	// leading values match a prefix of the tuple, ordered by the remaining fields.
+	// Documents missing a field are not indexed.
+	docs, _ = client.LookupCompositeIndex("by_tenant_status", "acme")
+	if keys := docKeys(docs); !reflect.DeepEqual(keys, []string{"o2", "o1", "o4"}) {
+		t.Fatalf("keys mismatch %v", keys)
+	}
+	docs, _ = client.LookupIndex("by_tenant_status", "initech")
+	if keys := docKeys(docs); !reflect.DeepEqual(keys, []string{"o3"}) {
+		t.Fatalf("keys mismatch %v", keys)
+	}
+
+	_, _ = client.Replace("o4", map[string]string{"tenant": "acme", "status": "closed"}, 1, 0)
+	docs, _ = client.LookupCompositeIndex("by_tenant_status", "acme", "closed")
+	if keys := docKeys(docs); !reflect.DeepEqual(keys, []string{"o2", "o4"}) {
+		t.Fatalf("keys mismatch %v", keys)
+	}
+
+	if _, err := client.LookupCompositeIndex("by_tenant_status", "acme", "open", 1); !reflect.DeepEqual(err, ErrInvalidIndexLookup) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestUniqueIndex(t *testing.T) {
+	client := NewSharded(4)
+	_, _ = client.InsertRaw("alice", []byte(`{"email":"alice@example.com"}`), 0)
+	_, _ = client.InsertRaw("bob", []byte(`{"email":"bob@example.com"}`), 0)
+
+	if err := client.CreateUniqueIndex("by_email", "email"); err != nil {
+		t.Fatal(err)
+	}
+
+	dup := []byte(`{"email":"alice@example.com"}`)
+	if _, err := client.InsertRaw("carol", dup, 0); !reflect.DeepEqual(err, ErrUniqueViolation) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.UpsertRaw("bob", dup, 0); !reflect.DeepEqual(err, ErrUniqueViolation) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.Replace("bob", map[string]string{"email": "alice@example.com"}, 1, 0); !reflect.DeepEqual(err, ErrUniqueViolation) {
+		t.Fatal("error mismatch")
+	}
+	if ok, _, _ := client.Exists("carol"); ok {
+		t.Fatal("violating insert was stored")
+	}
+	var act map[string]string
+	if _, err := client.Get("bob", &act); err != nil || act["email"] != "bob@example.com" {
+		t.Fatal("violating upsert was stored")
+	}
+
+	// a document may keep its own value, and values of removed or expired documents can be reused
+	if _, err := client.UpsertRaw("alice", dup, 0); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = client.Remove("bob", 1)
+	if _, err := client.InsertRaw("carol", []byte(`{"email":"bob@example.com"}`), 0); err != nil {
+		t.Fatal(err)
+	}
+	expireKey(client, "alice")
+	if _, err := client.InsertRaw("dave", dup, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// a transaction may swap values between documents but not duplicate them
+	txn := client.Begin()
+	_ = txn.Replace("carol", map[string]string{"email": "alice@example.com"}, 1, 0)
+	_ = txn.Replace("dave", map[string]string{"email": "bob@example.com"}, 1, 0)
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	txn = client.Begin()
+	_ = txn.Insert("erin", map[string]string{"email": "erin@example.com"}, 0)
+	_ = txn.Insert("frank", map[string]string{"email": "erin@example.com"}, 0)
+	if err := txn.Commit(); !reflect.DeepEqual(err, ErrUniqueViolation) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestUniqueIndexExistingDuplicates(t *testing.T) {
+	client := New()
+	_, _ = client.InsertRaw("a", []byte(`{"email":"x"}`), 0)
+	_, _ = client.InsertRaw("b", []byte(`{"email":"x"}`), 0)
+
+	if err := client.CreateUniqueIndex("by_email", "email"); !reflect.DeepEqual(err, ErrUniqueViolation) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.LookupIndex("by_email", "x"); !reflect.DeepEqual(err, ErrIndexNotFound) {
+		t.Fatal("index was created despite duplicates")
+	}
+}
+
+func TestUniqueIndexConcurrentInserts(t *testing.T) {
+	client := NewSharded(8)
+	_ = client.CreateUniqueIndex("by_email", "email")
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		success int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := client.InsertRaw(strconv.Itoa(i), []byte(`{"email":"same"}`), 0)
+			if err == nil {
+				mu.Lock()
+				success++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if success != 1 {
+		t.Fatalf("%d documents stored with the same unique value", success)
+	}
+}
@@ -0,0 +1,21 @@
+package crud
+
+import "unique"
+
+// WithKeyInterning keeps a single copy of each distinct key, shared by the documents of the store and the
+// change log, audit log, key heat and other bookkeeping recording their keys. Without it, every key built by
+// a caller for an operation may be retained separately, which dominates the memory used by fixtures with
+// millions of small documents. Interning costs a lookup in a process wide table on every write.
+func WithKeyInterning() Option {
+	return func(crud *CRUD) {
+		crud.internKeys = true
+	}
+}
+
+// intern returns the canonical copy of key when keys are interned, otherwise key itself
+func (crud *CRUD) intern(key string) string {
+	if !crud.internKeys {
+		return key
+	}
+	return unique.Make(key).Value()
+}
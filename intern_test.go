@@ -0,0 +1,32 @@
+package crud
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestKeyInterning(t *testing.T) {
+	// distinct allocations of the same key, as callers building keys for each operation make
+	key := func() string {
+		return string([]byte("doc::1"))
+	}
+	shared := func(client *CRUD) bool {
+		_, _ = client.Insert(key(), 1, 0)
+		_, _ = client.Upsert(key(), 2, 0)
+		_, _ = client.Get(key(), new(int))
+		entries := client.AuditLog()
+		for _, e := range entries[1:] {
+			if unsafe.StringData(e.Key) != unsafe.StringData(entries[0].Key) {
+				return false
+			}
+		}
+		return len(entries) == 3
+	}
+
+	if shared(New(WithAuditLog(10))) {
+		t.Fatal("keys shared without interning")
+	}
+	if !shared(New(WithAuditLog(10), WithKeyInterning())) {
+		t.Fatal("keys not shared")
+	}
+}
@@ -0,0 +1,41 @@
+package crud
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// WithTTLJitter randomises relative expiries by up to pct percent in either direction,
+// modelling the jitter real caches add to avoid stampedes. The jitter is drawn from a source
+// seeded with seed so test runs are reproducible. Absolute expiries are applied unchanged.
+func WithTTLJitter(pct float64, seed int64) Option {
+	return func(crud *CRUD) {
+		crud.jitter = &ttlJitter{
+			pct: pct / 100,
+			rnd: rand.New(rand.NewSource(seed)),
+		}
+	}
+}
+
+// ttlJitter applies a seeded random jitter to relative TTLs
+type ttlJitter struct {
+	mu  sync.Mutex
+	pct float64
+	rnd *rand.Rand
+}
+
+// apply returns ttl adjusted by a random factor within the configured percentage.
+// The result is always positive.
+func (j *ttlJitter) apply(ttl time.Duration) time.Duration {
+	j.mu.Lock()
+	factor := 1 + j.pct*(2*j.rnd.Float64()-1)
+	j.mu.Unlock()
+
+	jittered := time.Duration(math.Round(float64(ttl) * factor))
+	if jittered < 1 {
+		return 1
+	}
+	return jittered
+}
@@ -0,0 +1,50 @@
+package crud
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTTLJitter(t *testing.T) {
+	client := New(WithTTLJitter(10, 7))
+
+	varied := false
+	for i := 0; i < 50; i++ {
+		key := strconv.Itoa(i)
+		_, _ = client.Insert(key, "val", 1000)
+
+		// allow for the clock ticking between the write and the read
+		ttl := expiresIn(client, key)
+		if ttl < 899*time.Second || ttl > 1100*time.Second {
+			t.Fatalf("jittered ttl %s outside of 10%%", ttl)
+		}
+		if ttl < 999*time.Second || ttl > 1000*time.Second {
+			varied = true
+		}
+	}
+	if !varied {
+		t.Fatal("expiries were not jittered")
+	}
+}
+
+func TestTTLJitterSeeded(t *testing.T) {
+	first := New(WithTTLJitter(25, 7))
+	second := New(WithTTLJitter(25, 7))
+
+	for i := 0; i < 50; i++ {
+		if first.jitter.apply(3600) != second.jitter.apply(3600) {
+			t.Fatal("jitter is not reproducible for the same seed")
+		}
+	}
+}
+
+func TestTTLJitterSkipsAbsoluteExpiry(t *testing.T) {
+	client := New(WithTTLJitter(50, 1))
+	abs := uint32(client.now().Unix() + ThirtyDaySeconds*2)
+
+	_, _ = client.Insert("key", "val", abs)
+	if stored(client, "key").TTL != time.Unix(int64(abs), 0).UnixNano() {
+		t.Fatal("absolute expiry was jittered")
+	}
+}
@@ -0,0 +1,162 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrKeyCollision defines the error value returned by InsertAuto when every generated key already exists
+var ErrKeyCollision = errors.New("generated key collides with an existing document")
+
+// maxKeyAttempts is the number of keys InsertAuto will try before giving up with ErrKeyCollision
+const maxKeyAttempts = 16
+
+// KeyGenerator produces document keys for InsertAuto.
+// Implementations must be safe for concurrent use.
+type KeyGenerator interface {
+	NextKey() string
+}
+
+// WithKeyGenerator sets the KeyGenerator used by InsertAuto. The default is SequentialKeys.
+func WithKeyGenerator(gen KeyGenerator) Option {
+	return func(crud *CRUD) {
+		crud.keygen = gen
+	}
+}
+
+// counterKeys generates keys by appending an incrementing counter to a fixed prefix
+type counterKeys struct {
+	prefix string
+	n      uint64
+}
+
+func (g *counterKeys) NextKey() string {
+	return g.prefix + strconv.FormatUint(atomic.AddUint64(&g.n, 1), 10)
+}
+
+// reset restarts the counter, see Flush
+func (g *counterKeys) reset() {
+	atomic.StoreUint64(&g.n, 0)
+}
+
+// SequentialKeys returns a KeyGenerator producing "1", "2", "3", ...
+func SequentialKeys() KeyGenerator {
+	return &counterKeys{}
+}
+
+// PrefixKeys returns a KeyGenerator producing prefix+"1", prefix+"2", prefix+"3", ...
+func PrefixKeys(prefix string) KeyGenerator {
+	return &counterKeys{prefix: prefix}
+}
+
+// crockford is the base32 alphabet used by ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidKeys generates monotonic ULIDs. Keys generated within the same millisecond
+// increment the random component of the previous key so ordering is preserved.
+type ulidKeys struct {
+	mu      sync.Mutex
+	rnd     *rand.Rand
+	lastMs  uint64
+	lastRnd [10]byte
+}
+
+// ULIDKeys returns a KeyGenerator producing lexicographically sortable ULIDs.
+// The random component is drawn from a source seeded with seed, so the sequence is reproducible
+// for a given clock. InsertAuto timestamps the keys with the store's clock, see WithClock and FreezeTime.
+func ULIDKeys(seed int64) KeyGenerator {
+	return &ulidKeys{rnd: rand.New(rand.NewSource(seed))}
+}
+
+func (g *ulidKeys) NextKey() string {
+	return g.nextKeyAt(time.Now())
+}
+
+// nextKeyAt generates the key for the time now, see nextKey
+func (g *ulidKeys) nextKeyAt(now time.Time) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(now.UnixNano() / int64(time.Millisecond))
+	if ms > g.lastMs {
+		g.lastMs = ms
+		g.rnd.Read(g.lastRnd[:])
+	} else {
+		// same (or earlier) millisecond: keep the timestamp and increment the random part
+		for i := len(g.lastRnd) - 1; i >= 0; i-- {
+			g.lastRnd[i]++
+			if g.lastRnd[i] != 0 {
+				break
+			}
+		}
+	}
+
+	var id [16]byte
+	for i := 0; i < 6; i++ {
+		id[i] = byte(g.lastMs >> uint(40-8*i))
+	}
+	copy(id[6:], g.lastRnd[:])
+	return encodeULID(id)
+}
+
+// encodeULID encodes 128 bits as 26 Crockford base32 characters
+func encodeULID(id [16]byte) string {
+	out := make([]byte, 26)
+	// 130 bits of output for 128 bits of input: the first character only carries 3 bits
+	var acc uint64
+	bits := uint(2)
+	pos := 0
+	for _, b := range id {
+		acc = acc<<8 | uint64(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			out[pos] = crockford[(acc>>bits)&31]
+			pos++
+		}
+	}
+	return string(out)
+}
+
+// nextKey returns the next key of the configured KeyGenerator, passing the store's time to the
+// generators that use one
+func (crud *CRUD) nextKey() string {
+	if g, ok := crud.keygen.(interface{ nextKeyAt(time.Time) string }); ok {
+		return g.nextKeyAt(crud.now())
+	}
+	return crud.keygen.NextKey()
+}
+
+// InsertAuto inserts value under a key produced by the configured KeyGenerator and returns the key.
+// Generated keys that already exist are skipped; after repeated collisions ErrKeyCollision is returned.
+func (crud *CRUD) InsertAuto(value interface{}, expiry uint32) (string, uint64, error) {
+	release, err := crud.admitWrite(context.Background())
+	if err != nil {
+		return "", 0, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpInsert); err != nil {
+		return "", 0, err
+	}
+
+	for i := 0; i < maxKeyAttempts; i++ {
+		key := crud.nextKey()
+		s := crud.shardFor(key)
+		s.lock()
+		cas, err := crud.insert(key, value, writeOptions{expiry: legacyExpiry(expiry)})
+		s.mu.Unlock()
+		if err == ErrKeyExist || err == ErrDocumentLocked {
+			continue
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		return key, cas, nil
+	}
+	return "", 0, ErrKeyCollision
+}
@@ -0,0 +1,100 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInsertAutoSequential(t *testing.T) {
+	client := New()
+
+	for _, exp := range []string{"1", "2", "3"} {
+		key, cas, err := client.InsertAuto("val", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != exp {
+			t.Fatalf("expected key %q, got %q", exp, key)
+		}
+		if cas != 1 {
+			t.Fatal("cas mismatch")
+		}
+	}
+}
+
+func TestInsertAutoSkipsCollisions(t *testing.T) {
+	client := New(WithKeyGenerator(PrefixKeys("user::")))
+	_, _ = client.Insert("user::1", "taken", 0)
+	_, _ = client.Insert("user::2", "taken", 0)
+
+	key, _, err := client.InsertAuto("val", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "user::3" {
+		t.Fatalf("expected key user::3, got %q", key)
+	}
+
+	var act string
+	if _, err := client.Get(key, &act); err != nil {
+		t.Fatal(err)
+	}
+	if act != "val" {
+		t.Fatal("results mismatch")
+	}
+}
+
+type constKey string
+
+func (k constKey) NextKey() string { return string(k) }
+
+func TestInsertAutoCollision(t *testing.T) {
+	client := New(WithKeyGenerator(constKey("fixed")))
+	if _, _, err := client.InsertAuto("val", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := client.InsertAuto("val", 0)
+	if !reflect.DeepEqual(err, ErrKeyCollision) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestULIDKeys(t *testing.T) {
+	gen := ULIDKeys(42)
+
+	prev := gen.NextKey()
+	for i := 0; i < 100; i++ {
+		key := gen.NextKey()
+		if len(key) != 26 {
+			t.Fatalf("unexpected ULID length %d", len(key))
+		}
+		if key <= prev {
+			t.Fatalf("ULIDs not monotonic: %q <= %q", key, prev)
+		}
+		prev = key
+	}
+}
+
+func TestULIDKeysStoreClock(t *testing.T) {
+	client := New(WithClock(newManualClock()), WithKeyGenerator(ULIDKeys(42)))
+	client.FreezeTime()
+
+	var keys []string
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			client.Advance(time.Millisecond)
+		}
+		key, _, err := client.InsertAuto("val", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+
+	exp := []string{"01HF7YAT00AE67Z5NHCJZHQ5XV", "01HF7YAT00AE67Z5NHCJZHQ5XW", "01HF7YAT01KX5V8WQ8KXDH917J"}
+	if !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("keys mismatch: %q", keys)
+	}
+}
@@ -0,0 +1,93 @@
+package crud
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// ErrInvalidKey defines the error value returned when a write stores a document under a key breaking the key rules
+var ErrInvalidKey = errors.New("invalid key")
+
+// KeyRules are the constraints the keys of new documents must satisfy, see WithKeyRules
+type KeyRules struct {
+	// MaxLength is the maximum length of keys in bytes, zero when unlimited
+	MaxLength int
+	// AllowEmpty accepts the empty key
+	AllowEmpty bool
+	// AllowControl accepts keys containing ASCII control characters
+	AllowControl bool
+	// AllowBinary accepts keys which aren't valid UTF-8
+	AllowBinary bool
+}
+
+// StrictKeyRules are the key rules of Couchbase Server and its SDKs: keys are non-empty,
+// at most 250 bytes long, valid UTF-8 and free of control characters
+var StrictKeyRules = KeyRules{MaxLength: 250}
+
+// WithKeyRules makes writes storing a new document under a key breaking rules, such as Insert, Upsert,
+// counters and transactions, return ErrInvalidKey. Without it every key is accepted.
+// Documents restored by Load, RollbackTo and WithBackend aren't checked.
+func WithKeyRules(rules KeyRules) Option {
+	return func(crud *CRUD) {
+		crud.keyRules = &rules
+	}
+}
+
+// WithBinaryKeys accepts or rejects keys which aren't valid UTF-8, on top of the rules given to an earlier
+// WithKeyRules or WithStrictKeys. Without key rules, it rejects binary keys and accepts every other key.
+// Binary keys are base64 encoded under "key_base64" instead of "key" in JSON snapshots, write-ahead logs,
+// fixture files, webhook events and remote requests, since JSON strings can only carry UTF-8.
+func WithBinaryKeys(allow bool) Option {
+	return func(crud *CRUD) {
+		rules := KeyRules{AllowEmpty: true, AllowControl: true}
+		if crud.keyRules != nil {
+			rules = *crud.keyRules
+		}
+		rules.AllowBinary = allow
+		crud.keyRules = &rules
+	}
+}
+
+// WithStrictKeys applies StrictKeyRules, so that keys the real server would reject fail in tests too
+func WithStrictKeys() Option {
+	return WithKeyRules(StrictKeyRules)
+}
+
+// validateKey returns ErrInvalidKey when key breaks the key rules
+func (crud *CRUD) validateKey(key string) error {
+	rules := crud.keyRules
+	if rules == nil {
+		return nil
+	}
+	if (key == "" && !rules.AllowEmpty) || (rules.MaxLength > 0 && len(key) > rules.MaxLength) {
+		return ErrInvalidKey
+	}
+	if !rules.AllowBinary && !utf8.ValidString(key) {
+		return ErrInvalidKey
+	}
+	if !rules.AllowControl {
+		for i := 0; i < len(key); i++ {
+			if key[i] < 0x20 || key[i] == 0x7f {
+				return ErrInvalidKey
+			}
+		}
+	}
+	return nil
+}
+
+// encodeKey returns the fields holding key in JSON documents: keys which aren't valid UTF-8, which JSON
+// strings can't carry, are returned base64 encodable with the string field left empty
+func encodeKey(key string) (string, []byte) {
+	if utf8.ValidString(key) {
+		return key, nil
+	}
+	return "", []byte(key)
+}
+
+// decodeKey returns the key held by fields returned by encodeKey
+func decodeKey(key string, keyBase64 []byte) string {
+	if keyBase64 != nil {
+		return string(keyBase64)
+	}
+	return key
+}
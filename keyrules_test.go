@@ -0,0 +1,121 @@
+package crud
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStrictKeys(t *testing.T) {
+	client := New(WithStrictKeys())
+	for _, key := range []string{"", strings.Repeat("k", 251), "tab\tkey", "del\x7f"} {
+		if _, err := client.Insert(key, 1, 0); !reflect.DeepEqual(err, ErrInvalidKey) {
+			t.Fatalf("error mismatch for %q", key)
+		}
+		if _, err := client.Upsert(key, 1, 0); !reflect.DeepEqual(err, ErrInvalidKey) {
+			t.Fatalf("error mismatch for %q", key)
+		}
+	}
+	if _, _, err := client.Increment("", 1, 0, 0); !reflect.DeepEqual(err, ErrInvalidKey) {
+		t.Fatal("error mismatch")
+	}
+	txn := client.Begin()
+	_ = txn.Insert("", 1, 0)
+	if err := txn.Commit(); !reflect.DeepEqual(err, ErrInvalidKey) {
+		t.Fatal("error mismatch")
+	}
+	for _, key := range []string{strings.Repeat("k", 250), "user::ünïcode"} {
+		if _, err := client.Insert(key, 1, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := New().Insert("", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeyRules(t *testing.T) {
+	client := New(WithKeyRules(KeyRules{MaxLength: 4, AllowEmpty: true, AllowControl: true}))
+	for _, key := range []string{"", "a\tb"} {
+		if _, err := client.Insert(key, 1, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.Insert("abcde", 1, 0); !reflect.DeepEqual(err, ErrInvalidKey) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestBinaryKeys(t *testing.T) {
+	const key = "legacy::\xff\xfe"
+	if _, err := New(WithBinaryKeys(false)).Insert(key, 1, 0); !reflect.DeepEqual(err, ErrInvalidKey) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := New(WithStrictKeys()).Insert(key, 1, 0); !reflect.DeepEqual(err, ErrInvalidKey) {
+		t.Fatal("error mismatch")
+	}
+	strict := New(WithStrictKeys(), WithBinaryKeys(true))
+	if _, err := strict.Insert(key, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := strict.Insert("", 1, 0); !reflect.DeepEqual(err, ErrInvalidKey) {
+		t.Fatal("error mismatch")
+	}
+
+	client := New()
+	_, _ = client.Insert(key, 1, 0)
+	_, _ = client.Insert("plain", 2, 0)
+
+	var buf bytes.Buffer
+	_ = client.Save(&buf)
+	if !strings.Contains(buf.String(), `"key_base64":"bGVnYWN5Ojr//g=="`) {
+		t.Fatal("snapshot mismatch")
+	}
+	restored := New()
+	if err := restored.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if keys := restored.Keys(); !reflect.DeepEqual(keys, []string{key, "plain"}) {
+		t.Fatal("keys mismatch")
+	}
+
+	for _, name := range []string{"keys.json", "keys.yaml"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := client.DumpFixtures(path); err != nil {
+			t.Fatal(err)
+		}
+		loaded := New()
+		if err := loaded.LoadFixtures(path); err != nil {
+			t.Fatal(err)
+		}
+		if keys := loaded.Keys(); !reflect.DeepEqual(keys, []string{key, "plain"}) {
+			t.Fatalf("%s keys mismatch", name)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "crud.wal")
+	logged, _ := NewFromWAL(path)
+	_, _ = logged.Insert(key, 1, 0)
+	_ = logged.CloseWAL()
+	replayed, err := NewFromWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replayed.CloseWAL()
+	if exists, _, _ := replayed.Exists(key); !exists {
+		t.Fatal("wal mismatch")
+	}
+
+	remote, err := Dial(serveUnix(t, client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+	var act int
+	if _, err := remote.Get(key, &act); err != nil || act != 1 {
+		t.Fatal("remote mismatch")
+	}
+}
@@ -0,0 +1,192 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"iter"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidPattern defines the error value returned when a key pattern can't be parsed
+var ErrInvalidPattern = errors.New("invalid key pattern")
+
+// Keys returns, in lexicographic order, the keys of every live document in the store
+func (crud *CRUD) Keys() []string {
+	return crud.KeysWithPrefix("")
+}
+
+// KeysWithPrefix returns, in lexicographic order, the keys of live documents starting with prefix
+func (crud *CRUD) KeysWithPrefix(prefix string) []string {
+	return crud.keysWhere(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// KeysMatching returns, in lexicographic order, the keys of live documents matching the glob pattern,
+// such as "order::*::2024*". In the pattern * matches any run of characters, ? any single character,
+// [abc], [a-z] and [!a-z] a character of a class, and \ escapes the character after it.
+// A malformed pattern returns ErrInvalidPattern.
+func (crud *CRUD) KeysMatching(pattern string) ([]string, error) {
+	re, err := globRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return crud.KeysMatchingRegexp(re), nil
+}
+
+// KeysMatchingRegexp returns, in lexicographic order, the keys of live documents matched by re.
+// re is unanchored, so it matches keys containing a match unless it uses ^ and $.
+func (crud *CRUD) KeysMatchingRegexp(re *regexp.Regexp) []string {
+	return crud.keysWhere(re.MatchString)
+}
+
+// keysWhere returns, in lexicographic order, the keys of live documents satisfying match
+func (crud *CRUD) keysWhere(match func(key string) bool) []string {
+	now := crud.getTime()
+	keys := []string{}
+	crud.rangeDocs(func(key string, doc *document) {
+		if match(key) && !doc.expired(now) {
+			keys = append(keys, key)
+		}
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+// globRegexp compiles a glob pattern into an anchored regular expression
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^(?s:")
+	src := []rune(pattern)
+	for i := 0; i < len(src); i++ {
+		switch r := src[i]; r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			if i++; i == len(src) {
+				return nil, ErrInvalidPattern
+			}
+			b.WriteString(regexp.QuoteMeta(string(src[i])))
+		case '[':
+			j := i + 1
+			if j < len(src) && (src[j] == '!' || src[j] == '^') {
+				j++
+			}
+			// a ] right after the opening bracket belongs to the class
+			end := j
+			if end < len(src) && src[end] == ']' {
+				end++
+			}
+			for end < len(src) && src[end] != ']' {
+				end++
+			}
+			if end == len(src) {
+				return nil, ErrInvalidPattern
+			}
+			b.WriteByte('[')
+			if j > i+1 {
+				b.WriteByte('^')
+			}
+			for k := j; k < end; k++ {
+				if src[k] == '-' && k > j && k < end-1 {
+					b.WriteRune('-')
+					continue
+				}
+				b.WriteString(regexp.QuoteMeta(string(src[k])))
+			}
+			b.WriteByte(']')
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString(")$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, ErrInvalidPattern
+	}
+	return re, nil
+}
+
+// All returns an iterator over the key and stored value of every live document, in key order.
+// The documents are those live when iteration starts, so the store may be modified while iterating.
+// Values are the bytes produced by the transcoder, which are only valid JSON with the default JSONTranscoder.
+func (crud *CRUD) All() iter.Seq2[string, json.RawMessage] {
+	return func(yield func(string, json.RawMessage) bool) {
+		for _, doc := range crud.scan(nil) {
+			if !yield(doc.Key, doc.Value) {
+				return
+			}
+		}
+	}
+}
+
+// ForEach calls fn with the key and stored value of every live document, in key order,
+// stopping at and returning the first error returned by fn. See All.
+func (crud *CRUD) ForEach(fn func(key string, value json.RawMessage) error) error {
+	for key, value := range crud.All() {
+		if err := fn(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Count returns the number of live documents in the store
+func (crud *CRUD) Count() int {
+	now := crud.getTime()
+	count := 0
+	crud.rangeDocs(func(key string, doc *document) {
+		if !doc.expired(now) {
+			count++
+		}
+	})
+	return count
+}
+
+// Flush removes every document from the store so it can be reused between tests.
+// It also discards the key heat, the change log retained for ResumeFrom, the revision history, the audit log and the
+// operation counters of Stats, and restarts the SequentialKeys and PrefixKeys generators.
+// No events are published for the removed documents.
+func (crud *CRUD) Flush() error {
+	release, err := crud.admitWrite(context.Background())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	unlock := crud.lockAll()
+	defer unlock()
+
+	for _, s := range crud.shards {
+		s.reset()
+	}
+	crud.stored.Store(0)
+	crud.memory.Store(0)
+	crud.resetEviction()
+	if crud.index != nil {
+		crud.index.clear()
+	}
+	crud.indexes.clear()
+	crud.feed.truncate()
+	crud.persistClear()
+	if crud.history != nil {
+		crud.history.clear()
+	}
+	if crud.audit != nil {
+		crud.audit.clear()
+	}
+	if crud.heat != nil {
+		crud.heat.reset()
+	}
+	crud.counters.reset()
+	if r, ok := crud.keygen.(interface{ reset() }); ok {
+		r.reset()
+	}
+	return nil
+}
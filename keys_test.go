@@ -0,0 +1,178 @@
+package crud
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestKeys(t *testing.T) {
+	client := NewSharded(4)
+	if keys := client.Keys(); len(keys) != 0 {
+		t.Fatal("empty store reported keys")
+	}
+
+	for _, key := range []string{"user::2", "order::1", "user::1", "user::3"} {
+		_, _ = client.Insert(key, "val", 0)
+	}
+	_, _ = client.Remove("user::3", 1)
+	_, _ = client.Insert("user::4", "val", 0)
+	expireKey(client, "user::4")
+
+	if keys := client.Keys(); !reflect.DeepEqual(keys, []string{"order::1", "user::1", "user::2"}) {
+		t.Fatalf("keys mismatch %v", keys)
+	}
+	if keys := client.KeysWithPrefix("user::"); !reflect.DeepEqual(keys, []string{"user::1", "user::2"}) {
+		t.Fatalf("keys mismatch %v", keys)
+	}
+	if keys := client.KeysWithPrefix("none"); len(keys) != 0 {
+		t.Fatal("keys mismatch")
+	}
+}
+
+func TestCount(t *testing.T) {
+	client := New()
+	if client.Count() != 0 {
+		t.Fatal("count mismatch")
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		_, _ = client.Insert(key, "val", 0)
+	}
+	expireKey(client, "c")
+	if client.Count() != 2 {
+		t.Fatal("count mismatch")
+	}
+
+	_, _ = client.Remove("a", 1)
+	if client.Reader().Count() != 1 {
+		t.Fatal("count mismatch")
+	}
+}
+
+func TestFlush(t *testing.T) {
+	client := NewSharded(4, WithKeyHeat(time.Minute))
+	for i := 0; i < 3; i++ {
+		_, _, _ = client.InsertAuto("val", 0)
+	}
+	first, _ := client.Watch()
+	_, _ = client.Upsert("1", "val2", 0)
+	token := nextEvent(t, first).Token()
+	first.Close()
+
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if client.Count() != 0 {
+		t.Fatal("documents survived the flush")
+	}
+	if len(client.TopKeys(10, ByWrites)) != 0 {
+		t.Fatal("key heat survived the flush")
+	}
+	if _, err := client.Watch(ResumeFrom(token)); !reflect.DeepEqual(err, ErrResumeTokenExpired) {
+		t.Fatal("error mismatch")
+	}
+
+	// the store is usable again, with keys generated from the start
+	key, cas, err := client.InsertAuto("val", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "1" || cas != 1 {
+		t.Fatal("store was not reset")
+	}
+}
+
+func TestAll(t *testing.T) {
+	client := NewSharded(4)
+	for _, key := range []string{"c", "a", "b"} {
+		_, _ = client.Insert(key, key+"-val", 0)
+	}
+
+	var keys []string
+	for key, value := range client.All() {
+		keys = append(keys, key)
+		if string(value) != `"`+key+`-val"` {
+			t.Fatalf("value mismatch %s", value)
+		}
+		// the store can be modified while iterating
+		_, _ = client.Upsert("z", "new", 0)
+	}
+	if !reflect.DeepEqual(keys, []string{"a", "b", "c"}) {
+		t.Fatalf("keys mismatch %v", keys)
+	}
+
+	for key := range client.All() {
+		if key != "a" {
+			t.Fatal("iteration did not start at the first key")
+		}
+		break
+	}
+}
+
+func TestForEach(t *testing.T) {
+	client := New()
+	for _, key := range []string{"a", "b", "c"} {
+		_, _ = client.Insert(key, "val", 0)
+	}
+
+	stop := errors.New("stop")
+	var keys []string
+	err := client.Reader().ForEach(func(key string, value json.RawMessage) error {
+		keys = append(keys, key)
+		if key == "b" {
+			return stop
+		}
+		return nil
+	})
+	if !reflect.DeepEqual(err, stop) {
+		t.Fatal("error mismatch")
+	}
+	if !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Fatalf("keys mismatch %v", keys)
+	}
+}
+
+func TestKeysMatching(t *testing.T) {
+	client := New()
+	for _, key := range []string{"order::1::2024-01", "order::2::2023-12", "order::10::2024-02", "user::1", "a*b", "a-b"} {
+		client.Insert(key, "v", 0)
+	}
+
+	tests := []struct {
+		pattern string
+		exp     []string
+	}{
+		{"order::*::2024*", []string{"order::10::2024-02", "order::1::2024-01"}},
+		{"order::?::*", []string{"order::1::2024-01", "order::2::2023-12"}},
+		{"order::[12]::*", []string{"order::1::2024-01", "order::2::2023-12"}},
+		{"order::[!1]::*", []string{"order::2::2023-12"}},
+		{"*::1*", []string{"order::10::2024-02", "order::1::2024-01", "user::1"}},
+		{`a\*b`, []string{"a*b"}},
+		{"a[*-]b", []string{"a*b", "a-b"}},
+		{"user", []string{}},
+	}
+	for _, test := range tests {
+		act, err := client.KeysMatching(test.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(act, test.exp) {
+			t.Fatalf("keys mismatch for %q: %v", test.pattern, act)
+		}
+	}
+
+	for _, pattern := range []string{"order::[12", `trailing\`} {
+		if _, err := client.KeysMatching(pattern); !reflect.DeepEqual(err, ErrInvalidPattern) {
+			t.Fatal("error mismatch")
+		}
+	}
+
+	act := client.KeysMatchingRegexp(regexp.MustCompile(`^order::\d+::2023`))
+	if !reflect.DeepEqual(act, []string{"order::2::2023-12"}) {
+		t.Fatal("keys mismatch")
+	}
+}
@@ -0,0 +1,121 @@
+package crud
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Operation identifies a kind of operation, see WithLatency
+type Operation int
+
+const (
+	// OpGet covers the reads of a single document: Get and its variants, GetRaw, GetMeta and Exists
+	OpGet Operation = iota + 1
+	// OpInsert covers Insert and its variants, including InsertAuto
+	OpInsert
+	// OpUpsert covers Upsert and its variants
+	OpUpsert
+	// OpReplace covers Replace and its variants
+	OpReplace
+	// OpRemove covers Remove and its variants
+	OpRemove
+	// OpTouch covers Touch and its variants
+	OpTouch
+	// OpGetAndTouch covers GetAndTouch and GetAndTouchWith
+	OpGetAndTouch
+	// OpGetAndLock covers GetAndLock
+	OpGetAndLock
+	// OpUnlock covers Unlock
+	OpUnlock
+	// OpCounter covers Increment and Decrement
+	OpCounter
+	// OpAppend covers Append and Prepend
+	OpAppend
+	// OpLookupIn covers LookupIn
+	OpLookupIn
+	// OpMutateIn covers MutateIn
+	OpMutateIn
+	// OpCommit covers Txn.Commit
+	OpCommit
+	// OpQuery covers Query, QueryView, Search and Aggregate
+	OpQuery
+)
+
+func (op Operation) String() string {
+	switch op {
+	case OpGet:
+		return "get"
+	case OpInsert:
+		return "insert"
+	case OpUpsert:
+		return "upsert"
+	case OpReplace:
+		return "replace"
+	case OpRemove:
+		return "remove"
+	case OpTouch:
+		return "touch"
+	case OpGetAndTouch:
+		return "get_and_touch"
+	case OpGetAndLock:
+		return "get_and_lock"
+	case OpUnlock:
+		return "unlock"
+	case OpCounter:
+		return "counter"
+	case OpAppend:
+		return "append"
+	case OpLookupIn:
+		return "lookup_in"
+	case OpMutateIn:
+		return "mutate_in"
+	case OpCommit:
+		return "commit"
+	case OpQuery:
+		return "query"
+	}
+	return "unknown"
+}
+
+// latency is the range of durations an operation is delayed by
+type latency struct {
+	min, max time.Duration
+}
+
+// WithLatency delays every operation of kind op by a duration drawn uniformly between min and max, or by
+// min when max isn't greater, to test timeouts and slow dependencies. The delay is spent before the operation
+// is applied, on the wall clock rather than the store's clock, while the operation holds its place in the queue,
+// see WithQueue. Operations given a context, such as GetCtx, return its error without being applied when it is
+// done before the delay ends. WithLatency can be given once for each kind of operation.
+func WithLatency(op Operation, min, max time.Duration) Option {
+	return func(crud *CRUD) {
+		if crud.latency == nil {
+			crud.latency = make(map[Operation]latency)
+		}
+		crud.latency[op] = latency{min: min, max: max}
+	}
+}
+
+// delay sleeps for the latency configured for op, returning the error of ctx if it is done first
+func (crud *CRUD) delay(ctx context.Context, op Operation) error {
+	l, ok := crud.latency[op]
+	if !ok {
+		return nil
+	}
+	d := l.min
+	if l.max > l.min {
+		d += time.Duration(rand.Int63n(int64(l.max-l.min) + 1))
+	}
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
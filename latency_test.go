@@ -0,0 +1,62 @@
+package crud
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithLatency(t *testing.T) {
+	client := New(WithLatency(OpGet, 20*time.Millisecond, 20*time.Millisecond))
+	_, _ = client.Insert("key", "val", 0)
+
+	start := time.Now()
+	var act string
+	if _, err := client.Get("key", &act); err != nil || act != "val" {
+		t.Fatal("value mismatch")
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("get took %s", elapsed)
+	}
+
+	start = time.Now()
+	if _, _, err := client.Reader().Exists("key"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("exists took %s", elapsed)
+	}
+}
+
+func TestWithLatencyJitter(t *testing.T) {
+	client := New(WithLatency(OpUpsert, 10*time.Millisecond, 30*time.Millisecond))
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		_, _ = client.Upsert("key", i, 0)
+		if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+			t.Fatalf("upsert took %s", elapsed)
+		}
+	}
+}
+
+func TestWithLatencyTimeout(t *testing.T) {
+	client := New(WithLatency(OpInsert, time.Second, time.Second), WithLatency(OpGet, time.Second, time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.InsertCtx(ctx, "key", "val", 0); !reflect.DeepEqual(err, context.DeadlineExceeded) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.GetCtx(ctx, "key", new(string)); !reflect.DeepEqual(err, context.DeadlineExceeded) {
+		t.Fatal("error mismatch")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("timed out operations took %s", elapsed)
+	}
+	// the timed out insert wasn't applied
+	if client.Count() != 0 {
+		t.Fatal("count mismatch")
+	}
+}
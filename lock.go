@@ -0,0 +1,103 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	// ErrDocumentLocked defines the error value returned when a document locked by GetAndLock is modified
+	// without the lock CAS or locked again
+	ErrDocumentLocked = errors.New("document locked")
+	// ErrDocumentNotLocked defines the error value returned when unlocking a document which isn't locked
+	ErrDocumentNotLocked = errors.New("document not locked")
+)
+
+// LockedCas is the CAS reported for a locked document to everyone but the lock holder, as Couchbase does
+const LockedCas = math.MaxUint64
+
+const (
+	// defaultLockTime is the lock time in seconds used when none or an out of range value is given
+	defaultLockTime = 15
+	// maxLockTime is the longest a document may be locked for in seconds
+	maxLockTime = 30
+)
+
+// GetAndLock retrieves a document and locks it for lockTime seconds. While locked, writes fail with
+// ErrDocumentLocked unless they present the returned CAS; a successful Replace or Remove with it releases the lock.
+// Lock times of zero or above 30 seconds fall back to the default of 15 seconds.
+func (crud *CRUD) GetAndLock(key string, lockTime uint32, valuePtr interface{}) (uint64, error) {
+	release, err := crud.admitWrite(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpGetAndLock); err != nil {
+		return 0, err
+	}
+
+	s := crud.shardFor(key)
+	s.lock()
+	defer s.mu.Unlock()
+
+	doc, err := crud.lookup(key)
+	if err != nil {
+		return 0, err
+	}
+
+	now := crud.getTime()
+	if doc.locked(now) {
+		return 0, ErrDocumentLocked
+	}
+
+	if err := crud.transcoder.Unmarshal(doc.value(), valuePtr); err != nil {
+		return 0, err
+	}
+	crud.recordRead(key, doc.size(), doc.Cas)
+
+	if lockTime == 0 || lockTime > maxLockTime {
+		lockTime = defaultLockTime
+	}
+	doc.LockedUntil = now + int64(time.Duration(lockTime)*time.Second)
+	// locking hands out a fresh CAS so that earlier CAS values can't bypass the lock
+	cas := doc.Cas
+	doc.Cas = crud.nextCas(cas)
+	crud.counters.locks.Add(1)
+	crud.recordAudit(AuditLock, crud.intern(key), cas, doc.Cas)
+	crud.persistPut(key, doc)
+
+	return doc.Cas, nil
+}
+
+// Unlock releases a lock taken by GetAndLock. cas must be the CAS returned by GetAndLock.
+func (crud *CRUD) Unlock(key string, cas uint64) error {
+	release, err := crud.admitWrite(context.Background())
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpUnlock); err != nil {
+		return err
+	}
+
+	s := crud.shardFor(key)
+	s.lock()
+	defer s.mu.Unlock()
+
+	doc, err := crud.lookup(key)
+	if err != nil {
+		return err
+	}
+
+	if !doc.locked(crud.getTime()) {
+		return ErrDocumentNotLocked
+	}
+	if doc.Cas != cas {
+		return ErrCasMismatch
+	}
+
+	doc.LockedUntil = 0
+	return nil
+}
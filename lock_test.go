@@ -0,0 +1,103 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetAndLock(t *testing.T) {
+	client := New()
+	cas, _ := client.Insert("key", "val", 0)
+
+	var act string
+	lockCas, err := client.GetAndLock("key", 10, &act)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act != "val" {
+		t.Fatal("results mismatch")
+	}
+	if lockCas == cas {
+		t.Fatal("lock did not change cas")
+	}
+
+	// reads still succeed but hide the real CAS
+	getCas, err := client.Get("key", &act)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if getCas != LockedCas {
+		t.Fatal("cas mismatch")
+	}
+
+	if _, err := client.GetAndLock("key", 10, &act); !reflect.DeepEqual(err, ErrDocumentLocked) {
+		t.Fatal("error mismatch")
+	}
+
+	// the lock holder can replace the document, which releases the lock
+	cas2, err := client.Replace("key", "val2", lockCas, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Upsert("key", "val3", 0); err != nil {
+		t.Fatal(err)
+	}
+	if cas2 != lockCas+1 {
+		t.Fatal("cas mismatch")
+	}
+}
+
+func TestLockedDocumentErrors(t *testing.T) {
+	client := New()
+	cas, _ := client.Insert("key", "val", 0)
+
+	var act string
+	lockCas, _ := client.GetAndLock("key", 10, &act)
+
+	locked := map[string]error{}
+	_, locked["insert"] = client.Insert("key", "val", 0)
+	_, locked["upsert"] = client.Upsert("key", "val", 0)
+	_, locked["replace"] = client.Replace("key", "val", cas, 0)
+	_, locked["remove"] = client.Remove("key", cas)
+	_, locked["touch"] = client.Touch("key", lockCas, 0)
+	for op, err := range locked {
+		if !reflect.DeepEqual(err, ErrDocumentLocked) {
+			t.Fatalf("%s: error mismatch %v", op, err)
+		}
+	}
+
+	meta, err := client.GetWithMeta("key", &act)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.Locked || meta.Cas != LockedCas {
+		t.Fatalf("unexpected meta %+v", meta)
+	}
+
+	if err := client.Unlock("key", cas); !reflect.DeepEqual(err, ErrCasMismatch) {
+		t.Fatal("error mismatch")
+	}
+	if err := client.Unlock("key", lockCas); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Unlock("key", lockCas); !reflect.DeepEqual(err, ErrDocumentNotLocked) {
+		t.Fatal("error mismatch")
+	}
+
+	if _, err := client.Remove("key", lockCas); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLockExpires(t *testing.T) {
+	client := New()
+	_, _ = client.Insert("key", "val", 0)
+
+	var act string
+	_, _ = client.GetAndLock("key", 10, &act)
+	stored(client, "key").LockedUntil = client.getTime()
+
+	if _, err := client.Upsert("key", "val2", 0); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,143 @@
+package crud
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrChecksumMismatch defines the error value returned when the checksum given to IfMatchChecksum
+// doesn't match the current document
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Datatype describes the content of a document
+type Datatype int
+
+const (
+	// DatatypeJSON marks documents whose value is valid JSON
+	DatatypeJSON Datatype = iota
+	// DatatypeBinary marks documents whose value is opaque bytes
+	DatatypeBinary
+)
+
+func (d Datatype) String() string {
+	if d == DatatypeJSON {
+		return "json"
+	}
+	return "binary"
+}
+
+// detectDatatype inspects a value the way Couchbase does on writes, flagging it as JSON only when it parses
+func detectDatatype(value []byte) Datatype {
+	if json.Valid(value) {
+		return DatatypeJSON
+	}
+	return DatatypeBinary
+}
+
+// checksum returns the content hash of a value, suitable for use as an HTTP ETag
+func checksum(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
+
+// IfMatchChecksum makes a write conditional on the current document having the given checksum,
+// as reported by Meta.Checksum. The write fails with ErrChecksumMismatch otherwise, including when
+// the document doesn't exist.
+func IfMatchChecksum(sum string) WriteOption {
+	return func(o *writeOptions) {
+		o.checksum = sum
+	}
+}
+
+// Meta holds the metadata of a document
+type Meta struct {
+	// Cas is the current CAS of the document, or LockedCas when it is locked
+	Cas uint64
+	// Expiry is the time the document expires, zero when it never does
+	Expiry time.Time
+	// Locked is true when the document is locked by GetAndLock
+	Locked bool
+	// Datatype reports whether the document holds JSON or binary content
+	Datatype Datatype
+	// Checksum is the content hash of the document, changing whenever its value does
+	Checksum string
+	// Size is the length of the stored value in bytes
+	Size int
+	// Deleted is true when the document has expired but has not been purged yet
+	Deleted bool
+	// Created is the time the document was stored, which updates keep
+	Created time.Time
+	// Updated is the time the document was last mutated, including changes of its expiry
+	Updated time.Time
+}
+
+// meta builds the Meta of doc at the given Unix time in nanoseconds
+func (d *document) meta(now int64) Meta {
+	m := Meta{
+		Cas:      d.reportedCas(now),
+		Locked:   d.locked(now),
+		Datatype: d.Datatype,
+		Checksum: d.Checksum,
+		Size:     d.size(),
+		Deleted:  d.expired(now),
+		Created:  time.Unix(0, d.Created),
+		Updated:  time.Unix(0, d.Updated),
+	}
+	if d.TTL > 0 {
+		m.Expiry = time.Unix(0, d.TTL)
+	}
+	return m
+}
+
+// GetWithMeta provides the Get Database Operation, also returning the document's metadata.
+// Locked documents are returned along with Meta.Locked set.
+func (crud *CRUD) GetWithMeta(key string, valuePtr interface{}) (Meta, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return Meta{}, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpGet); err != nil {
+		return Meta{}, err
+	}
+
+	doc, err := crud.fetch(key)
+	if err != nil {
+		return Meta{}, err
+	}
+
+	if err := crud.transcoder.Unmarshal(doc.value(), valuePtr); err != nil {
+		return Meta{}, err
+	}
+	crud.recordRead(key, doc.size(), doc.Cas)
+
+	return doc.meta(crud.getTime()), nil
+}
+
+// GetMeta returns the metadata of the document stored under key without decoding its value.
+// Expired documents which haven't been purged yet are reported with Meta.Deleted set rather than as missing,
+// and are left in place.
+func (crud *CRUD) GetMeta(key string) (Meta, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return Meta{}, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpGet); err != nil {
+		return Meta{}, err
+	}
+
+	s := crud.shardFor(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc, ok := s.docs[key]
+	if !ok {
+		return Meta{}, ErrKeyNotExist
+	}
+	return doc.meta(crud.getTime()), nil
+}
@@ -0,0 +1,128 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetWithMeta(t *testing.T) {
+	client := New()
+	at := time.Now().Add(time.Hour).Truncate(time.Second)
+	cas, _ := client.InsertWith("key", "val", WithAbsoluteExpiry(at))
+
+	var act string
+	meta, err := client.GetWithMeta("key", &act)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act != "val" {
+		t.Fatal("results mismatch")
+	}
+	if meta.Cas != cas || meta.Locked || !meta.Expiry.Equal(at) || meta.Datatype != DatatypeJSON {
+		t.Fatalf("unexpected meta %+v", meta)
+	}
+}
+
+func TestDetectDatatype(t *testing.T) {
+	cases := map[string]Datatype{
+		`{"a":1}`:           DatatypeJSON,
+		`"string"`:          DatatypeJSON,
+		`42`:                DatatypeJSON,
+		`{"a":`:             DatatypeBinary,
+		"\x89PNG\r\n\x1a\n": DatatypeBinary,
+		``:                  DatatypeBinary,
+	}
+	for value, exp := range cases {
+		if act := detectDatatype([]byte(value)); act != exp {
+			t.Fatalf("%q: expected %s, got %s", value, exp, act)
+		}
+	}
+}
+
+func TestIfMatchChecksum(t *testing.T) {
+	client := New()
+	_, _ = client.Insert("key", "val", 0)
+
+	var act string
+	meta, _ := client.GetWithMeta("key", &act)
+	if meta.Checksum == "" {
+		t.Fatal("missing checksum")
+	}
+
+	if _, err := client.UpsertWith("key", "val2", IfMatchChecksum(meta.Checksum)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the stale checksum no longer matches
+	_, err := client.UpsertWith("key", "val3", IfMatchChecksum(meta.Checksum))
+	if !reflect.DeepEqual(err, ErrChecksumMismatch) {
+		t.Fatal("error mismatch")
+	}
+
+	meta2, _ := client.GetWithMeta("key", &act)
+	if act != "val2" || meta2.Checksum == meta.Checksum {
+		t.Fatal("results mismatch")
+	}
+
+	_, err = client.ReplaceWith("key", "val3", meta2.Cas, IfMatchChecksum(meta.Checksum))
+	if !reflect.DeepEqual(err, ErrChecksumMismatch) {
+		t.Fatal("error mismatch")
+	}
+
+	_, err = client.UpsertWith("missing", "val", IfMatchChecksum(meta.Checksum))
+	if !reflect.DeepEqual(err, ErrChecksumMismatch) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestGetMeta(t *testing.T) {
+	client := New()
+
+	if _, err := client.GetMeta("key"); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+
+	_, _ = client.InsertRaw("key", []byte("not json"), 100)
+	meta, err := client.GetMeta("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Cas != 1 || meta.Size != 8 || meta.Deleted {
+		t.Fatalf("meta mismatch %+v", meta)
+	}
+	if meta.Expiry.IsZero() {
+		t.Fatal("expiry mismatch")
+	}
+
+	expireKey(client, "key")
+	if meta, err = client.GetMeta("key"); err != nil || !meta.Deleted {
+		t.Fatal("expired document not reported as deleted")
+	}
+}
+
+func TestMetaTimestamps(t *testing.T) {
+	clock := newManualClock()
+	client := New(WithClock(clock))
+	created := clock.Now()
+	cas, _ := client.Insert("key", "val", 0)
+
+	meta, _ := client.GetMeta("key")
+	if !meta.Created.Equal(created) || !meta.Updated.Equal(created) {
+		t.Fatal("timestamp mismatch")
+	}
+
+	clock.add(time.Minute)
+	cas, _ = client.Replace("key", "val2", cas, 0)
+	meta, _ = client.GetMeta("key")
+	if !meta.Created.Equal(created) || !meta.Updated.Equal(created.Add(time.Minute)) {
+		t.Fatal("timestamp mismatch")
+	}
+
+	clock.add(time.Minute)
+	_, _ = client.Touch("key", cas, 60)
+	meta, _ = client.GetMeta("key")
+	if !meta.Created.Equal(created) || !meta.Updated.Equal(created.Add(2*time.Minute)) {
+		t.Fatal("timestamp mismatch")
+	}
+}
@@ -0,0 +1,834 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidQuery defines the error value returned when a query statement can't be parsed
+var ErrInvalidQuery = errors.New("invalid query statement")
+
+// QueryResult holds the rows produced by Query, each encoded as JSON
+type QueryResult struct {
+	Rows []json.RawMessage
+}
+
+// All decodes every row into slicePtr, which must point to a slice
+func (r *QueryResult) All(slicePtr interface{}) error {
+	data, err := json.Marshal(r.Rows)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, slicePtr)
+}
+
+// Query runs a statement written in a small subset of N1QL against the live JSON documents:
+//
+//	SELECT [RAW] * | expr [AS alias], ... FROM keyspace
+//	[WHERE cond] [ORDER BY expr [ASC|DESC], ...] [LIMIT n] [OFFSET n]
+//
+// Expressions are field paths such as address.city or tags[0], META().id, META().cas, string, number
+// and boolean literals, NULL, MISSING and the parameters $1, $2, ... or ?, which are bound to params in order.
+// Conditions combine the comparisons =, ==, !=, <>, <, <=, >, >=, LIKE, IS [NOT] NULL and IS [NOT] MISSING
+// with AND, OR, NOT and parentheses. Comparing a missing or null value yields neither true nor false, as in N1QL.
+//
+// SELECT * returns each document under the keyspace name; other fields are named by their alias or the
+// last field of their path, and are left out of the row when missing. SELECT RAW returns the bare values.
+// The keyspace name is only used to name the rows, as every query runs against the whole store.
+func (crud *CRUD) Query(statement string, params ...interface{}) (*QueryResult, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpQuery); err != nil {
+		return nil, err
+	}
+
+	q, err := parseQuery(statement, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []*queryRow
+	for _, doc := range crud.scan(nil) {
+		root, err := decodeJSON(doc.Value)
+		if err != nil {
+			continue
+		}
+		row := &queryRow{key: doc.Key, cas: doc.Cas, root: root}
+		if q.where != nil {
+			if v, ok := q.where.eval(row); !ok || v != true {
+				continue
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	if len(q.order) > 0 {
+		sort.SliceStable(rows, func(i, j int) bool {
+			for _, term := range q.order {
+				c := compareMissing(term.expr, rows[i], rows[j])
+				if c != 0 {
+					return (c < 0) != term.desc
+				}
+			}
+			return false
+		})
+	}
+
+	if q.offset >= len(rows) {
+		rows = nil
+	} else {
+		rows = rows[q.offset:]
+	}
+	if q.limit >= 0 && q.limit < len(rows) {
+		rows = rows[:q.limit]
+	}
+
+	res := &QueryResult{Rows: make([]json.RawMessage, 0, len(rows))}
+	for _, row := range rows {
+		out, ok := q.project(row)
+		if !ok {
+			continue
+		}
+		data, err := json.Marshal(out)
+		if err != nil {
+			return nil, err
+		}
+		res.Rows = append(res.Rows, data)
+	}
+	return res, nil
+}
+
+// queryRow is a document considered by a query
+type queryRow struct {
+	key  string
+	cas  uint64
+	root interface{}
+}
+
+// queryExpr is an expression evaluated against a row. eval returns false when the value is missing.
+type queryExpr interface {
+	eval(row *queryRow) (interface{}, bool)
+}
+
+// compareMissing orders the values of expr for two rows, with missing values first
+func compareMissing(expr queryExpr, a, b *queryRow) int {
+	va, okA := expr.eval(a)
+	vb, okB := expr.eval(b)
+	if okA && okB {
+		return jsonCompare(va, vb)
+	}
+	switch {
+	case okA:
+		return 1
+	case okB:
+		return -1
+	}
+	return 0
+}
+
+type literalExpr struct {
+	value interface{}
+}
+
+func (e literalExpr) eval(*queryRow) (interface{}, bool) {
+	return e.value, true
+}
+
+type missingExpr struct{}
+
+func (missingExpr) eval(*queryRow) (interface{}, bool) {
+	return nil, false
+}
+
+type pathExpr struct {
+	segs []pathSegment
+}
+
+func (e pathExpr) eval(row *queryRow) (interface{}, bool) {
+	return getPath(row.root, e.segs)
+}
+
+// metaExpr reads a document's metadata: its key or CAS
+type metaExpr struct {
+	field string
+}
+
+func (e metaExpr) eval(row *queryRow) (interface{}, bool) {
+	if e.field == "cas" {
+		return json.Number(strconv.FormatUint(row.cas, 10)), true
+	}
+	return row.key, true
+}
+
+type compareExpr struct {
+	op          string
+	left, right queryExpr
+	// like is the compiled LIKE pattern when the right operand is a literal or a parameter
+	like *regexp.Regexp
+}
+
+func (e compareExpr) eval(row *queryRow) (interface{}, bool) {
+	a, okA := e.left.eval(row)
+	b, okB := e.right.eval(row)
+	if !okA || !okB {
+		return nil, false
+	}
+	if a == nil || b == nil {
+		return nil, true
+	}
+	switch e.op {
+	case "=":
+		return jsonEqual(a, b), true
+	case "!=":
+		return !jsonEqual(a, b), true
+	case "like":
+		s, okS := a.(string)
+		pattern, okP := b.(string)
+		if !okS || !okP {
+			return nil, true
+		}
+		if e.like != nil {
+			return e.like.MatchString(s), true
+		}
+		return likePattern(pattern).MatchString(s), true
+	}
+	c := jsonCompare(a, b)
+	switch e.op {
+	case "<":
+		return c < 0, true
+	case "<=":
+		return c <= 0, true
+	case ">":
+		return c > 0, true
+	}
+	return c >= 0, true
+}
+
+// likePattern compiles a LIKE pattern, where % matches any run of characters and _ any single character
+func likePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^(?s:")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString(")$")
+	return regexp.MustCompile(b.String())
+}
+
+// isExpr tests whether a value is null or missing
+type isExpr struct {
+	expr    queryExpr
+	missing bool
+	not     bool
+}
+
+func (e isExpr) eval(row *queryRow) (interface{}, bool) {
+	v, ok := e.expr.eval(row)
+	var is bool
+	if e.missing {
+		is = !ok
+	} else {
+		if !ok {
+			return nil, false
+		}
+		is = v == nil
+	}
+	return is != e.not, true
+}
+
+// logicExpr combines two conditions with AND or OR, using N1QL's logic for null and missing operands
+type logicExpr struct {
+	or          bool
+	left, right queryExpr
+}
+
+func (e logicExpr) eval(row *queryRow) (interface{}, bool) {
+	a, okA := e.left.eval(row)
+	b, okB := e.right.eval(row)
+	// a false operand decides AND, a true one decides OR
+	if (okA && a == e.or) || (okB && b == e.or) {
+		return e.or, true
+	}
+	if okA && okB && a == !e.or && b == !e.or {
+		return !e.or, true
+	}
+	if !okA || !okB {
+		return nil, false
+	}
+	return nil, true
+}
+
+type notExpr struct {
+	expr queryExpr
+}
+
+func (e notExpr) eval(row *queryRow) (interface{}, bool) {
+	v, ok := e.expr.eval(row)
+	if b, isBool := v.(bool); isBool {
+		return !b, true
+	}
+	return nil, ok
+}
+
+// selectItem is a projected expression of a query
+type selectItem struct {
+	expr  queryExpr
+	alias string
+	// star selects the whole document
+	star bool
+}
+
+// orderTerm is an expression of an ORDER BY clause
+type orderTerm struct {
+	expr queryExpr
+	desc bool
+}
+
+// query is a parsed statement
+type query struct {
+	raw      bool
+	items    []selectItem
+	keyspace string
+	where    queryExpr
+	order    []orderTerm
+	limit    int
+	offset   int
+}
+
+// project builds the output of a row, returning false when a raw projection is missing
+func (q *query) project(row *queryRow) (interface{}, bool) {
+	if q.raw {
+		return q.items[0].expr.eval(row)
+	}
+	out := make(map[string]interface{}, len(q.items))
+	for _, item := range q.items {
+		if item.star {
+			out[q.keyspace] = row.root
+			continue
+		}
+		if v, ok := item.expr.eval(row); ok {
+			out[item.alias] = v
+		}
+	}
+	return out, true
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokQuotedIdent
+	tokNumber
+	tokString
+	tokParam
+	tokSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// lexQuery splits a statement into tokens
+func lexQuery(statement string) ([]token, error) {
+	var toks []token
+	src := []rune(statement)
+	for i := 0; i < len(src); {
+		r := src[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(src) && (unicode.IsLetter(src[j]) || unicode.IsDigit(src[j]) || src[j] == '_') {
+				j++
+			}
+			toks = append(toks, token{tokIdent, string(src[i:j])})
+			i = j
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(src) && (unicode.IsDigit(src[j]) || strings.ContainsRune(".eE", src[j]) ||
+				((src[j] == '+' || src[j] == '-') && (src[j-1] == 'e' || src[j-1] == 'E'))) {
+				j++
+			}
+			text := string(src[i:j])
+			if !json.Valid([]byte(text)) {
+				return nil, ErrInvalidQuery
+			}
+			toks = append(toks, token{tokNumber, text})
+			i = j
+		case r == '\'' || r == '"' || r == '`':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(src); j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+				} else if src[j] == r {
+					// a doubled quote stands for the quote itself
+					if j+1 < len(src) && src[j+1] == r {
+						j++
+					} else {
+						break
+					}
+				}
+				b.WriteRune(src[j])
+			}
+			if j >= len(src) {
+				return nil, ErrInvalidQuery
+			}
+			kind := tokString
+			if r == '`' {
+				kind = tokQuotedIdent
+			}
+			toks = append(toks, token{kind, b.String()})
+			i = j + 1
+		case r == '$':
+			j := i + 1
+			for j < len(src) && unicode.IsDigit(src[j]) {
+				j++
+			}
+			if j == i+1 {
+				return nil, ErrInvalidQuery
+			}
+			toks = append(toks, token{tokParam, string(src[i+1 : j])})
+			i = j
+		case r == '?':
+			toks = append(toks, token{tokParam, ""})
+			i++
+		default:
+			if i+1 < len(src) {
+				if two := string(src[i : i+2]); two == "==" || two == "!=" || two == "<>" || two == "<=" || two == ">=" {
+					toks = append(toks, token{tokSymbol, two})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune(",.()*[]=<>-;", r) {
+				return nil, ErrInvalidQuery
+			}
+			toks = append(toks, token{tokSymbol, string(r)})
+			i++
+		}
+	}
+	return append(toks, token{kind: tokEOF}), nil
+}
+
+// queryKeywords are the reserved words, which must be quoted with backticks to be used as field names
+var queryKeywords = map[string]bool{
+	"SELECT": true, "RAW": true, "FROM": true, "WHERE": true, "AND": true, "OR": true, "NOT": true,
+	"ORDER": true, "BY": true, "ASC": true, "DESC": true, "LIMIT": true, "OFFSET": true, "AS": true,
+	"IS": true, "NULL": true, "MISSING": true, "TRUE": true, "FALSE": true, "LIKE": true,
+}
+
+// queryParser is a recursive descent parser over the tokens of a statement
+type queryParser struct {
+	toks   []token
+	pos    int
+	params []interface{}
+	// nextParam is the index of the parameter bound to the next ?
+	nextParam int
+}
+
+func (p *queryParser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *queryParser) advance() token {
+	tok := p.toks[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword consumes the next token if it is the keyword kw
+func (p *queryParser) keyword(kw string) bool {
+	if tok := p.peek(); tok.kind == tokIdent && strings.EqualFold(tok.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// symbol consumes the next token if it is the symbol sym
+func (p *queryParser) symbol(sym string) bool {
+	if tok := p.peek(); tok.kind == tokSymbol && tok.text == sym {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// identifier consumes a field or keyspace name. Keywords are accepted when allowKeyword is set.
+func (p *queryParser) identifier(allowKeyword bool) (string, error) {
+	tok := p.peek()
+	switch {
+	case tok.kind == tokQuotedIdent:
+	case tok.kind == tokIdent && (allowKeyword || !queryKeywords[strings.ToUpper(tok.text)]):
+	default:
+		return "", ErrInvalidQuery
+	}
+	p.pos++
+	return tok.text, nil
+}
+
+// integer consumes a non-negative integer literal or parameter
+func (p *queryParser) integer() (int, error) {
+	expr, err := p.operand()
+	if err != nil {
+		return 0, err
+	}
+	lit, ok := expr.(literalExpr)
+	if !ok {
+		return 0, ErrInvalidQuery
+	}
+	num, ok := lit.value.(json.Number)
+	if !ok {
+		return 0, ErrInvalidQuery
+	}
+	n, err := strconv.Atoi(string(num))
+	if err != nil || n < 0 {
+		return 0, ErrInvalidQuery
+	}
+	return n, nil
+}
+
+// parseQuery parses a statement, binding its parameters to params
+func parseQuery(statement string, params []interface{}) (*query, error) {
+	toks, err := lexQuery(statement)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{toks: toks, params: params}
+	q := &query{limit: -1}
+
+	if !p.keyword("SELECT") {
+		return nil, ErrInvalidQuery
+	}
+	q.raw = p.keyword("RAW")
+	for {
+		var item selectItem
+		if !q.raw && p.symbol("*") {
+			item.star = true
+		} else {
+			if item.expr, err = p.expr(); err != nil {
+				return nil, err
+			}
+			if p.keyword("AS") {
+				if item.alias, err = p.identifier(true); err != nil {
+					return nil, err
+				}
+			}
+		}
+		q.items = append(q.items, item)
+		if q.raw || !p.symbol(",") {
+			break
+		}
+	}
+
+	if !p.keyword("FROM") {
+		return nil, ErrInvalidQuery
+	}
+	if q.keyspace, err = p.identifier(false); err != nil {
+		return nil, err
+	}
+	for i, item := range q.items {
+		if item.alias == "" && !item.star {
+			q.items[i].alias = defaultAlias(item.expr, i)
+		}
+	}
+
+	if p.keyword("WHERE") {
+		if q.where, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("ORDER") {
+		if !p.keyword("BY") {
+			return nil, ErrInvalidQuery
+		}
+		for {
+			var term orderTerm
+			if term.expr, err = p.expr(); err != nil {
+				return nil, err
+			}
+			if p.keyword("DESC") {
+				term.desc = true
+			} else {
+				p.keyword("ASC")
+			}
+			q.order = append(q.order, term)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+	for {
+		switch {
+		case p.keyword("LIMIT"):
+			q.limit, err = p.integer()
+		case p.keyword("OFFSET"):
+			q.offset, err = p.integer()
+		default:
+			p.symbol(";")
+			if p.peek().kind != tokEOF {
+				return nil, ErrInvalidQuery
+			}
+			return q, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// defaultAlias names a projected expression without an alias like N1QL: by the last field of a path,
+// by the metadata field, or else by its position as $1, $2, ...
+func defaultAlias(expr queryExpr, i int) string {
+	switch e := expr.(type) {
+	case pathExpr:
+		if last := e.segs[len(e.segs)-1]; !last.isIndex {
+			return last.field
+		}
+	case metaExpr:
+		return e.field
+	}
+	return "$" + strconv.Itoa(i+1)
+}
+
+// expr parses a condition or operand
+func (p *queryParser) expr() (queryExpr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = logicExpr{or: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) and() (queryExpr, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = logicExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) not() (queryExpr, error) {
+	if p.keyword("NOT") {
+		expr, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr}, nil
+	}
+	return p.comparison()
+}
+
+// compareOps maps the comparison symbols to their canonical operator
+var compareOps = map[string]string{
+	"=": "=", "==": "=", "!=": "!=", "<>": "!=", "<": "<", "<=": "<=", ">": ">", ">=": ">=",
+}
+
+func (p *queryParser) comparison() (queryExpr, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.keyword("IS") {
+		is := isExpr{expr: left, not: p.keyword("NOT")}
+		switch {
+		case p.keyword("NULL"):
+		case p.keyword("MISSING"):
+			is.missing = true
+		default:
+			return nil, ErrInvalidQuery
+		}
+		return is, nil
+	}
+
+	negate := p.keyword("NOT")
+	if p.keyword("LIKE") {
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		like := compareExpr{op: "like", left: left, right: right}
+		if lit, ok := right.(literalExpr); ok {
+			if pattern, ok := lit.value.(string); ok {
+				like.like = likePattern(pattern)
+			}
+		}
+		var expr queryExpr = like
+		if negate {
+			expr = notExpr{expr}
+		}
+		return expr, nil
+	}
+	if negate {
+		return nil, ErrInvalidQuery
+	}
+
+	if tok := p.peek(); tok.kind == tokSymbol {
+		if op, ok := compareOps[tok.text]; ok {
+			p.pos++
+			right, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			return compareExpr{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+// operand parses a literal, parameter, path, META() field or parenthesised expression
+func (p *queryParser) operand() (queryExpr, error) {
+	tok := p.peek()
+	switch {
+	case p.symbol("("):
+		expr, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.symbol(")") {
+			return nil, ErrInvalidQuery
+		}
+		return expr, nil
+	case p.symbol("-"):
+		if num := p.advance(); num.kind == tokNumber {
+			return literalExpr{json.Number("-" + num.text)}, nil
+		}
+		return nil, ErrInvalidQuery
+	case tok.kind == tokNumber:
+		p.pos++
+		return literalExpr{json.Number(tok.text)}, nil
+	case tok.kind == tokString:
+		p.pos++
+		return literalExpr{tok.text}, nil
+	case tok.kind == tokParam:
+		p.pos++
+		return p.param(tok.text)
+	case p.keyword("TRUE"):
+		return literalExpr{true}, nil
+	case p.keyword("FALSE"):
+		return literalExpr{false}, nil
+	case p.keyword("NULL"):
+		return literalExpr{nil}, nil
+	case p.keyword("MISSING"):
+		return missingExpr{}, nil
+	case tok.kind == tokIdent && strings.EqualFold(tok.text, "META") && p.toks[p.pos+1].text == "(":
+		return p.meta()
+	}
+	return p.path()
+}
+
+// param binds the parameter named by a $n or ? token
+func (p *queryParser) param(name string) (queryExpr, error) {
+	i := p.nextParam
+	if name != "" {
+		n, err := strconv.Atoi(name)
+		if err != nil || n < 1 {
+			return nil, ErrInvalidQuery
+		}
+		i = n - 1
+	} else {
+		p.nextParam++
+	}
+	if i >= len(p.params) {
+		return nil, ErrInvalidQuery
+	}
+	data, err := json.Marshal(p.params[i])
+	if err != nil {
+		return nil, err
+	}
+	value, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return literalExpr{value}, nil
+}
+
+// meta parses META().id or META().cas, optionally naming the keyspace within the parentheses
+func (p *queryParser) meta() (queryExpr, error) {
+	p.pos += 2
+	if !p.symbol(")") {
+		if _, err := p.identifier(false); err != nil || !p.symbol(")") {
+			return nil, ErrInvalidQuery
+		}
+	}
+	if !p.symbol(".") {
+		return nil, ErrInvalidQuery
+	}
+	field, err := p.identifier(true)
+	if err != nil {
+		return nil, err
+	}
+	switch field = strings.ToLower(field); field {
+	case "id", "cas":
+		return metaExpr{field}, nil
+	}
+	return nil, ErrInvalidQuery
+}
+
+// path parses a field path such as address.city or tags[0].name
+func (p *queryParser) path() (queryExpr, error) {
+	field, err := p.identifier(false)
+	if err != nil {
+		return nil, err
+	}
+	segs := []pathSegment{{field: field}}
+	for {
+		switch {
+		case p.symbol("."):
+			if field, err = p.identifier(true); err != nil {
+				return nil, err
+			}
+			segs = append(segs, pathSegment{field: field})
+		case p.symbol("["):
+			sign := ""
+			if p.symbol("-") {
+				sign = "-"
+			}
+			tok := p.advance()
+			index, err := strconv.Atoi(sign + tok.text)
+			if tok.kind != tokNumber || err != nil || !p.symbol("]") {
+				return nil, ErrInvalidQuery
+			}
+			segs = append(segs, pathSegment{index: index, isIndex: true})
+		default:
+			return pathExpr{segs}, nil
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func seedQuery(client *CRUD) {
+	client.Insert("u1", map[string]interface{}{"name": "alice", "age": 30, "city": "paris", "tags": []string{"admin"}}, 0)
+	client.Insert("u2", map[string]interface{}{"name": "bob", "age": 25, "city": "london"}, 0)
+	client.Insert("u3", map[string]interface{}{"name": "carol", "age": 35, "city": "paris", "nick": nil}, 0)
+	client.Insert("u4", map[string]interface{}{"name": "dave", "city": "berlin"}, 0)
+	client.UpsertRaw("bin", []byte("not json"), 0)
+}
+
+// queryNames runs a RAW query and returns the selected strings
+func queryNames(t *testing.T, client *CRUD, statement string, params ...interface{}) []string {
+	res, err := client.Query(statement, params...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	if err := res.All(&names); err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestQueryWhere(t *testing.T) {
+	client := New()
+	seedQuery(client)
+
+	tests := []struct {
+		statement string
+		params    []interface{}
+		exp       []string
+	}{
+		{"SELECT RAW name FROM users", nil, []string{"alice", "bob", "carol", "dave"}},
+		{"SELECT RAW name FROM users WHERE city = 'paris'", nil, []string{"alice", "carol"}},
+		{"select raw name from users where age >= 30 and city == \"paris\"", nil, []string{"alice", "carol"}},
+		{"SELECT RAW name FROM users WHERE age < 30 OR city = 'berlin'", nil, []string{"bob", "dave"}},
+		{"SELECT RAW name FROM users WHERE NOT (age > 26)", nil, []string{"bob"}},
+		{"SELECT RAW name FROM users WHERE age != 30", nil, []string{"bob", "carol"}},
+		{"SELECT RAW name FROM users WHERE age IS MISSING", nil, []string{"dave"}},
+		{"SELECT RAW name FROM users WHERE nick IS NULL", nil, []string{"carol"}},
+		{"SELECT RAW name FROM users WHERE name LIKE '_a%'", nil, []string{"carol", "dave"}},
+		{"SELECT RAW name FROM users WHERE name NOT LIKE '%o%'", nil, []string{"alice", "dave"}},
+		{"SELECT RAW name FROM users WHERE name LIKE $1", []interface{}{"%l%"}, []string{"alice", "carol"}},
+		{"SELECT RAW name FROM users WHERE city LIKE name", nil, []string{}},
+		{"SELECT RAW name FROM users WHERE tags[0] = 'admin'", nil, []string{"alice"}},
+		{"SELECT RAW name FROM users WHERE META().id = 'u2'", nil, []string{"bob"}},
+		{"SELECT RAW name FROM users WHERE city = $2 AND age > $1", []interface{}{26, "paris"}, []string{"alice", "carol"}},
+		{"SELECT RAW name FROM users WHERE age > ? AND age < ?", []interface{}{26, 33}, []string{"alice"}},
+	}
+	for _, test := range tests {
+		if act := queryNames(t, client, test.statement, test.params...); !reflect.DeepEqual(act, test.exp) {
+			t.Fatalf("results mismatch for %q: %v", test.statement, act)
+		}
+	}
+}
+
+func TestQueryLikeCompiledOnce(t *testing.T) {
+	for _, params := range [][]interface{}{nil, {"_a%"}} {
+		statement := "SELECT RAW name FROM users WHERE name LIKE '_a%'"
+		if params != nil {
+			statement = "SELECT RAW name FROM users WHERE name LIKE ?"
+		}
+		q, err := parseQuery(statement, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.where.(compareExpr).like == nil {
+			t.Fatal("pattern not compiled")
+		}
+	}
+
+	q, err := parseQuery("SELECT RAW name FROM users WHERE city LIKE name", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.where.(compareExpr).like != nil {
+		t.Fatal("pattern mismatch")
+	}
+}
+
+func TestQueryOrderLimit(t *testing.T) {
+	client := New()
+	seedQuery(client)
+
+	tests := []struct {
+		statement string
+		exp       []string
+	}{
+		{"SELECT RAW name FROM users ORDER BY age DESC", []string{"carol", "alice", "bob", "dave"}},
+		{"SELECT RAW name FROM users ORDER BY age", []string{"dave", "bob", "alice", "carol"}},
+		{"SELECT RAW name FROM users ORDER BY city, name DESC", []string{"dave", "bob", "carol", "alice"}},
+		{"SELECT RAW name FROM users ORDER BY name LIMIT 2 OFFSET 1;", []string{"bob", "carol"}},
+		{"SELECT RAW name FROM users OFFSET 10", []string{}},
+	}
+	for _, test := range tests {
+		if act := queryNames(t, client, test.statement); !reflect.DeepEqual(act, test.exp) {
+			t.Fatalf("results mismatch for %q: %v", test.statement, act)
+		}
+	}
+}
+
+func TestQueryProjection(t *testing.T) {
+	client := New()
+	seedQuery(client)
+
+	res, err := client.Query("SELECT META().id, name AS n, age FROM users WHERE city = 'berlin' OR name = 'bob' ORDER BY n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []map[string]interface{}
+	if err := res.All(&rows); err != nil {
+		t.Fatal(err)
+	}
+	exp := []map[string]interface{}{
+		{"id": "u2", "n": "bob", "age": float64(25)},
+		{"id": "u4", "n": "dave"},
+	}
+	if !reflect.DeepEqual(rows, exp) {
+		t.Fatal("rows mismatch")
+	}
+
+	res, err = client.Query("SELECT * FROM `users` WHERE name = 'bob'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var star []map[string]map[string]interface{}
+	if err := res.All(&star); err != nil {
+		t.Fatal(err)
+	}
+	if len(star) != 1 || star[0]["users"]["city"] != "london" {
+		t.Fatal("rows mismatch")
+	}
+}
+
+func TestQueryInvalid(t *testing.T) {
+	client := New()
+	for _, statement := range []string{
+		"",
+		"SELECT",
+		"SELECT name",
+		"SELECT name FROM",
+		"SELECT name FROM users WHERE",
+		"SELECT name FROM users WHERE name = 'x",
+		"SELECT name FROM users WHERE name = $1",
+		"SELECT name FROM users LIMIT -1",
+		"SELECT name FROM users ORDER name",
+		"SELECT name FROM users extra",
+		"SELECT name FROM users WHERE name IS 3",
+		"SELECT META().rev FROM users",
+		"DELETE FROM users",
+	} {
+		if _, err := client.Query(statement); !reflect.DeepEqual(err, ErrInvalidQuery) {
+			t.Fatalf("error mismatch for %q", statement)
+		}
+	}
+}
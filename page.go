@@ -0,0 +1,88 @@
+package crud
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+)
+
+// ErrInvalidCursor defines the error value returned when a pagination cursor was not issued by a scan
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is an opaque continuation token resuming a paginated scan after the last document of a page
+type Cursor string
+
+// Page is a page of documents returned by a paginated scan
+type Page struct {
+	Docs []Document
+	// Next resumes the scan after this page, empty when there are no more documents
+	Next Cursor
+}
+
+// after returns the key a scan resumed from cursor must start after, empty for the first page
+func (c Cursor) after() (string, error) {
+	if c == "" {
+		return "", nil
+	}
+	key, err := base64.RawURLEncoding.DecodeString(string(c))
+	if err != nil {
+		return "", ErrInvalidCursor
+	}
+	return string(key), nil
+}
+
+func cursorAfter(key string) Cursor {
+	return Cursor(base64.RawURLEncoding.EncodeToString([]byte(key)))
+}
+
+// RangePage returns a page of at most pageSize documents of the Range from startKey to endKey,
+// resuming after cursor. Pass an empty cursor for the first page and the Next cursor of the previous page
+// for the following ones; the last page has an empty Next cursor. A pageSize of zero or less returns every
+// remaining document in one page. Documents written behind the cursor while paginating are not returned.
+func (crud *CRUD) RangePage(startKey, endKey string, pageSize int, cursor Cursor) (Page, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return Page{}, err
+	}
+	defer release()
+
+	after, err := cursor.after()
+	if err != nil {
+		return Page{}, err
+	}
+	if cursor != "" {
+		// resume strictly after the last key returned
+		if next := after + "\x00"; next > startKey {
+			startKey = next
+		}
+	}
+
+	if pageSize <= 0 {
+		return Page{Docs: crud.scanRange(startKey, endKey, 0)}, nil
+	}
+	// fetch one extra document to learn whether there is another page
+	docs := crud.scanRange(startKey, endKey, pageSize+1)
+	if len(docs) <= pageSize {
+		return Page{Docs: docs}, nil
+	}
+	docs = docs[:pageSize]
+	return Page{Docs: docs, Next: cursorAfter(docs[pageSize-1].Key)}, nil
+}
+
+// ScanPrefixPage returns a page of at most pageSize of the documents ScanPrefix returns, resuming after cursor.
+// See RangePage for the use of cursors.
+func (crud *CRUD) ScanPrefixPage(prefix string, pageSize int, cursor Cursor) (Page, error) {
+	return crud.RangePage(prefix, prefixEnd(prefix), pageSize, cursor)
+}
+
+// prefixEnd returns the smallest key greater than every key starting with prefix, empty when there is none
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRangePage(t *testing.T) {
+	for name, client := range map[string]*CRUD{
+		"scan":  New(),
+		"index": New(WithKeyIndex()),
+	} {
+		for i := 0; i < 7; i++ {
+			_, _ = client.Insert(fmt.Sprintf("key-%d", i), i, 0)
+		}
+
+		var (
+			keys   []string
+			pages  int
+			cursor Cursor
+		)
+		for {
+			page, err := client.RangePage("key-1", "", 2, cursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pages++
+			for _, doc := range page.Docs {
+				keys = append(keys, doc.Key)
+			}
+			if page.Next == "" {
+				break
+			}
+			cursor = page.Next
+			if pages == 1 {
+				// documents written behind the cursor are not returned
+				_, _ = client.Insert("key-10", 10, 0)
+			}
+		}
+		if pages != 3 {
+			t.Fatalf("%s: page count mismatch %d", name, pages)
+		}
+		if !reflect.DeepEqual(keys, []string{"key-1", "key-2", "key-3", "key-4", "key-5", "key-6"}) {
+			t.Fatalf("%s: keys mismatch %v", name, keys)
+		}
+	}
+}
+
+func TestScanPrefixPage(t *testing.T) {
+	client := New()
+	for _, key := range []string{"a::1", "a::2", "a::3", "b::1"} {
+		_, _ = client.Insert(key, key, 0)
+	}
+
+	page, err := client.ScanPrefixPage("a::", 3, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an exactly full last page has no next cursor
+	if len(page.Docs) != 3 || page.Next != "" {
+		t.Fatal("page mismatch")
+	}
+
+	page, _ = client.ScanPrefixPage("a::", 2, "")
+	page, _ = client.ScanPrefixPage("a::", 2, page.Next)
+	if len(page.Docs) != 1 || page.Docs[0].Key != "a::3" || page.Next != "" {
+		t.Fatal("page mismatch")
+	}
+
+	if _, err := client.ScanPrefixPage("a::", 2, "not a cursor!"); !reflect.DeepEqual(err, ErrInvalidCursor) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestPrefixEnd(t *testing.T) {
+	cases := map[string]string{
+		"":         "",
+		"a":        "b",
+		"user::":   "user:;",
+		"a\xff":    "b",
+		"\xff\xff": "",
+	}
+	for prefix, exp := range cases {
+		if act := prefixEnd(prefix); act != exp {
+			t.Fatalf("prefixEnd(%q) = %q, expected %q", prefix, act, exp)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package crud
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPath defines the error value returned when a document path can't be parsed
+	ErrInvalidPath = errors.New("invalid document path")
+	// ErrNotJSON defines the error value returned when a path based operation targets a binary document
+	ErrNotJSON = errors.New("document is not JSON")
+)
+
+// pathSegment is a single step of a document path: either an object field or an array index.
+// Index segments parsed from a JSON pointer also keep their token as field, as they may name an object member.
+type pathSegment struct {
+	field   string
+	index   int
+	isIndex bool
+}
+
+// parsePath parses a sub-document path such as "address.city", "tags[0]", "items[-1].name" or
+// "`dotted.field`.value". Negative indexes count from the end of an array.
+func parsePath(path string) ([]pathSegment, error) {
+	if path == "" {
+		return nil, ErrInvalidPath
+	}
+
+	var (
+		segs  []pathSegment
+		field strings.Builder
+		// pending is true when field holds a segment which hasn't been appended yet
+		pending bool
+	)
+	flush := func() error {
+		if !pending {
+			return nil
+		}
+		if field.Len() == 0 {
+			return ErrInvalidPath
+		}
+		segs = append(segs, pathSegment{field: field.String()})
+		field.Reset()
+		pending = false
+		return nil
+	}
+
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '`':
+			// quoted field name, a doubled backtick is a literal backtick
+			pending = true
+			for i++; ; i++ {
+				if i >= len(path) {
+					return nil, ErrInvalidPath
+				}
+				if path[i] == '`' {
+					if i+1 < len(path) && path[i+1] == '`' {
+						field.WriteByte('`')
+						i++
+						continue
+					}
+					break
+				}
+				field.WriteByte(path[i])
+			}
+		case '.':
+			// a separator must follow a field or an index
+			if !pending && (i == 0 || path[i-1] != ']') {
+				return nil, ErrInvalidPath
+			}
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			if i == len(path)-1 {
+				return nil, ErrInvalidPath
+			}
+		case '[':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, ErrInvalidPath
+			}
+			index, err := strconv.Atoi(path[i+1 : i+end])
+			if err != nil {
+				return nil, ErrInvalidPath
+			}
+			segs = append(segs, pathSegment{index: index, isIndex: true})
+			i += end
+			// an index must be followed by the end of the path, a separator or another index
+			if next := i + 1; next < len(path) && path[next] != '.' && path[next] != '[' {
+				return nil, ErrInvalidPath
+			}
+		default:
+			pending = true
+			field.WriteByte(c)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return segs, nil
+}
+
+// parsePointer parses a JSON pointer (RFC 6901) such as "/address/city" or "/tags/0".
+// The empty pointer refers to the whole document.
+func parsePointer(ptr string) ([]pathSegment, error) {
+	if ptr == "" {
+		return nil, nil
+	}
+	if ptr[0] != '/' {
+		return nil, ErrInvalidPath
+	}
+
+	var segs []pathSegment
+	for _, tok := range strings.Split(ptr[1:], "/") {
+		tok, ok := unescapePointer(tok)
+		if !ok {
+			return nil, ErrInvalidPath
+		}
+
+		seg := pathSegment{field: tok}
+		// array indexes are canonical non-negative integers
+		if index, err := strconv.Atoi(tok); err == nil && index >= 0 && strconv.Itoa(index) == tok {
+			seg.index, seg.isIndex = index, true
+		}
+		segs = append(segs, seg)
+	}
+	return segs, nil
+}
+
+// unescapePointer decodes the ~0 and ~1 escapes of a JSON pointer token
+func unescapePointer(tok string) (string, bool) {
+	if !strings.Contains(tok, "~") {
+		return tok, true
+	}
+	var b strings.Builder
+	for i := 0; i < len(tok); i++ {
+		if tok[i] != '~' {
+			b.WriteByte(tok[i])
+			continue
+		}
+		if i+1 == len(tok) || (tok[i+1] != '0' && tok[i+1] != '1') {
+			return "", false
+		}
+		if tok[i+1] == '0' {
+			b.WriteByte('~')
+		} else {
+			b.WriteByte('/')
+		}
+		i++
+	}
+	return b.String(), true
+}
+
+// parseAnyPath parses a JSON pointer when path starts with a slash and a sub-document path otherwise
+func parseAnyPath(path string) ([]pathSegment, error) {
+	if strings.HasPrefix(path, "/") {
+		return parsePointer(path)
+	}
+	return parsePath(path)
+}
+
+// decodeJSON decodes a document value into generic JSON values, keeping numbers as json.Number
+// so they survive re-encoding unchanged
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// resolveIndex converts a possibly negative index into an offset into an array of length n
+func resolveIndex(index, n int) (int, bool) {
+	if index < 0 {
+		index += n
+	}
+	return index, index >= 0 && index < n
+}
+
+// getPath returns the value found at segs within root
+func getPath(root interface{}, segs []pathSegment) (interface{}, bool) {
+	cur := root
+	for _, seg := range segs {
+		if seg.isIndex {
+			arr, ok := cur.([]interface{})
+			if !ok {
+				// JSON pointer tokens can name object members as well as indexes
+				if obj, isObj := cur.(map[string]interface{}); isObj && seg.field != "" {
+					if cur, ok = obj[seg.field]; ok {
+						continue
+					}
+				}
+				return nil, false
+			}
+			i, ok := resolveIndex(seg.index, len(arr))
+			if !ok {
+				return nil, false
+			}
+			cur = arr[i]
+			continue
+		}
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[seg.field]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// projectPath copies value into dst at segs, creating the objects and arrays leading to it.
+// Array indexes are projected as appended elements, as Couchbase projections do.
+func projectPath(dst interface{}, segs []pathSegment, value interface{}) interface{} {
+	if len(segs) == 0 {
+		return value
+	}
+
+	seg := segs[0]
+	if seg.isIndex {
+		arr, _ := dst.([]interface{})
+		return append(arr, projectPath(nil, segs[1:], value))
+	}
+
+	obj, ok := dst.(map[string]interface{})
+	if !ok {
+		obj = make(map[string]interface{})
+	}
+	obj[seg.field] = projectPath(obj[seg.field], segs[1:], value)
+	return obj
+}
+
+// jsonEqual reports whether two decoded JSON values are equal, comparing numbers by value
+func jsonEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		ra, okA := new(big.Rat).SetString(string(a))
+		rb, okB := new(big.Rat).SetString(string(b))
+		return okA && okB && ra.Cmp(rb) == 0
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, va := range a {
+			vb, ok := b[k]
+			if !ok || !jsonEqual(va, vb) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// jsonRank orders the JSON types for jsonCompare: null, booleans, numbers, strings, arrays then objects
+func jsonRank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case json.Number:
+		return 2
+	case string:
+		return 3
+	case []interface{}:
+		return 4
+	}
+	return 5
+}
+
+// jsonCompare orders two decoded JSON values, returning a negative number, zero or a positive number when a
+// sorts before, equal to or after b. Values of different types are ordered by type, numbers by value, strings
+// bytewise, arrays element by element and objects by their canonical encoding.
+func jsonCompare(a, b interface{}) int {
+	if ra, rb := jsonRank(a), jsonRank(b); ra != rb {
+		return ra - rb
+	}
+	switch a := a.(type) {
+	case bool:
+		switch b := b.(bool); {
+		case a == b:
+			return 0
+		case !a:
+			return -1
+		}
+		return 1
+	case json.Number:
+		ra, okA := new(big.Rat).SetString(string(a))
+		rb, okB := new(big.Rat).SetString(string(b.(json.Number)))
+		if !okA || !okB {
+			return strings.Compare(string(a), string(b.(json.Number)))
+		}
+		return ra.Cmp(rb)
+	case string:
+		return strings.Compare(a, b.(string))
+	case []interface{}:
+		b := b.([]interface{})
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if c := jsonCompare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+		return len(a) - len(b)
+	case map[string]interface{}:
+		return strings.Compare(canonicalJSON(a), canonicalJSON(b))
+	}
+	return 0
+}
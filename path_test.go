@@ -0,0 +1,93 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	cases := map[string][]pathSegment{
+		"name":           {{field: "name"}},
+		"address.city":   {{field: "address"}, {field: "city"}},
+		"tags[0]":        {{field: "tags"}, {index: 0, isIndex: true}},
+		"items[-1].name": {{field: "items"}, {index: -1, isIndex: true}, {field: "name"}},
+		"grid[1][2]":     {{field: "grid"}, {index: 1, isIndex: true}, {index: 2, isIndex: true}},
+		"`a.b`.c":        {{field: "a.b"}, {field: "c"}},
+		"`tick``ed`":     {{field: "tick`ed"}},
+		"[0]":            {{index: 0, isIndex: true}},
+	}
+	for path, exp := range cases {
+		segs, err := parsePath(path)
+		if err != nil {
+			t.Fatalf("%q: %v", path, err)
+		}
+		if !reflect.DeepEqual(segs, exp) {
+			t.Fatalf("%q: unexpected segments %+v", path, segs)
+		}
+	}
+
+	for _, path := range []string{"", ".a", "a.", "a..b", "a[", "a[x]", "a[0]b", "`open"} {
+		if _, err := parsePath(path); !reflect.DeepEqual(err, ErrInvalidPath) {
+			t.Fatalf("%q: error mismatch %v", path, err)
+		}
+	}
+}
+
+func TestGetPath(t *testing.T) {
+	root, _ := decodeJSON([]byte(`{"a":{"b":[1,{"c":"x"}]}}`))
+
+	value, ok := getPath(root, []pathSegment{{field: "a"}, {field: "b"}, {index: -1, isIndex: true}, {field: "c"}})
+	if !ok || value != "x" {
+		t.Fatalf("unexpected value %v", value)
+	}
+
+	if _, ok := getPath(root, []pathSegment{{field: "a"}, {field: "missing"}}); ok {
+		t.Fatal("found missing path")
+	}
+	if _, ok := getPath(root, []pathSegment{{field: "a"}, {index: 0, isIndex: true}}); ok {
+		t.Fatal("indexed an object")
+	}
+}
+
+func TestParsePointer(t *testing.T) {
+	segs, err := parsePointer("/a~1b/~0c/0/01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []pathSegment{
+		{field: "a/b"},
+		{field: "~c"},
+		{field: "0", index: 0, isIndex: true},
+		{field: "01"},
+	}
+	if !reflect.DeepEqual(segs, exp) {
+		t.Fatalf("segments mismatch %+v", segs)
+	}
+
+	for _, ptr := range []string{"a/b", "/a~", "/~2"} {
+		if _, err := parsePointer(ptr); !reflect.DeepEqual(err, ErrInvalidPath) {
+			t.Fatalf("%q: error mismatch", ptr)
+		}
+	}
+
+	// index tokens may name object members
+	root, _ := decodeJSON([]byte(`{"0":"zero"}`))
+	segs, _ = parsePointer("/0")
+	if v, ok := getPath(root, segs); !ok || v != "zero" {
+		t.Fatal("value mismatch")
+	}
+}
+
+func TestJSONCompare(t *testing.T) {
+	ordered := []string{`null`, `false`, `true`, `-1`, `2`, `10.5`, `"10"`, `"a"`, `[]`, `[1]`, `[1,2]`, `[2]`, `{}`}
+	for i := range ordered {
+		for j := range ordered {
+			a, _ := decodeJSON([]byte(ordered[i]))
+			b, _ := decodeJSON([]byte(ordered[j]))
+			c := jsonCompare(a, b)
+			if (i < j && c >= 0) || (i == j && c != 0) || (i > j && c <= 0) {
+				t.Fatalf("order mismatch for %s and %s", ordered[i], ordered[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Find returns, in key order, the live documents for which match returns true.
+// match is called without any lock held, so it may use the store.
+func (crud *CRUD) Find(match func(key string, value json.RawMessage) bool) ([]Document, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var found []Document
+	for _, doc := range crud.scan(nil) {
+		if match(doc.Key, doc.Value) {
+			found = append(found, doc)
+		}
+	}
+	return found, nil
+}
+
+// QueryField returns, in key order, the live JSON documents whose field at path equals value.
+// path is either a sub-document path such as "address.city" or "tags[0]", or a JSON pointer such as
+// "/address/city". value is compared in its JSON encoding, with numbers compared by value.
+func (crud *CRUD) QueryField(path string, value interface{}) ([]Document, error) {
+	segs, err := parseAnyPath(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	want, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return crud.Find(func(key string, value json.RawMessage) bool {
+		root, err := decodeJSON(value)
+		if err != nil {
+			return false
+		}
+		got, ok := getPath(root, segs)
+		return ok && jsonEqual(got, want)
+	})
+}
@@ -0,0 +1,87 @@
+package crud
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func docKeys(docs []Document) []string {
+	keys := []string{}
+	for _, doc := range docs {
+		keys = append(keys, doc.Key)
+	}
+	return keys
+}
+
+func TestFind(t *testing.T) {
+	client := New()
+	_, _ = client.Insert("alice", testUser{Name: "Alice", Age: 30}, 0)
+	_, _ = client.Insert("bob", testUser{Name: "Bob", Age: 17}, 0)
+	_, _ = client.Insert("carol", testUser{Name: "Carol", Age: 45}, 0)
+	_, _ = client.InsertRaw("blob", []byte{0xff}, 0)
+
+	adults, err := client.Find(func(key string, value json.RawMessage) bool {
+		var user testUser
+		return json.Unmarshal(value, &user) == nil && user.Age >= 18
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := docKeys(adults); !reflect.DeepEqual(keys, []string{"alice", "carol"}) {
+		t.Fatalf("keys mismatch %v", keys)
+	}
+
+	var user testUser
+	if err := adults[1].Content(&user); err != nil || user.Name != "Carol" {
+		t.Fatal("value mismatch")
+	}
+
+	// the predicate may use the store
+	found, _ := client.Find(func(key string, value json.RawMessage) bool {
+		ok, _, _ := client.Exists(key)
+		return ok && key == "bob"
+	})
+	if len(found) != 1 {
+		t.Fatal("result count mismatch")
+	}
+}
+
+func TestQueryField(t *testing.T) {
+	client := New()
+	_, _ = client.InsertRaw("alice", []byte(`{"name":"Alice","age":30,"address":{"city":"Sydney"},"tags":["admin"]}`), 0)
+	_, _ = client.InsertRaw("bob", []byte(`{"name":"Bob","age":30.0,"address":{"city":"Perth"},"tags":["user"]}`), 0)
+	_, _ = client.InsertRaw("carol", []byte(`{"name":"Carol","age":45,"address":{"city":"Sydney"},"a/b":1}`), 0)
+	_, _ = client.InsertRaw("blob", []byte{0xff}, 0)
+
+	cases := []struct {
+		path  string
+		value interface{}
+		exp   []string
+	}{
+		{"address.city", "Sydney", []string{"alice", "carol"}},
+		{"/address/city", "Perth", []string{"bob"}},
+		{"age", 30, []string{"alice", "bob"}},
+		{"tags[0]", "admin", []string{"alice"}},
+		{"/tags/0", "user", []string{"bob"}},
+		{"/a~1b", 1, []string{"carol"}},
+		{"address", map[string]string{"city": "Perth"}, []string{"bob"}},
+		{"missing", nil, []string{}},
+	}
+	for _, c := range cases {
+		docs, err := client.QueryField(c.path, c.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys := docKeys(docs); !reflect.DeepEqual(keys, c.exp) {
+			t.Fatalf("%s: keys mismatch %v", c.path, keys)
+		}
+	}
+
+	if _, err := client.QueryField("a..b", 1); !reflect.DeepEqual(err, ErrInvalidPath) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.QueryField("/a~2", 1); !reflect.DeepEqual(err, ErrInvalidPath) {
+		t.Fatal("error mismatch")
+	}
+}
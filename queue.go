@@ -0,0 +1,115 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrQueueFull defines the error value returned when the operation queue is at its high-watermark
+// and the queue is configured to fail fast
+var ErrQueueFull = errors.New("operation queue full")
+
+// QueuePolicy controls what happens to operations arriving while the queue is at its high-watermark
+type QueuePolicy int
+
+const (
+	// QueueBlock makes operations wait until the queue drains below the high-watermark
+	QueueBlock QueuePolicy = iota
+	// QueueFailFast makes operations fail immediately with ErrQueueFull
+	QueueFailFast
+)
+
+// WithQueue limits the number of pending operations across all handles of the store to highWatermark.
+// Operations over the limit block or fail according to policy. A highWatermark of zero means no limit.
+func WithQueue(highWatermark int, policy QueuePolicy) Option {
+	return func(crud *CRUD) {
+		crud.queue = newOpQueue(highWatermark, policy)
+	}
+}
+
+// opQueue simulates the server-side queue of pending operations
+type opQueue struct {
+	slots  chan struct{}
+	policy QueuePolicy
+	depth  int64
+
+	// mu serialises Pause and Resume, paused is loaded without it so that operations don't contend
+	mu     sync.Mutex
+	paused atomic.Pointer[chan struct{}]
+}
+
+func newOpQueue(highWatermark int, policy QueuePolicy) *opQueue {
+	q := &opQueue{policy: policy}
+	if highWatermark > 0 {
+		q.slots = make(chan struct{}, highWatermark)
+	}
+	return q
+}
+
+// enter adds an operation to the queue and waits for processing to be resumed if the queue is paused.
+// Waiting is abandoned with the context's error when ctx is done.
+// The returned function must be called when the operation completes.
+func (q *opQueue) enter(ctx context.Context) (func(), error) {
+	if q.slots != nil {
+		if q.policy == QueueFailFast {
+			select {
+			case q.slots <- struct{}{}:
+			default:
+				return nil, ErrQueueFull
+			}
+		} else {
+			select {
+			case q.slots <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+	atomic.AddInt64(&q.depth, 1)
+
+	if paused := q.paused.Load(); paused != nil {
+		select {
+		case <-*paused:
+		case <-ctx.Done():
+			q.leave()
+			return nil, ctx.Err()
+		}
+	}
+
+	return q.leave, nil
+}
+
+func (q *opQueue) leave() {
+	atomic.AddInt64(&q.depth, -1)
+	if q.slots != nil {
+		<-q.slots
+	}
+}
+
+// Pause stops the store from processing operations. Operations issued while paused stay pending in the queue,
+// which lets tests build up queue depth deterministically.
+func (crud *CRUD) Pause() {
+	crud.queue.mu.Lock()
+	defer crud.queue.mu.Unlock()
+	if crud.queue.paused.Load() == nil {
+		paused := make(chan struct{})
+		crud.queue.paused.Store(&paused)
+	}
+}
+
+// Resume processes the operations pending since Pause
+func (crud *CRUD) Resume() {
+	crud.queue.mu.Lock()
+	defer crud.queue.mu.Unlock()
+	if paused := crud.queue.paused.Load(); paused != nil {
+		close(*paused)
+		crud.queue.paused.Store(nil)
+	}
+}
+
+// QueueDepth returns the number of operations currently pending in the queue
+func (crud *CRUD) QueueDepth() int {
+	return int(atomic.LoadInt64(&crud.queue.depth))
+}
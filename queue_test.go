@@ -0,0 +1,76 @@
+package crud
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func waitForDepth(t *testing.T, client *CRUD, depth int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for client.QueueDepth() != depth {
+		if time.Now().After(deadline) {
+			t.Fatalf("queue depth %d, expected %d", client.QueueDepth(), depth)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestQueueFailFast(t *testing.T) {
+	client := New(WithQueue(2, QueueFailFast))
+	client.Pause()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var act string
+			_, _ = client.Get("key", &act)
+		}()
+	}
+	waitForDepth(t, client, 2)
+
+	_, err := client.Insert("key", "val", 0)
+	if !reflect.DeepEqual(err, ErrQueueFull) {
+		t.Fatal("error mismatch")
+	}
+
+	client.Resume()
+	wg.Wait()
+	waitForDepth(t, client, 0)
+
+	if _, err := client.Insert("key", "val", 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueueBlock(t *testing.T) {
+	client := New(WithQueue(1, QueueBlock))
+	client.Pause()
+
+	done := make(chan error, 2)
+	for _, key := range []string{"a", "b"} {
+		go func(key string) {
+			_, err := client.Insert(key, "val", 0)
+			done <- err
+		}(key)
+	}
+
+	// only one operation fits in the queue, the other waits to enter it
+	waitForDepth(t, client, 1)
+	select {
+	case <-done:
+		t.Fatal("operation completed while paused")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	client.Resume()
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package crud
+
+import "errors"
+
+var (
+	// ErrQuotaExceeded defines the error value returned when a write would store more documents than WithMaxKeys allows
+	ErrQuotaExceeded = errors.New("document quota exceeded")
+	// ErrValueTooLarge defines the error value returned when a write would store a value larger than the maximum value size
+	ErrValueTooLarge = errors.New("value too large")
+)
+
+// DefaultMaxValueSize is the maximum size in bytes of stored values unless WithMaxValueSize is given,
+// which matches Couchbase Server's 20MiB document size limit
+const DefaultMaxValueSize = 20 * 1024 * 1024
+
+// WithMaxKeys caps the number of documents the store holds at max, so that writes storing new documents
+// beyond it, such as Insert, Upsert of a missing key, counters and transactions, return ErrQuotaExceeded
+// while writes to existing documents keep working. Expired documents count until they are purged.
+// Load, RollbackTo and WithBackend restore every document regardless. A max of zero means no cap.
+func WithMaxKeys(max int) Option {
+	return func(crud *CRUD) {
+		crud.maxKeys = max
+	}
+}
+
+// reserveQuota returns ErrQuotaExceeded when storing n more documents would exceed the cap set by WithMaxKeys.
+// Otherwise the returned function must be called once the documents are stored.
+func (crud *CRUD) reserveQuota(n int) (func(), error) {
+	if crud.maxKeys == 0 || n <= 0 {
+		return func() {}, nil
+	}
+	crud.quotaMu.Lock()
+	if crud.stored.Load()+int64(n) > int64(crud.maxKeys) {
+		crud.quotaMu.Unlock()
+		return nil, ErrQuotaExceeded
+	}
+	return crud.quotaMu.Unlock, nil
+}
+
+// WithMaxValueSize caps the size in bytes of stored values at max, so that writes storing larger values,
+// after they are encoded, return ErrValueTooLarge. A max of zero means no cap. See DefaultMaxValueSize.
+func WithMaxValueSize(max int) Option {
+	return func(crud *CRUD) {
+		crud.maxValueSize = max
+	}
+}
+
+// checkSize returns ErrValueTooLarge when data exceeds the maximum value size
+func (crud *CRUD) checkSize(data []byte) error {
+	if crud.maxValueSize > 0 && len(data) > crud.maxValueSize {
+		return ErrValueTooLarge
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMaxKeys(t *testing.T) {
+	clock := newManualClock()
+	client := New(WithMaxKeys(2), WithClock(clock))
+	cas, _ := client.Insert("a", 1, 0)
+	_, _ = client.Insert("b", 1, 1)
+
+	if _, err := client.Insert("c", 1, 0); !reflect.DeepEqual(err, ErrQuotaExceeded) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.Upsert("c", 1, 0); !reflect.DeepEqual(err, ErrQuotaExceeded) {
+		t.Fatal("error mismatch")
+	}
+	if _, _, err := client.Increment("c", 1, 0, 0); !reflect.DeepEqual(err, ErrQuotaExceeded) {
+		t.Fatal("error mismatch")
+	}
+	txn := client.Begin()
+	_ = txn.Insert("c", 1, 0)
+	if err := txn.Commit(); !reflect.DeepEqual(err, ErrQuotaExceeded) {
+		t.Fatal("error mismatch")
+	}
+	// existing documents can still be written
+	if _, err := client.Upsert("a", 2, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// a transaction removing as many documents as it stores fits
+	txn = client.Begin()
+	_ = txn.Remove("a", cas+1)
+	_ = txn.Insert("c", 1, 0)
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// expired documents count until purged
+	clock.add(2 * time.Second)
+	if _, err := client.Insert("d", 1, 0); !reflect.DeepEqual(err, ErrQuotaExceeded) {
+		t.Fatal("error mismatch")
+	}
+	_, _ = client.PurgeExpired()
+	if _, err := client.Insert("d", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = client.Flush()
+	if _, err := client.Insert("e", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaxKeysConcurrent(t *testing.T) {
+	client := NewSharded(8, WithMaxKeys(50))
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _ = client.Insert(fmt.Sprint(i), i, 0)
+		}(i)
+	}
+	wg.Wait()
+	if client.Count() != 50 {
+		t.Fatal("count mismatch")
+	}
+}
+
+func TestMaxValueSize(t *testing.T) {
+	client := New(WithMaxValueSize(8))
+	cas, err := client.Insert("key", "short", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large := "too long for the limit"
+	if _, err := client.Insert("other", large, 0); !reflect.DeepEqual(err, ErrValueTooLarge) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.Upsert("key", large, 0); !reflect.DeepEqual(err, ErrValueTooLarge) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.Replace("key", large, cas, 0); !reflect.DeepEqual(err, ErrValueTooLarge) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.UpsertRaw("key", []byte(large), 0); !reflect.DeepEqual(err, ErrValueTooLarge) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.Append("key", []byte("1234")); !reflect.DeepEqual(err, ErrValueTooLarge) {
+		t.Fatal("error mismatch")
+	}
+	var act string
+	if _, err := client.Get("key", &act); err != nil || act != "short" {
+		t.Fatal("value mismatch")
+	}
+
+	if New().maxValueSize != DefaultMaxValueSize {
+		t.Fatal("default mismatch")
+	}
+	if _, err := New(WithMaxValueSize(0)).Insert("key", large, 0); err != nil {
+		t.Fatal(err)
+	}
+}
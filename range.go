@@ -0,0 +1,128 @@
+package crud
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/btree"
+)
+
+// WithKeyIndex maintains an ordered index of the stored keys so that Range doesn't have to sort
+// every key of the store on each call. It speeds up range scans over large fixtures at a small cost per write.
+func WithKeyIndex() Option {
+	return func(crud *CRUD) {
+		crud.index = newKeyIndex()
+	}
+}
+
+// keyIndex is an ordered set of the stored keys
+type keyIndex struct {
+	mu   sync.RWMutex
+	tree *btree.BTreeG[string]
+}
+
+func newKeyIndex() *keyIndex {
+	return &keyIndex{tree: btree.NewOrderedG[string](32)}
+}
+
+func (ix *keyIndex) add(key string) {
+	ix.mu.Lock()
+	ix.tree.ReplaceOrInsert(key)
+	ix.mu.Unlock()
+}
+
+func (ix *keyIndex) remove(key string) {
+	ix.mu.Lock()
+	ix.tree.Delete(key)
+	ix.mu.Unlock()
+}
+
+func (ix *keyIndex) clear() {
+	ix.mu.Lock()
+	ix.tree.Clear(false)
+	ix.mu.Unlock()
+}
+
+// next returns up to n keys from start (inclusive) to end (exclusive, unbounded when empty), in order
+func (ix *keyIndex) next(start, end string, n int) []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	var keys []string
+	ix.tree.AscendGreaterOrEqual(start, func(key string) bool {
+		if end != "" && key >= end {
+			return false
+		}
+		keys = append(keys, key)
+		return len(keys) < n
+	})
+	return keys
+}
+
+// rangeBatch is the number of keys read from the key index at a time
+const rangeBatch = 256
+
+// Range returns, in key order, up to limit live documents with keys from startKey (inclusive)
+// to endKey (exclusive). An empty endKey leaves the range unbounded and a limit of zero or less returns
+// every matching document. Range is backed by the ordered index enabled by WithKeyIndex when present.
+func (crud *CRUD) Range(startKey, endKey string, limit int) ([]Document, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return crud.scanRange(startKey, endKey, limit), nil
+}
+
+// scanRange returns the documents of Range
+func (crud *CRUD) scanRange(startKey, endKey string, limit int) []Document {
+	if crud.index == nil {
+		docs := crud.scan(func(key string) bool {
+			return key >= startKey && (endKey == "" || key < endKey)
+		})
+		if limit > 0 && len(docs) > limit {
+			docs = docs[:limit]
+		}
+		return docs
+	}
+
+	var docs []Document
+	from := startKey
+	for {
+		// the index lock is released before the shards are locked, as writers take them the other way around
+		keys := crud.index.next(from, endKey, rangeBatch)
+		for _, key := range keys {
+			if doc, ok := crud.indexed(key); ok {
+				docs = append(docs, doc)
+				if limit > 0 && len(docs) == limit {
+					return docs
+				}
+			}
+		}
+		if len(keys) < rangeBatch {
+			return docs
+		}
+		// continue after the last key of the batch
+		from = keys[len(keys)-1] + "\x00"
+	}
+}
+
+// indexed returns a copy of the live document stored under key as a Document
+func (crud *CRUD) indexed(key string) (Document, bool) {
+	s := crud.shardFor(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := crud.getTime()
+	doc, ok := s.docs[key]
+	if !ok || doc.expired(now) {
+		return Document{}, false
+	}
+	return Document{
+		Key:        key,
+		Cas:        doc.reportedCas(now),
+		Value:      append([]byte(nil), doc.value()...),
+		transcoder: crud.transcoder,
+	}, true
+}
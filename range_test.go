@@ -0,0 +1,73 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func rangeKeys(t *testing.T, client *CRUD, start, end string, limit int) []string {
+	t.Helper()
+	docs, err := client.Range(start, end, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{}
+	for _, doc := range docs {
+		keys = append(keys, doc.Key)
+	}
+	return keys
+}
+
+func TestRange(t *testing.T) {
+	for name, client := range map[string]*CRUD{
+		"scan":  NewSharded(4),
+		"index": NewSharded(4, WithKeyIndex()),
+	} {
+		for _, key := range []string{"d", "a", "c", "e", "b"} {
+			_, _ = client.Insert(key, key, 0)
+		}
+		expireKey(client, "c")
+
+		if keys := rangeKeys(t, client, "b", "e", 0); !reflect.DeepEqual(keys, []string{"b", "d"}) {
+			t.Fatalf("%s: keys mismatch %v", name, keys)
+		}
+		if keys := rangeKeys(t, client, "", "", 3); !reflect.DeepEqual(keys, []string{"a", "b", "d"}) {
+			t.Fatalf("%s: keys mismatch %v", name, keys)
+		}
+		if keys := rangeKeys(t, client, "f", "", 0); len(keys) != 0 {
+			t.Fatalf("%s: keys mismatch %v", name, keys)
+		}
+
+		_, _ = client.Remove("b", 1)
+		if keys := rangeKeys(t, client, "a", "", 2); !reflect.DeepEqual(keys, []string{"a", "d"}) {
+			t.Fatalf("%s: keys mismatch %v", name, keys)
+		}
+	}
+}
+
+func TestRangeIndexBatches(t *testing.T) {
+	client := New(WithKeyIndex())
+	for i := 0; i < rangeBatch*3; i++ {
+		_, _ = client.Insert(fmt.Sprintf("key-%04d", i), i, 0)
+	}
+	// expired documents at the end of a batch are skipped without ending the scan
+	for i := rangeBatch - 10; i < rangeBatch; i++ {
+		expireKey(client, fmt.Sprintf("key-%04d", i))
+	}
+
+	keys := rangeKeys(t, client, "", "", rangeBatch)
+	if len(keys) != rangeBatch || keys[len(keys)-1] != fmt.Sprintf("key-%04d", rangeBatch+9) {
+		t.Fatalf("batch continuation mismatch, %d keys", len(keys))
+	}
+	if keys := rangeKeys(t, client, "", "", 0); len(keys) != rangeBatch*3-10 {
+		t.Fatalf("key count mismatch %d", len(keys))
+	}
+
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if keys := rangeKeys(t, client, "", "", 0); len(keys) != 0 {
+		t.Fatal("index survived the flush")
+	}
+}
@@ -0,0 +1,109 @@
+package crud
+
+import "context"
+
+// rawValue marks a value which is stored as is instead of being encoded by the transcoder
+type rawValue []byte
+
+// encode returns the bytes to store for value, or ErrValueTooLarge when they exceed the maximum value size
+func (crud *CRUD) encode(value interface{}) ([]byte, error) {
+	if raw, ok := value.(rawValue); ok {
+		if err := crud.checkSize(raw); err != nil {
+			return nil, err
+		}
+		// copy so the caller can't modify the stored document
+		return append([]byte(nil), raw...), nil
+	}
+	data, err := crud.transcoder.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return data, crud.checkSize(data)
+}
+
+// GetRaw provides the Get Database Operation, returning the stored bytes without decoding them
+func (crud *CRUD) GetRaw(key string) ([]byte, uint64, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return nil, 0, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpGet); err != nil {
+		return nil, 0, err
+	}
+
+	doc, err := crud.fetch(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	crud.recordRead(key, doc.size(), doc.Cas)
+
+	return append([]byte(nil), doc.value()...), doc.reportedCas(crud.getTime()), nil
+}
+
+// InsertRaw provides the Insert Database Operation storing value as is, without encoding it.
+// Values which aren't valid JSON are stored as DatatypeBinary.
+func (crud *CRUD) InsertRaw(key string, value []byte, expiry uint32) (uint64, error) {
+	return crud.insertWith(context.Background(), key, rawValue(value), WithExpiry(expiry))
+}
+
+// UpsertRaw provides the Upsert Database Operation storing value as is, without encoding it.
+// Values which aren't valid JSON are stored as DatatypeBinary.
+func (crud *CRUD) UpsertRaw(key string, value []byte, expiry uint32) (uint64, error) {
+	res, err := crud.upsertWith(context.Background(), key, rawValue(value), WithExpiry(expiry))
+	return res.Cas, err
+}
+
+// Append adds data to the end of the value stored under key and returns the new CAS.
+// The document's expiry is unchanged.
+func (crud *CRUD) Append(key string, data []byte) (uint64, error) {
+	return crud.concat(key, func(value []byte) []byte {
+		return append(append(make([]byte, 0, len(value)+len(data)), value...), data...)
+	})
+}
+
+// Prepend adds data to the start of the value stored under key and returns the new CAS.
+// The document's expiry is unchanged.
+func (crud *CRUD) Prepend(key string, data []byte) (uint64, error) {
+	return crud.concat(key, func(value []byte) []byte {
+		return append(append(make([]byte, 0, len(value)+len(data)), data...), value...)
+	})
+}
+
+// concat replaces the value stored under key with join applied to it
+func (crud *CRUD) concat(key string, join func(value []byte) []byte) (uint64, error) {
+	release, err := crud.admitWrite(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpAppend); err != nil {
+		return 0, err
+	}
+
+	s := crud.shardFor(key)
+	s.lock()
+	defer s.mu.Unlock()
+
+	doc, err := crud.lookup(key)
+	if err != nil {
+		return 0, err
+	}
+	if doc.locked(crud.getTime()) {
+		return 0, ErrDocumentLocked
+	}
+
+	data := join(doc.value())
+	if err := crud.checkSize(data); err != nil {
+		return 0, err
+	}
+	unreserve, err := crud.reserveUnique(map[string][]byte{key: data})
+	if err != nil {
+		return 0, err
+	}
+	defer unreserve()
+
+	doc = doc.set(data)
+	crud.put(key, doc, EventUpdate)
+	return doc.Cas, nil
+}
@@ -0,0 +1,94 @@
+package crud
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestRaw(t *testing.T) {
+	client := New()
+	payload := []byte{0xde, 0xad, 0xbe, 0xef}
+
+	cas, err := client.InsertRaw("bin", payload, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.InsertRaw("bin", payload, 0); !reflect.DeepEqual(err, ErrKeyExist) {
+		t.Fatal("error mismatch")
+	}
+
+	// the store keeps its own copy of the payload
+	payload[0] = 0
+	act, actCas, err := client.GetRaw("bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actCas != cas {
+		t.Fatal("cas mismatch")
+	}
+	if !bytes.Equal(act, []byte{0xde, 0xad, 0xbe, 0xef}) {
+		t.Fatal("value mismatch")
+	}
+	if stored(client, "bin").Datatype != DatatypeBinary {
+		t.Fatal("datatype mismatch")
+	}
+
+	act[0] = 0
+	if act, _, _ = client.GetRaw("bin"); act[0] != 0xde {
+		t.Fatal("returned value aliases the stored document")
+	}
+
+	if _, _, err := client.GetRaw("missing"); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestUpsertRawJSON(t *testing.T) {
+	client := New()
+
+	// pre-encoded JSON is stored without being encoded again
+	if _, err := client.UpsertRaw("key", []byte(`{"name":"alice"}`), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var act struct{ Name string }
+	if _, err := client.Get("key", &act); err != nil || act.Name != "alice" {
+		t.Fatal("value mismatch")
+	}
+	if raw, _, _ := client.GetRaw("key"); string(raw) != `{"name":"alice"}` {
+		t.Fatal("raw value mismatch")
+	}
+}
+
+func TestAppendPrepend(t *testing.T) {
+	client := New()
+
+	if _, err := client.Append("key", []byte("x")); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+
+	_, _ = client.InsertRaw("key", []byte("mid"), 0)
+	if _, err := client.Append("key", []byte("-end")); err != nil {
+		t.Fatal(err)
+	}
+	cas, err := client.Prepend("key", []byte("start-"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cas != 3 {
+		t.Fatal("cas mismatch")
+	}
+
+	act, _, _ := client.GetRaw("key")
+	if string(act) != "start-mid-end" {
+		t.Fatalf("value mismatch %q", act)
+	}
+
+	_, _ = client.InsertRaw("json", []byte(`"val"`), 0)
+	var val string
+	_, _ = client.GetAndLock("json", 10, &val)
+	if _, err := client.Append("json", []byte("!")); !reflect.DeepEqual(err, ErrDocumentLocked) {
+		t.Fatal("error mismatch")
+	}
+}
@@ -0,0 +1,168 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrNotModified defines the error value returned by GetIfChanged when the document still has the known CAS
+var ErrNotModified = errors.New("document not modified")
+
+// GetIfChanged provides a conditional Get Database Operation. When the document's CAS still equals
+// knownCas it returns ErrNotModified without decoding the value, otherwise it behaves like Get.
+func (crud *CRUD) GetIfChanged(key string, knownCas uint64, valuePtr interface{}) (uint64, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpGet); err != nil {
+		return 0, err
+	}
+
+	doc, err := crud.fetch(key)
+	if err != nil {
+		return 0, err
+	}
+
+	cas := doc.reportedCas(crud.getTime())
+	if cas == knownCas {
+		return cas, ErrNotModified
+	}
+
+	if err := crud.transcoder.Unmarshal(doc.value(), valuePtr); err != nil {
+		return 0, err
+	}
+	crud.recordRead(key, doc.size(), doc.Cas)
+
+	return cas, nil
+}
+
+// Exists reports whether a live document is stored under key, along with its CAS, without decoding the value.
+// A missing or expired document is reported as false with a nil error.
+func (crud *CRUD) Exists(key string) (bool, uint64, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return false, 0, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpGet); err != nil {
+		return false, 0, err
+	}
+
+	doc, err := crud.fetch(key)
+	if err == ErrKeyNotExist {
+		return false, 0, nil
+	}
+	if err != nil {
+		return false, 0, err
+	}
+	return true, doc.reportedCas(crud.getTime()), nil
+}
+
+// GetAndTouch provides the Get Database Operation while updating the document's expiry in the same step.
+// Like Touch, it changes the document's CAS value.
+func (crud *CRUD) GetAndTouch(key string, expiry uint32, valuePtr interface{}) (uint64, error) {
+	return crud.GetAndTouchWith(key, valuePtr, WithExpiry(expiry))
+}
+
+// GetAndTouchWith is GetAndTouch configured by WriteOptions, such as WithRelativeExpiry
+func (crud *CRUD) GetAndTouchWith(key string, valuePtr interface{}, opts ...WriteOption) (uint64, error) {
+	release, err := crud.admitWrite(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpGetAndTouch); err != nil {
+		return 0, err
+	}
+
+	s := crud.shardFor(key)
+	s.lock()
+	defer s.mu.Unlock()
+
+	doc, err := crud.lookup(key)
+	if err != nil {
+		return 0, err
+	}
+	if doc.locked(crud.getTime()) {
+		return 0, ErrDocumentLocked
+	}
+
+	o := newWriteOptions(opts)
+	if err := o.precondition(doc); err != nil {
+		return 0, err
+	}
+	ttl, err := crud.resolveExpiry(o.expiry)
+	if err != nil {
+		return 0, err
+	}
+	if err := crud.transcoder.Unmarshal(doc.value(), valuePtr); err != nil {
+		return 0, err
+	}
+	crud.recordRead(key, doc.size(), doc.Cas)
+
+	doc.TTL = ttl
+	crud.put(key, doc, EventUpdate)
+
+	return doc.Cas, nil
+}
+
+// GetWithProjection provides a Get Database Operation decoding only the fields at the given paths into valuePtr,
+// mirroring the Project option of gocb. The projected fields keep their position in the document structure;
+// paths which don't exist are skipped.
+func (crud *CRUD) GetWithProjection(key string, valuePtr interface{}, paths ...string) (uint64, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpGet); err != nil {
+		return 0, err
+	}
+
+	parsed := make([][]pathSegment, len(paths))
+	for i, path := range paths {
+		if parsed[i], err = parsePath(path); err != nil {
+			return 0, err
+		}
+	}
+
+	doc, err := crud.fetch(key)
+	if err != nil {
+		return 0, err
+	}
+	if doc.Datatype != DatatypeJSON {
+		return 0, ErrNotJSON
+	}
+
+	root, err := decodeJSON(doc.value())
+	if err != nil {
+		return 0, err
+	}
+
+	var projected interface{} = map[string]interface{}{}
+	for _, segs := range parsed {
+		if value, ok := getPath(root, segs); ok {
+			projected = projectPath(projected, segs, value)
+		}
+	}
+
+	data, err := json.Marshal(projected)
+	if err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(data, valuePtr); err != nil {
+		return 0, err
+	}
+	crud.recordRead(key, doc.size(), doc.Cas)
+
+	return doc.reportedCas(crud.getTime()), nil
+}
+
+// GetProject provides a projected Get Database Operation decoding only the fields at paths into valuePtr.
+// It is GetWithProjection with the arguments in the order of the sub-document API.
+func (crud *CRUD) GetProject(key string, paths []string, valuePtr interface{}) (uint64, error) {
+	return crud.GetWithProjection(key, valuePtr, paths...)
+}
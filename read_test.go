@@ -0,0 +1,173 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetIfChanged(t *testing.T) {
+	client := New()
+	cas, _ := client.Insert("key", "val", 0)
+
+	// the destination is left untouched when nothing changed
+	act := "untouched"
+	cas2, err := client.GetIfChanged("key", cas, &act)
+	if !reflect.DeepEqual(err, ErrNotModified) {
+		t.Fatal("error mismatch")
+	}
+	if cas2 != cas || act != "untouched" {
+		t.Fatal("results mismatch")
+	}
+
+	cas3, _ := client.Upsert("key", "val2", 0)
+	cas4, err := client.GetIfChanged("key", cas, &act)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cas4 != cas3 || act != "val2" {
+		t.Fatal("results mismatch")
+	}
+
+	_, err = client.GetIfChanged("missing", cas, &act)
+	if !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestGetWithProjection(t *testing.T) {
+	type address struct {
+		City    string `json:"city"`
+		Country string `json:"country"`
+	}
+	type user struct {
+		Name    string   `json:"name"`
+		Email   string   `json:"email"`
+		Address address  `json:"address"`
+		Tags    []string `json:"tags"`
+	}
+
+	client := New()
+	cas, _ := client.Insert("key", user{
+		Name:    "jo",
+		Email:   "jo@example.com",
+		Address: address{City: "Sydney", Country: "AU"},
+		Tags:    []string{"a", "b"},
+	}, 0)
+
+	var act user
+	cas2, err := client.GetWithProjection("key", &act, "name", "address.city", "tags[1]", "missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cas2 != cas {
+		t.Fatal("cas mismatch")
+	}
+
+	exp := user{Name: "jo", Address: address{City: "Sydney"}, Tags: []string{"b"}}
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatalf("unexpected projection %+v", act)
+	}
+
+	_, err = client.GetWithProjection("key", &act, "bad..path")
+	if !reflect.DeepEqual(err, ErrInvalidPath) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestExists(t *testing.T) {
+	client := New()
+
+	ok, cas, err := client.Exists("key")
+	if err != nil || ok || cas != 0 {
+		t.Fatal("missing document reported as existing")
+	}
+
+	// values which can't be decoded into a particular type still exist
+	_, _ = client.InsertRaw("key", []byte{0xff, 0x00}, 0)
+	ok, cas, err = client.Exists("key")
+	if err != nil || !ok || cas != 1 {
+		t.Fatal("existing document reported as missing")
+	}
+
+	expireKey(client, "key")
+	if ok, _, _ := client.Reader().Exists("key"); ok {
+		t.Fatal("expired document reported as existing")
+	}
+}
+
+func TestGetAndTouch(t *testing.T) {
+	client := New()
+
+	var act string
+	if _, err := client.GetAndTouch("key", 10, &act); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+
+	_, _ = client.Insert("key", "val", 1)
+	cas, err := client.GetAndTouch("key", 100, &act)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act != "val" {
+		t.Fatal("value mismatch")
+	}
+	if cas != 2 {
+		t.Fatal("cas mismatch")
+	}
+	if ttl := expiresIn(client, "key"); ttl < 99*time.Second || ttl > 100*time.Second {
+		t.Fatalf("expiry mismatch %s", ttl)
+	}
+
+	_, _ = client.GetAndLock("key", 10, &act)
+	if _, err := client.GetAndTouch("key", 100, &act); !reflect.DeepEqual(err, ErrDocumentLocked) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestGetAndTouchWith(t *testing.T) {
+	client := New()
+	_, _ = client.Insert("key", "val", 0)
+
+	// 45 days is beyond the relative threshold of the uint32 convention
+	var act string
+	if _, err := client.GetAndTouchWith("key", &act, WithRelativeExpiry(45*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if expiresIn(client, "key") < 45*24*time.Hour-time.Second {
+		t.Fatal("expiry mismatch")
+	}
+
+	at := client.now().Add(time.Minute)
+	if _, err := client.GetAndTouchWith("key", &act, WithAbsoluteExpiry(at)); err != nil {
+		t.Fatal(err)
+	}
+	if stored(client, "key").TTL != at.UnixNano() {
+		t.Fatal("expiry mismatch")
+	}
+
+	if _, err := client.GetAndTouchWith("key", &act, WithRelativeExpiry(-time.Second)); !reflect.DeepEqual(err, ErrInvalidExpiry) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestGetProject(t *testing.T) {
+	client := New()
+	_, _ = client.InsertRaw("key", []byte(`{"name":"jo","email":"jo@example.com","address":{"city":"Sydney","country":"AU"}}`), 0)
+
+	var act map[string]interface{}
+	if _, err := client.GetProject("key", []string{"name", "address.country"}, &act); err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]interface{}{
+		"name":    "jo",
+		"address": map[string]interface{}{"country": "AU"},
+	}
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatalf("projection mismatch %v", act)
+	}
+
+	if _, err := client.GetProject("key", []string{"a..b"}, &act); !reflect.DeepEqual(err, ErrInvalidPath) {
+		t.Fatal("error mismatch")
+	}
+}
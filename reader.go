@@ -0,0 +1,175 @@
+package crud
+
+import (
+	"encoding/json"
+	"iter"
+	"regexp"
+	"time"
+)
+
+// Reader is a read-only handle to a store. Components which must never write can be given a Reader
+// so that the restriction is enforced at compile time. Reads through a Reader never modify the store,
+// expired documents are reported as missing but left for a writer to purge.
+type Reader struct {
+	crud *CRUD
+}
+
+// Reader returns a read-only handle sharing the documents and client options of crud
+func (crud *CRUD) Reader() *Reader {
+	return &Reader{crud: &CRUD{db: crud.db, limiter: crud.limiter, reader: true, label: crud.label}}
+}
+
+// Get provides the Get Database Operation, see CRUD.Get
+func (r *Reader) Get(key string, valuePtr interface{}) (uint64, error) {
+	return r.crud.Get(key, valuePtr)
+}
+
+// GetWithMeta provides the Get Database Operation along with the document's metadata, see CRUD.GetWithMeta
+func (r *Reader) GetWithMeta(key string, valuePtr interface{}) (Meta, error) {
+	return r.crud.GetWithMeta(key, valuePtr)
+}
+
+// GetIfChanged provides a conditional Get Database Operation, see CRUD.GetIfChanged
+func (r *Reader) GetIfChanged(key string, knownCas uint64, valuePtr interface{}) (uint64, error) {
+	return r.crud.GetIfChanged(key, knownCas, valuePtr)
+}
+
+// GetWithProjection provides a projected Get Database Operation, see CRUD.GetWithProjection
+func (r *Reader) GetWithProjection(key string, valuePtr interface{}, paths ...string) (uint64, error) {
+	return r.crud.GetWithProjection(key, valuePtr, paths...)
+}
+
+// GetRaw provides the Get Database Operation returning the stored bytes, see CRUD.GetRaw
+func (r *Reader) GetRaw(key string) ([]byte, uint64, error) {
+	return r.crud.GetRaw(key)
+}
+
+// Exists reports whether a live document is stored under key, see CRUD.Exists
+func (r *Reader) Exists(key string) (bool, uint64, error) {
+	return r.crud.Exists(key)
+}
+
+// GetMeta returns the metadata of the document stored under key, see CRUD.GetMeta
+func (r *Reader) GetMeta(key string) (Meta, error) {
+	return r.crud.GetMeta(key)
+}
+
+// GetWithExpiry provides the Get Database Operation along with the document's expiry, see CRUD.GetWithExpiry
+func (r *Reader) GetWithExpiry(key string, valuePtr interface{}) (uint64, time.Time, error) {
+	return r.crud.GetWithExpiry(key, valuePtr)
+}
+
+// Keys returns the keys of every live document in the store, see CRUD.Keys
+func (r *Reader) Keys() []string {
+	return r.crud.Keys()
+}
+
+// KeysWithPrefix returns the keys of live documents starting with prefix, see CRUD.KeysWithPrefix
+func (r *Reader) KeysWithPrefix(prefix string) []string {
+	return r.crud.KeysWithPrefix(prefix)
+}
+
+// Count returns the number of live documents in the store, see CRUD.Count
+func (r *Reader) Count() int {
+	return r.crud.Count()
+}
+
+// All returns an iterator over every live document, see CRUD.All
+func (r *Reader) All() iter.Seq2[string, json.RawMessage] {
+	return r.crud.All()
+}
+
+// ForEach calls fn for every live document, see CRUD.ForEach
+func (r *Reader) ForEach(fn func(key string, value json.RawMessage) error) error {
+	return r.crud.ForEach(fn)
+}
+
+// ScanPrefix returns the live documents whose key starts with prefix, see CRUD.ScanPrefix
+func (r *Reader) ScanPrefix(prefix string) ([]Document, error) {
+	return r.crud.ScanPrefix(prefix)
+}
+
+// Range returns the live documents with keys in the given range, see CRUD.Range
+func (r *Reader) Range(startKey, endKey string, limit int) ([]Document, error) {
+	return r.crud.Range(startKey, endKey, limit)
+}
+
+// RangePage returns a page of the documents with keys in the given range, see CRUD.RangePage
+func (r *Reader) RangePage(startKey, endKey string, pageSize int, cursor Cursor) (Page, error) {
+	return r.crud.RangePage(startKey, endKey, pageSize, cursor)
+}
+
+// ScanPrefixPage returns a page of the documents whose key starts with prefix, see CRUD.ScanPrefixPage
+func (r *Reader) ScanPrefixPage(prefix string, pageSize int, cursor Cursor) (Page, error) {
+	return r.crud.ScanPrefixPage(prefix, pageSize, cursor)
+}
+
+// Find returns the live documents matching a predicate, see CRUD.Find
+func (r *Reader) Find(match func(key string, value json.RawMessage) bool) ([]Document, error) {
+	return r.crud.Find(match)
+}
+
+// QueryField returns the live JSON documents whose field at path equals value, see CRUD.QueryField
+func (r *Reader) QueryField(path string, value interface{}) ([]Document, error) {
+	return r.crud.QueryField(path, value)
+}
+
+// GetProject provides a projected Get Database Operation, see CRUD.GetProject
+func (r *Reader) GetProject(key string, paths []string, valuePtr interface{}) (uint64, error) {
+	return r.crud.GetProject(key, paths, valuePtr)
+}
+
+// LookupIn reads several paths of a JSON document, see CRUD.LookupIn
+func (r *Reader) LookupIn(key string, specs ...LookupInSpec) (*LookupInResult, error) {
+	return r.crud.LookupIn(key, specs...)
+}
+
+// Query runs a N1QL-like statement, see CRUD.Query
+func (r *Reader) Query(statement string, params ...interface{}) (*QueryResult, error) {
+	return r.crud.Query(statement, params...)
+}
+
+// QueryView returns the rows of a view, see CRUD.QueryView
+func (r *Reader) QueryView(name string, opts ViewOptions) (*ViewResult, error) {
+	return r.crud.QueryView(name, opts)
+}
+
+// Aggregate computes aggregates of a field across documents, see CRUD.Aggregate
+func (r *Reader) Aggregate(path string, opts AggregateOptions) ([]AggregateResult, error) {
+	return r.crud.Aggregate(path, opts)
+}
+
+// Search runs a full-text query against a search index, see CRUD.Search
+func (r *Reader) Search(name string, query SearchQuery, limit int) ([]SearchHit, error) {
+	return r.crud.Search(name, query, limit)
+}
+
+// KeysMatching returns the keys of live documents matching a glob pattern, see CRUD.KeysMatching
+func (r *Reader) KeysMatching(pattern string) ([]string, error) {
+	return r.crud.KeysMatching(pattern)
+}
+
+// KeysMatchingRegexp returns the keys of live documents matched by re, see CRUD.KeysMatchingRegexp
+func (r *Reader) KeysMatchingRegexp(re *regexp.Regexp) []string {
+	return r.crud.KeysMatchingRegexp(re)
+}
+
+// ListRevisions returns the retained revisions of a document, see CRUD.ListRevisions
+func (r *Reader) ListRevisions(key string) ([]Revision, error) {
+	return r.crud.ListRevisions(key)
+}
+
+// GetRevision decodes a retained revision of a document, see CRUD.GetRevision
+func (r *Reader) GetRevision(key string, cas uint64, valuePtr interface{}) error {
+	return r.crud.GetRevision(key, cas, valuePtr)
+}
+
+// GetAsOf decodes the value a document had at a point in time, see CRUD.GetAsOf
+func (r *Reader) GetAsOf(key string, t time.Time, valuePtr interface{}) (uint64, error) {
+	return r.crud.GetAsOf(key, t, valuePtr)
+}
+
+// History returns the mutations applied to a document, see CRUD.History
+func (r *Reader) History(key string) ([]HistoryEntry, error) {
+	return r.crud.History(key)
+}
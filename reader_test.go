@@ -0,0 +1,51 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	client := New()
+	reader := client.Reader()
+	cas, _ := client.Insert("key", "val", 0)
+
+	var act string
+	cas2, err := reader.Get("key", &act)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cas2 != cas || act != "val" {
+		t.Fatal("results mismatch")
+	}
+
+	// writes through the store are visible to the reader
+	_, _ = client.Upsert("key", "val2", 0)
+	if _, err := reader.Get("key", &act); err != nil {
+		t.Fatal(err)
+	}
+	if act != "val2" {
+		t.Fatal("results mismatch")
+	}
+}
+
+func TestReaderLeavesExpiredDocuments(t *testing.T) {
+	client := New()
+	reader := client.Reader()
+	_, _ = client.Insert("key", "val", 0)
+	expireKey(client, "key")
+
+	var act string
+	_, err := reader.Get("key", &act)
+	if !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+	if keys := client.ExpiredKeys(); !reflect.DeepEqual(keys, []string{"key"}) {
+		t.Fatal("reader purged an expired document")
+	}
+
+	_, _ = client.Get("key", &act)
+	if keys := client.ExpiredKeys(); len(keys) != 0 {
+		t.Fatal("store did not purge the expired document")
+	}
+}
@@ -0,0 +1,19 @@
+package crud
+
+import "errors"
+
+// ErrReadOnly defines the error value returned when a mutation is attempted while the store is read-only
+var ErrReadOnly = errors.New("store is read-only")
+
+// SetReadOnly puts the store in or out of read-only mode, which every handle of the store shares.
+// While read-only, operations mutating documents, including locks, touches, transactions, Flush, RollbackTo
+// and Load, return ErrReadOnly and reads keep working. Documents still expire, though the reaper started by
+// StartReaper leaves them in place until the store is writable again.
+func (crud *CRUD) SetReadOnly(readOnly bool) {
+	crud.readOnly.Store(readOnly)
+}
+
+// ReadOnly reports whether the store is in read-only mode, see SetReadOnly
+func (crud *CRUD) ReadOnly() bool {
+	return crud.readOnly.Load()
+}
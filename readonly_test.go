@@ -0,0 +1,51 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	client := New()
+	cas, _ := client.Insert("key", "value", 0)
+	handle := client.Client()
+	client.SetReadOnly(true)
+	if !handle.ReadOnly() {
+		t.Fatal("mode mismatch")
+	}
+
+	var v string
+	errs := []error{}
+	_, err := handle.Insert("other", 1, 0)
+	errs = append(errs, err)
+	_, err = handle.Upsert("key", 1, 0)
+	errs = append(errs, err)
+	_, err = handle.Replace("key", 1, cas, 0)
+	errs = append(errs, err)
+	_, err = handle.Remove("key", cas)
+	errs = append(errs, err)
+	_, err = handle.Touch("key", cas, 10)
+	errs = append(errs, err)
+	_, err = handle.GetAndLock("key", 5, &v)
+	errs = append(errs, err)
+	_, _, err = handle.Increment("counter", 1, 0, 0)
+	errs = append(errs, err)
+	errs = append(errs, handle.Flush())
+	for i, err := range errs {
+		if !reflect.DeepEqual(err, ErrReadOnly) {
+			t.Fatalf("error %d mismatch", i)
+		}
+	}
+	if results := handle.UpsertMulti([]MultiDoc{{Key: "a", Value: 1}}); !reflect.DeepEqual(results[0].Err, ErrReadOnly) {
+		t.Fatal("error mismatch")
+	}
+
+	if _, err := handle.Get("key", &v); err != nil || v != "value" {
+		t.Fatal("value mismatch")
+	}
+
+	client.SetReadOnly(false)
+	if _, err := handle.Upsert("key", "updated", 0); err != nil {
+		t.Fatal(err)
+	}
+}
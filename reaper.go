@@ -0,0 +1,73 @@
+package crud
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultReaperInterval is the interval used by StartReaper when it is given none
+const defaultReaperInterval = time.Second
+
+// reaper periodically purges expired documents in the background
+type reaper struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// reaperState holds the reaper started by StartReaper, nil when none is running
+type reaperState struct {
+	mu     sync.Mutex
+	reaper *reaper
+}
+
+// StartReaper starts a background goroutine purging expired documents every interval, so that Keys, Count
+// and scans stop seeing them and their memory is reclaimed without waiting for them to be accessed.
+// Purges fire EventExpire events and OnExpire hooks. Starting a reaper replaces the one already running.
+// The reaper isn't subject to rate limits or the operation queue, and it skips purges while the store is
+// read-only. An interval of zero or less purges every second.
+func (crud *CRUD) StartReaper(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultReaperInterval
+	}
+	crud.reaping.mu.Lock()
+	defer crud.reaping.mu.Unlock()
+	crud.reaping.reaper.halt()
+
+	r := &reaper{stop: make(chan struct{}), done: make(chan struct{})}
+	crud.reaping.reaper = r
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if crud.readOnly.Load() {
+					continue
+				}
+				crud.purgeExpired()
+				crud.hooks.run()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopReaper stops the reaper started by StartReaper, waiting for a purge in progress to finish.
+// It does nothing when no reaper is running.
+func (crud *CRUD) StopReaper() {
+	crud.reaping.mu.Lock()
+	defer crud.reaping.mu.Unlock()
+	crud.reaping.reaper.halt()
+	crud.reaping.reaper = nil
+}
+
+// halt stops the reaper and waits for it to exit, a nil reaper is ignored
+func (r *reaper) halt() {
+	if r == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+}
@@ -0,0 +1,56 @@
+package crud
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReaper(t *testing.T) {
+	client := New()
+	client.Insert("a", "val", 0)
+	client.Insert("live", "val", 0)
+	expireKey(client, "a")
+
+	expired := make(chan string, 1)
+	client.OnExpire(func(key string, oldValue, newValue []byte, cas uint64) {
+		expired <- key
+	})
+
+	client.StartReaper(time.Millisecond)
+	defer client.StopReaper()
+
+	select {
+	case key := <-expired:
+		if key != "a" {
+			t.Fatal("key mismatch")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the reaper")
+	}
+	if client.ExpiredKeys() != nil || client.Count() != 1 {
+		t.Fatal("purge mismatch")
+	}
+
+	// restarting replaces the reaper, stopping twice is harmless
+	client.StartReaper(time.Hour)
+	client.StopReaper()
+	client.StopReaper()
+}
+
+func TestReaperReadOnly(t *testing.T) {
+	client := New()
+	client.Insert("a", "val", 0)
+	expireKey(client, "a")
+	client.SetReadOnly(true)
+
+	// a non-positive interval falls back to the default rather than panicking
+	client.StartReaper(0)
+	client.StopReaper()
+
+	client.StartReaper(time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	client.StopReaper()
+	if client.stored.Load() != 1 {
+		t.Fatal("purge mismatch")
+	}
+}
@@ -0,0 +1,218 @@
+package crud
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+)
+
+// ErrUnsupportedTranscoder defines the error value returned by Serve for a store whose Transcoder isn't
+// JSONTranscoder, since Remote sends and receives values as JSON
+var ErrUnsupportedTranscoder = errors.New("remote clients require the JSON transcoder")
+
+// wireErrors are the sentinel errors restored by Remote when returned by the serving process.
+// Only the errors the operations forwarded by Remote can return are listed.
+var wireErrors = []error{
+	ErrKeyExist,
+	ErrKeyNotExist,
+	ErrCasMismatch,
+	ErrRateLimited,
+	ErrQueueFull,
+	ErrInvalidExpiry,
+	ErrMaxTTLExceeded,
+	ErrDocumentLocked,
+	ErrUniqueViolation,
+	ErrReadOnly,
+	ErrQuotaExceeded,
+	ErrValueTooLarge,
+	ErrInvalidKey,
+}
+
+// wireRequest is the request message of the internal wire protocol
+type wireRequest struct {
+	Op     string          `json:"op"`
+	Key    string          `json:"key"`
+	Value  json.RawMessage `json:"value,omitempty"`
+	Cas    uint64          `json:"cas,omitempty"`
+	Expiry uint32          `json:"expiry,omitempty"`
+	// KeyBase64 holds the key in place of Key when it isn't valid UTF-8, see encodeKey
+	KeyBase64 []byte `json:"key_base64,omitempty"`
+}
+
+// wireResponse is the response message of the internal wire protocol
+type wireResponse struct {
+	Cas   uint64          `json:"cas"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Err   string          `json:"err,omitempty"`
+}
+
+// server serves a store to Remote clients
+type server struct {
+	crud *CRUD
+}
+
+// handle executes a single request against the store
+func (s *server) handle(req wireRequest) wireResponse {
+	var (
+		res wireResponse
+		err error
+	)
+	key := decodeKey(req.Key, req.KeyBase64)
+	switch req.Op {
+	case "get":
+		res.Cas, err = s.crud.Get(key, &res.Value)
+	case "insert":
+		res.Cas, err = s.crud.Insert(key, req.Value, req.Expiry)
+	case "upsert":
+		res.Cas, err = s.crud.Upsert(key, req.Value, req.Expiry)
+	case "replace":
+		res.Cas, err = s.crud.Replace(key, req.Value, req.Cas, req.Expiry)
+	case "remove":
+		res.Cas, err = s.crud.Remove(key, req.Cas)
+	case "touch":
+		res.Cas, err = s.crud.Touch(key, req.Cas, req.Expiry)
+	default:
+		err = errors.New("unknown operation " + req.Op)
+	}
+	if err != nil {
+		res.Err = err.Error()
+	}
+	return res
+}
+
+// serveConn handles requests from a single connection until it is closed
+func (s *server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	for {
+		var req wireRequest
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		if err := enc.Encode(s.handle(req)); err != nil {
+			return
+		}
+	}
+}
+
+// Serve accepts connections on l and serves the store to Remote clients until l is closed.
+// It is typically used with a unix socket so that several test processes share the same mock state.
+// Stores whose Transcoder isn't JSONTranscoder return ErrUnsupportedTranscoder without accepting connections.
+func (crud *CRUD) Serve(l net.Listener) error {
+	switch crud.transcoder.(type) {
+	case JSONTranscoder, *JSONTranscoder:
+	default:
+		return ErrUnsupportedTranscoder
+	}
+	s := &server{crud: crud}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Remote is a client for a store served by another process.
+// It is safe for concurrent use; requests are sent one at a time over a single connection.
+type Remote struct {
+	mu   sync.Mutex
+	conn net.Conn
+	enc  *json.Encoder
+	dec  *json.Decoder
+}
+
+// Dial connects to a store served on the unix socket at path
+func Dial(path string) (*Remote, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &Remote{conn: conn, enc: json.NewEncoder(conn), dec: json.NewDecoder(conn)}, nil
+}
+
+// Close closes the connection to the serving process
+func (r *Remote) Close() error {
+	return r.conn.Close()
+}
+
+// call sends a request and waits for its response, restoring sentinel errors
+func (r *Remote) call(req wireRequest) (wireResponse, error) {
+	req.Key, req.KeyBase64 = encodeKey(req.Key)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var res wireResponse
+	if err := r.enc.Encode(req); err != nil {
+		return res, err
+	}
+	if err := r.dec.Decode(&res); err != nil {
+		return res, err
+	}
+	if res.Err != "" {
+		for _, known := range wireErrors {
+			if res.Err == known.Error() {
+				return res, known
+			}
+		}
+		return res, errors.New(res.Err)
+	}
+	return res, nil
+}
+
+// Get provides the Get Database Operation of the remote store
+func (r *Remote) Get(key string, valuePtr interface{}) (uint64, error) {
+	res, err := r.call(wireRequest{Op: "get", Key: key})
+	if err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(res.Value, valuePtr); err != nil {
+		return 0, err
+	}
+	return res.Cas, nil
+}
+
+// Insert provides the Insert Database Operation of the remote store
+func (r *Remote) Insert(key string, value interface{}, expiry uint32) (uint64, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, err
+	}
+	res, err := r.call(wireRequest{Op: "insert", Key: key, Value: data, Expiry: expiry})
+	return res.Cas, err
+}
+
+// Upsert provides the Upsert Database Operation of the remote store
+func (r *Remote) Upsert(key string, value interface{}, expiry uint32) (uint64, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, err
+	}
+	res, err := r.call(wireRequest{Op: "upsert", Key: key, Value: data, Expiry: expiry})
+	return res.Cas, err
+}
+
+// Replace provides the Replace Database Operation of the remote store
+func (r *Remote) Replace(key string, value interface{}, cas uint64, expiry uint32) (uint64, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, err
+	}
+	res, err := r.call(wireRequest{Op: "replace", Key: key, Value: data, Cas: cas, Expiry: expiry})
+	return res.Cas, err
+}
+
+// Remove provides the Remove Database Operation of the remote store
+func (r *Remote) Remove(key string, cas uint64) (uint64, error) {
+	res, err := r.call(wireRequest{Op: "remove", Key: key, Cas: cas})
+	return res.Cas, err
+}
+
+// Touch provides the Touch Database Operation of the remote store
+func (r *Remote) Touch(key string, cas uint64, expiry uint32) (uint64, error) {
+	res, err := r.call(wireRequest{Op: "touch", Key: key, Cas: cas, Expiry: expiry})
+	return res.Cas, err
+}
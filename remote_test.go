@@ -0,0 +1,217 @@
+package crud
+
+import (
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveUnix(t *testing.T, store *CRUD) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "crud.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go store.Serve(l)
+	return path
+}
+
+func TestRemote(t *testing.T) {
+	store := New()
+	path := serveUnix(t, store)
+
+	remote, err := Dial(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+
+	cas, err := remote.Insert("key", "val", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the serving store observes writes made by remote clients
+	var act string
+	if _, err := store.Get("key", &act); err != nil {
+		t.Fatal(err)
+	}
+	if act != "val" {
+		t.Fatal("results mismatch")
+	}
+
+	cas2, err := remote.Replace("key", "val2", cas, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cas2 != cas+1 {
+		t.Fatal("cas mismatch")
+	}
+
+	if _, err := remote.Get("key", &act); err != nil {
+		t.Fatal(err)
+	}
+	if act != "val2" {
+		t.Fatal("results mismatch")
+	}
+
+	_, err = remote.Insert("key", "val", 0)
+	if !reflect.DeepEqual(err, ErrKeyExist) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestRemoteSharedBetweenClients(t *testing.T) {
+	path := serveUnix(t, New())
+
+	first, err := Dial(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := Dial(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	cas, _ := first.Upsert("key", 42, 0)
+	if _, err := second.Remove("key", cas); err != nil {
+		t.Fatal(err)
+	}
+
+	var act int
+	_, err = first.Get("key", &act)
+	if !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestRemoteWireErrors(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+	tests := []struct {
+		exp   error
+		store func() *CRUD
+		op    func(r *Remote) error
+	}{
+		{ErrKeyExist, func() *CRUD { return New() }, func(r *Remote) error {
+			r.Insert("key", "val", 0)
+			_, err := r.Insert("key", "val", 0)
+			return err
+		}},
+		{ErrKeyNotExist, func() *CRUD { return New() }, func(r *Remote) error {
+			_, err := r.Get("key", new(string))
+			return err
+		}},
+		{ErrCasMismatch, func() *CRUD { return New() }, func(r *Remote) error {
+			cas, _ := r.Insert("key", "val", 0)
+			_, err := r.Replace("key", "val", cas+1, 0)
+			return err
+		}},
+		{ErrRateLimited, func() *CRUD { return New().Client(WithRateLimit(0, 1)) }, func(r *Remote) error {
+			r.Insert("key", "val", 0)
+			_, err := r.Insert("key2", "val", 0)
+			return err
+		}},
+		{ErrQueueFull, func() *CRUD {
+			store := New(WithQueue(1, QueueFailFast))
+			store.Pause()
+			go store.Get("key", new(string))
+			for store.QueueDepth() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			return store
+		}, func(r *Remote) error {
+			_, err := r.Insert("key", "val", 0)
+			return err
+		}},
+		{ErrInvalidExpiry, func() *CRUD { return New() }, func(r *Remote) error {
+			_, err := r.Insert("key", "val", ThirtyDaySeconds+1)
+			return err
+		}},
+		{ErrMaxTTLExceeded, func() *CRUD { return New(WithMaxTTL(time.Minute, MaxTTLReject)) }, func(r *Remote) error {
+			_, err := r.Insert("key", "val", 120)
+			return err
+		}},
+		{ErrDocumentLocked, func() *CRUD {
+			store := New()
+			store.Insert("key", "val", 0)
+			store.GetAndLock("key", 10, new(string))
+			return store
+		}, func(r *Remote) error {
+			_, err := r.Upsert("key", "val", 0)
+			return err
+		}},
+		{ErrUniqueViolation, func() *CRUD {
+			store := New()
+			store.CreateUniqueIndex("name", "name")
+			return store
+		}, func(r *Remote) error {
+			r.Insert("a", user{Name: "x"}, 0)
+			_, err := r.Insert("b", user{Name: "x"}, 0)
+			return err
+		}},
+		{ErrReadOnly, func() *CRUD {
+			store := New()
+			store.SetReadOnly(true)
+			return store
+		}, func(r *Remote) error {
+			_, err := r.Insert("key", "val", 0)
+			return err
+		}},
+		{ErrQuotaExceeded, func() *CRUD { return New(WithMaxKeys(1)) }, func(r *Remote) error {
+			r.Insert("a", "val", 0)
+			_, err := r.Insert("b", "val", 0)
+			return err
+		}},
+		{ErrValueTooLarge, func() *CRUD { return New(WithMaxValueSize(4)) }, func(r *Remote) error {
+			_, err := r.Insert("key", "too long", 0)
+			return err
+		}},
+		{ErrInvalidKey, func() *CRUD { return New(WithStrictKeys()) }, func(r *Remote) error {
+			_, err := r.Insert(strings.Repeat("k", 251), "val", 0)
+			return err
+		}},
+	}
+
+	covered := map[error]bool{}
+	for _, test := range tests {
+		store := test.store()
+		remote, err := Dial(serveUnix(t, store))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = test.op(remote)
+		remote.Close()
+		store.Resume()
+		if !reflect.DeepEqual(err, test.exp) {
+			t.Fatalf("error mismatch: %v, expected %v", err, test.exp)
+		}
+		covered[test.exp] = true
+	}
+
+	// every error restored by Remote can be returned by a remote operation
+	for _, known := range wireErrors {
+		if !covered[known] {
+			t.Fatalf("wire error %v not covered", known)
+		}
+	}
+}
+
+func TestServeUnsupportedTranscoder(t *testing.T) {
+	l, err := net.Listen("unix", filepath.Join(t.TempDir(), "crud.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := New(WithTranscoder(gobTranscoder{})).Serve(l); !reflect.DeepEqual(err, ErrUnsupportedTranscoder) {
+		t.Fatal("error mismatch")
+	}
+}
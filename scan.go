@@ -0,0 +1,58 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Document is a document returned by a scan or query
+type Document struct {
+	Key string
+	Cas uint64
+	// Value holds the bytes produced by the transcoder, which are only valid JSON with the default JSONTranscoder
+	Value json.RawMessage
+
+	transcoder Transcoder
+}
+
+// Content decodes the document's value into valuePtr
+func (d Document) Content(valuePtr interface{}) error {
+	return d.transcoder.Unmarshal(d.Value, valuePtr)
+}
+
+// scan returns the live documents whose key satisfies match, or every live document when match is nil, in key order.
+// The documents are copied so they stay valid while the store is modified.
+func (crud *CRUD) scan(match func(key string) bool) []Document {
+	now := crud.getTime()
+	var docs []Document
+	crud.rangeDocs(func(key string, doc *document) {
+		if doc.expired(now) || (match != nil && !match(key)) {
+			return
+		}
+		docs = append(docs, Document{
+			Key:        key,
+			Cas:        doc.reportedCas(now),
+			Value:      append(json.RawMessage(nil), doc.value()...),
+			transcoder: crud.transcoder,
+		})
+	})
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].Key < docs[j].Key
+	})
+	return docs
+}
+
+// ScanPrefix returns, in key order, the live documents whose key starts with prefix
+func (crud *CRUD) ScanPrefix(prefix string) ([]Document, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return crud.scan(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	}), nil
+}
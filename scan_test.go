@@ -0,0 +1,38 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanPrefix(t *testing.T) {
+	client := NewSharded(4)
+	for _, key := range []string{"user::2::profile", "user::1::profile", "user::10::profile", "order::1"} {
+		_, _ = client.Insert(key, key, 0)
+	}
+	_, _ = client.Insert("user::3::profile", "val", 0)
+	expireKey(client, "user::3::profile")
+
+	docs, err := client.ScanPrefix("user::")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, doc := range docs {
+		keys = append(keys, doc.Key)
+		var act string
+		if err := doc.Content(&act); err != nil || act != doc.Key {
+			t.Fatal("value mismatch")
+		}
+		if doc.Cas != 1 {
+			t.Fatal("cas mismatch")
+		}
+	}
+	if !reflect.DeepEqual(keys, []string{"user::10::profile", "user::1::profile", "user::2::profile"}) {
+		t.Fatalf("keys mismatch %v", keys)
+	}
+
+	if docs, _ := client.ScanPrefix("user::1::"); len(docs) != 1 {
+		t.Fatal("prefix matched too many documents")
+	}
+}
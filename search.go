@@ -0,0 +1,246 @@
+package crud
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+type searchKind int
+
+const (
+	searchMatch searchKind = iota
+	searchPrefix
+	searchFuzzy
+)
+
+// SearchQuery is a full-text query run by Search
+type SearchQuery struct {
+	kind      searchKind
+	terms     []string
+	fuzziness int
+}
+
+// MatchQuery matches documents containing any of the words of text, scoring higher the documents
+// containing more of them
+func MatchQuery(text string) SearchQuery {
+	return SearchQuery{kind: searchMatch, terms: tokenize(text)}
+}
+
+// PrefixQuery matches documents containing a word starting with prefix
+func PrefixQuery(prefix string) SearchQuery {
+	return SearchQuery{kind: searchPrefix, terms: []string{strings.ToLower(prefix)}}
+}
+
+// FuzzyQuery matches documents containing a word within fuzziness edits of term.
+// Closer words score higher.
+func FuzzyQuery(term string, fuzziness int) SearchQuery {
+	return SearchQuery{kind: searchFuzzy, terms: []string{strings.ToLower(term)}, fuzziness: fuzziness}
+}
+
+// SearchHit is a document matched by Search
+type SearchHit struct {
+	Key   string
+	Score float64
+}
+
+// searchSet holds the search indexes created by CreateSearchIndex, each defined by the paths of its fields
+type searchSet struct {
+	mu      sync.RWMutex
+	indexes map[string][][]pathSegment
+}
+
+func newSearchSet() *searchSet {
+	return &searchSet{indexes: make(map[string][][]pathSegment)}
+}
+
+// CreateSearchIndex creates a full-text index over the string fields at paths, emulating a search service.
+// A field holding an array has each of its strings indexed. The index is computed from the live documents
+// each time it is searched, so it never lags behind writes.
+func (crud *CRUD) CreateSearchIndex(name string, paths ...string) error {
+	if len(paths) == 0 {
+		return ErrInvalidPath
+	}
+	fields := make([][]pathSegment, len(paths))
+	for i, path := range paths {
+		segs, err := parsePath(path)
+		if err != nil {
+			return err
+		}
+		fields[i] = segs
+	}
+
+	crud.search.mu.Lock()
+	defer crud.search.mu.Unlock()
+	if _, ok := crud.search.indexes[name]; ok {
+		return ErrIndexExists
+	}
+	crud.search.indexes[name] = fields
+	return nil
+}
+
+// DropSearchIndex removes a search index
+func (crud *CRUD) DropSearchIndex(name string) error {
+	crud.search.mu.Lock()
+	defer crud.search.mu.Unlock()
+	if _, ok := crud.search.indexes[name]; !ok {
+		return ErrIndexNotFound
+	}
+	delete(crud.search.indexes, name)
+	return nil
+}
+
+// tokenize lower cases text and splits it into words of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Search runs query against a search index and returns up to limit hits ordered by descending score,
+// then by key. A limit of zero returns every hit. Scores weigh each matched word by how often it occurs
+// in the document and how rare it is across the indexed documents.
+func (crud *CRUD) Search(name string, query SearchQuery, limit int) ([]SearchHit, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpQuery); err != nil {
+		return nil, err
+	}
+
+	crud.search.mu.RLock()
+	fields, ok := crud.search.indexes[name]
+	crud.search.mu.RUnlock()
+	if !ok {
+		return nil, ErrIndexNotFound
+	}
+
+	// count the words of each document
+	type indexed struct {
+		key   string
+		words map[string]int
+	}
+	var docs []indexed
+	for _, doc := range crud.scan(nil) {
+		root, err := decodeJSON(doc.Value)
+		if err != nil {
+			continue
+		}
+		words := make(map[string]int)
+		for _, segs := range fields {
+			v, _ := getPath(root, segs)
+			values, isArray := v.([]interface{})
+			if !isArray {
+				values = []interface{}{v}
+			}
+			for _, value := range values {
+				if s, ok := value.(string); ok {
+					for _, word := range tokenize(s) {
+						words[word]++
+					}
+				}
+			}
+		}
+		if len(words) > 0 {
+			docs = append(docs, indexed{key: doc.Key, words: words})
+		}
+	}
+
+	// weigh each word of the documents by how closely it matches a query term
+	weights := make(map[string]float64)
+	freq := make(map[string]int)
+	for _, doc := range docs {
+		for word := range doc.words {
+			if _, seen := weights[word]; !seen {
+				weights[word] = query.weight(word)
+			}
+			if weights[word] > 0 {
+				freq[word]++
+			}
+		}
+	}
+
+	var hits []SearchHit
+	for _, doc := range docs {
+		var score float64
+		for word, n := range doc.words {
+			if w := weights[word]; w > 0 {
+				idf := math.Log(1 + float64(len(docs))/float64(freq[word]))
+				score += w * math.Sqrt(float64(n)) * idf
+			}
+		}
+		if score > 0 {
+			hits = append(hits, SearchHit{Key: doc.key, Score: score})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Key < hits[j].Key
+	})
+	if limit > 0 && limit < len(hits) {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// weight returns how well word matches the query, zero when it doesn't
+func (q SearchQuery) weight(word string) float64 {
+	var best float64
+	for _, term := range q.terms {
+		var w float64
+		switch q.kind {
+		case searchMatch:
+			if word == term {
+				w = 1
+			}
+		case searchPrefix:
+			if strings.HasPrefix(word, term) {
+				w = 1
+			}
+		case searchFuzzy:
+			if d := editDistance(word, term, q.fuzziness); d <= q.fuzziness {
+				w = 1 / float64(1+d)
+			}
+		}
+		best = math.Max(best, w)
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b, or max+1 once it is known to exceed max
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,95 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func hitKeys(hits []SearchHit) []string {
+	keys := []string{}
+	for _, hit := range hits {
+		keys = append(keys, hit.Key)
+	}
+	return keys
+}
+
+func TestSearch(t *testing.T) {
+	client := New()
+	client.Insert("b1", map[string]interface{}{"title": "The Go Programming Language", "tags": []string{"golang", "programming"}}, 0)
+	client.Insert("b2", map[string]interface{}{"title": "Programming Pearls", "body": "go go go"}, 0)
+	client.Insert("b3", map[string]interface{}{"title": "Cooking for Programmers", "tags": []string{"food"}}, 0)
+	client.Insert("b4", map[string]interface{}{"year": 1999}, 0)
+
+	if err := client.CreateSearchIndex("books", "title", "tags"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query SearchQuery
+		exp   []string
+	}{
+		// b1 matches both words, b2 only one of them
+		{MatchQuery("go programming"), []string{"b1", "b2"}},
+		{MatchQuery("GO"), []string{"b1"}},
+		{MatchQuery("food"), []string{"b3"}},
+		{MatchQuery("missing"), []string{}},
+		// the rarer matching word ranks its document first
+		{PrefixQuery("program"), []string{"b3", "b1", "b2"}},
+		// words up to fuzziness edits away match
+		{FuzzyQuery("pearl", 1), []string{"b2"}},
+		{FuzzyQuery("cookin", 1), []string{"b3"}},
+		{FuzzyQuery("perls", 0), []string{}},
+	}
+	for _, test := range tests {
+		hits, err := client.Search("books", test.query, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := hitKeys(hits); !reflect.DeepEqual(act, test.exp) {
+			t.Fatalf("hits mismatch: %v", act)
+		}
+	}
+
+	hits, _ := client.Search("books", FuzzyQuery("programing", 2), 2)
+	if len(hits) != 2 || hits[0].Score < hits[1].Score {
+		t.Fatal("hits mismatch")
+	}
+}
+
+func TestSearchIndexErrors(t *testing.T) {
+	client := New()
+	if err := client.CreateSearchIndex("idx"); !reflect.DeepEqual(err, ErrInvalidPath) {
+		t.Fatal("error mismatch")
+	}
+	client.CreateSearchIndex("idx", "title")
+	if err := client.CreateSearchIndex("idx", "body"); !reflect.DeepEqual(err, ErrIndexExists) {
+		t.Fatal("error mismatch")
+	}
+	if err := client.DropSearchIndex("idx"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.DropSearchIndex("idx"); !reflect.DeepEqual(err, ErrIndexNotFound) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.Search("idx", MatchQuery("x"), 0); !reflect.DeepEqual(err, ErrIndexNotFound) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		max  int
+		exp  int
+	}{
+		{"kitten", "sitting", 5, 3},
+		{"kitten", "sitting", 2, 3},
+		{"", "abc", 5, 3},
+		{"same", "same", 0, 0},
+	}
+	for _, test := range tests {
+		if act := editDistance(test.a, test.b, test.max); act != test.exp {
+			t.Fatal("distance mismatch")
+		}
+	}
+}
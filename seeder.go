@@ -0,0 +1,98 @@
+package crud
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ValueFunc returns the value of the i-th document generated by a Seeder, drawing any randomness from rnd
+type ValueFunc func(i int, rnd *rand.Rand) interface{}
+
+// TTLFunc returns the time the i-th document generated by a Seeder lives for, zero when it never expires
+type TTLFunc func(i int, rnd *rand.Rand) time.Duration
+
+// FixedTTL returns a TTLFunc giving every document the same ttl
+func FixedTTL(ttl time.Duration) TTLFunc {
+	return func(int, *rand.Rand) time.Duration {
+		return ttl
+	}
+}
+
+// UniformTTL returns a TTLFunc drawing TTLs uniformly between min and max
+func UniformTTL(min, max time.Duration) TTLFunc {
+	return func(_ int, rnd *rand.Rand) time.Duration {
+		if max <= min {
+			return min
+		}
+		return min + time.Duration(rnd.Int63n(int64(max-min)+1))
+	}
+}
+
+// Seeder generates synthetic documents, see CRUD.Seeder
+type Seeder struct {
+	crud    *CRUD
+	pattern string
+	value   ValueFunc
+	ttl     TTLFunc
+	rnd     *rand.Rand
+	next    int
+}
+
+// Seeder returns a builder populating the store with generated documents. By default the i-th document,
+// counting from zero, is stored under "doc::i" with the value {"id": i} and never expires.
+// The randomness given to the value and TTL functions is seeded with zero unless Seed is called,
+// so the same builder calls always generate the same documents.
+func (crud *CRUD) Seeder() *Seeder {
+	return &Seeder{
+		crud:    crud,
+		pattern: "doc::%d",
+		value: func(i int, _ *rand.Rand) interface{} {
+			return map[string]int{"id": i}
+		},
+		rnd: rand.New(rand.NewSource(0)),
+	}
+}
+
+// Keys sets the pattern of the generated keys, formatted with fmt.Sprintf and the document's index,
+// such as "user::%05d"
+func (s *Seeder) Keys(pattern string) *Seeder {
+	s.pattern = pattern
+	return s
+}
+
+// Values sets the function generating the documents' values
+func (s *Seeder) Values(fn ValueFunc) *Seeder {
+	s.value = fn
+	return s
+}
+
+// TTLs sets the function generating the documents' TTLs, such as FixedTTL or UniformTTL
+func (s *Seeder) TTLs(fn TTLFunc) *Seeder {
+	s.ttl = fn
+	return s
+}
+
+// Seed reseeds the randomness given to the value and TTL functions
+func (s *Seeder) Seed(seed int64) *Seeder {
+	s.rnd = rand.New(rand.NewSource(seed))
+	return s
+}
+
+// Insert generates n documents and inserts them, continuing the numbering of earlier calls.
+// It stops at the first failed insert, returning its error.
+func (s *Seeder) Insert(n int) error {
+	for end := s.next + n; s.next < end; s.next++ {
+		i := s.next
+		key := fmt.Sprintf(s.pattern, i)
+		value := s.value(i, s.rnd)
+		var ttl time.Duration
+		if s.ttl != nil {
+			ttl = s.ttl(i, s.rnd)
+		}
+		if _, err := s.crud.InsertWith(key, value, WithRelativeExpiry(ttl)); err != nil {
+			return fmt.Errorf("seeding %q: %w", key, err)
+		}
+	}
+	return nil
+}
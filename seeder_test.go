@@ -0,0 +1,56 @@
+package crud
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestSeeder(t *testing.T) {
+	client := New()
+	if err := client.Seeder().Insert(3); err != nil {
+		t.Fatal(err)
+	}
+	var act map[string]int
+	if _, err := client.Get("doc::2", &act); err != nil || act["id"] != 2 {
+		t.Fatal("value mismatch")
+	}
+
+	seed := func(c *CRUD) *Seeder {
+		return c.Seeder().
+			Keys("user::%03d").
+			Values(func(i int, rnd *rand.Rand) interface{} {
+				return map[string]int{"score": rnd.Intn(1000)}
+			}).
+			TTLs(UniformTTL(time.Minute, time.Hour)).
+			Seed(42)
+	}
+	first, second := New(), New()
+	s := seed(first)
+	if err := s.Insert(50); err != nil {
+		t.Fatal(err)
+	}
+	// numbering continues across calls
+	if err := s.Insert(50); err != nil {
+		t.Fatal(err)
+	}
+	if first.Count() != 100 || first.Keys()[99] != "user::099" {
+		t.Fatal("count mismatch")
+	}
+	for i, key := range first.Keys() {
+		if ttl := expiresIn(first, key); ttl < time.Minute-time.Second || ttl > time.Hour {
+			t.Fatalf("ttl %d mismatch %s", i, ttl)
+		}
+	}
+
+	// the same seed generates the same documents
+	_ = seed(second).Insert(100)
+	if diff, _ := first.Diff(second); !diff.Empty() {
+		t.Fatal("seeded documents mismatch")
+	}
+
+	if err := client.Seeder().Insert(1); !errors.Is(err, ErrKeyExist) {
+		t.Fatal("error mismatch")
+	}
+}
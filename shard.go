@@ -0,0 +1,137 @@
+package crud
+
+import (
+	"sort"
+	"sync"
+)
+
+// shard is a partition of the store's documents guarded by its own lock
+type shard struct {
+	// mu guards docs and the documents in it
+	mu   sync.RWMutex
+	docs map[string]*document
+	// shared is set while docs is shared with a snapshot view, see SnapshotView
+	shared bool
+}
+
+// lock write locks the shard, first copying its documents if a snapshot view shares them.
+// Writers must lock the shard with lock, or with lockKeys.
+func (s *shard) lock() {
+	s.mu.Lock()
+	if !s.shared {
+		return
+	}
+	docs := make(map[string]*document, len(s.docs))
+	for key, doc := range s.docs {
+		// documents are modified in place by some writes, values never are
+		cp := *doc
+		docs[key] = &cp
+	}
+	s.docs = docs
+	s.shared = false
+}
+
+// reset empties the shard, it must be called with the write lock held
+func (s *shard) reset() {
+	s.docs = make(map[string]*document)
+	s.shared = false
+}
+
+func newShards(n int) []*shard {
+	if n < 1 {
+		n = 1
+	}
+	shards := make([]*shard, n)
+	for i := range shards {
+		shards[i] = &shard{docs: make(map[string]*document)}
+	}
+	return shards
+}
+
+// NewSharded creates a crud database whose documents are partitioned by key hash into the given
+// number of shards, each with its own lock, so that operations on different keys rarely contend.
+// New is equivalent to NewSharded with a single shard, where every write excludes every other operation.
+//
+// Operations on keys in different shards never contend, while two keys share a shard with a probability of
+// 1/shards. Outside of the shards, writes only serialise briefly to order the change feed, and on the options
+// needing a global view such as unique indexes, WithKeyIndex, WithMaxKeys and WithMemoryLimit. More shards
+// cost a lock and a map each, a few hundred bytes, and make the operations spanning every key, such as Keys,
+// scans, queries, Flush and Clone, lock more shards in turn. A few shards per core is usually enough.
+func NewSharded(shards int, opts ...Option) *CRUD {
+	return newCRUD(shards, opts)
+}
+
+// WithLockStripes is the option form of NewSharded, for constructors taking only options such as
+// NewWithBackend and NewFromWAL: New(WithLockStripes(n)) is NewSharded(n).
+func WithLockStripes(n int) Option {
+	return func(crud *CRUD) {
+		crud.shards = newShards(n)
+	}
+}
+
+// shardFor returns the shard holding key
+func (db *db) shardFor(key string) *shard {
+	return db.shards[db.shardIndex(key)]
+}
+
+// shardIndex returns the index of the shard holding key.
+// Operations locking several shards must lock them in index order.
+func (db *db) shardIndex(key string) int {
+	if len(db.shards) == 1 {
+		return 0
+	}
+	// FNV-1a, inlined to avoid allocating a hash.Hash per operation
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return int(h % uint32(len(db.shards)))
+}
+
+// lockKeys write locks the shards holding keys in index order and returns a function unlocking them
+func (db *db) lockKeys(keys []string) func() {
+	seen := make(map[int]bool)
+	var indexes []int
+	for _, key := range keys {
+		if i := db.shardIndex(key); !seen[i] {
+			seen[i] = true
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		db.shards[i].lock()
+	}
+	return func() {
+		for j := len(indexes) - 1; j >= 0; j-- {
+			db.shards[indexes[j]].mu.Unlock()
+		}
+	}
+}
+
+// lockAll write locks every shard and returns a function unlocking them. Shards shared with a snapshot view
+// aren't copied, so callers must only read the documents or reset the shards.
+func (db *db) lockAll() func() {
+	for _, s := range db.shards {
+		s.mu.Lock()
+	}
+	return func() {
+		for j := len(db.shards) - 1; j >= 0; j-- {
+			db.shards[j].mu.Unlock()
+		}
+	}
+}
+
+// rangeDocs calls fn for every stored document, including expired ones.
+// Each shard is read locked in turn, so the documents seen are not a point in time view across shards.
+// fn must not modify the document.
+func (db *db) rangeDocs(fn func(key string, doc *document)) {
+	for _, s := range db.shards {
+		s.mu.RLock()
+		for key, doc := range s.docs {
+			fn(key, doc)
+		}
+		s.mu.RUnlock()
+	}
+}
@@ -0,0 +1,117 @@
+package crud
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// stored returns the document held for key, nil when there is none
+func stored(client *CRUD, key string) *document {
+	return client.shardFor(key).docs[key]
+}
+
+func TestSharded(t *testing.T) {
+	client := NewSharded(8)
+
+	for i := 0; i < 100; i++ {
+		if _, err := client.Insert(strconv.Itoa(i), i, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	used := 0
+	for _, s := range client.shards {
+		if len(s.docs) > 0 {
+			used++
+		}
+	}
+	if used < 2 {
+		t.Fatal("keys were not partitioned across shards")
+	}
+
+	var val int
+	cas, err := client.Get("42", &val)
+	if err != nil || val != 42 {
+		t.Fatal("value mismatch")
+	}
+	if _, err := client.Remove("42", cas); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get("42", &val); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+	if report := client.SizeHistogram(""); report.Count != 99 {
+		t.Fatalf("count mismatch %d", report.Count)
+	}
+}
+
+func TestShardedConcurrentAccess(t *testing.T) {
+	client := NewSharded(4)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				key := strconv.Itoa(w*100 + i)
+				_, _ = client.Upsert(key, i, 0)
+				var val int
+				_, _ = client.Get(key, &val)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if report := client.SizeHistogram(""); report.Count != 800 {
+		t.Fatalf("count mismatch %d", report.Count)
+	}
+}
+
+func benchmarkUpsertParallel(b *testing.B, client *CRUD) {
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			_, _ = client.Upsert(strconv.Itoa(i%1024), i, 0)
+			i++
+		}
+	})
+}
+
+func BenchmarkUpsertParallel(b *testing.B) {
+	benchmarkUpsertParallel(b, New())
+}
+
+func BenchmarkUpsertParallelSharded(b *testing.B) {
+	benchmarkUpsertParallel(b, NewSharded(32))
+}
+
+func TestWithLockStripes(t *testing.T) {
+	client := New(WithLockStripes(16))
+	if len(client.shards) != 16 {
+		t.Fatal("stripe count mismatch")
+	}
+	for i := 0; i < 100; i++ {
+		_, _ = client.Insert(strconv.Itoa(i), i, 0)
+	}
+	if client.Count() != 100 {
+		t.Fatal("count mismatch")
+	}
+
+	// documents loaded by a backend are partitioned as well
+	b := newMemBackend()
+	_ = b.Put(Record{Key: "a", Value: []byte("1"), Cas: 1})
+	restored, err := NewWithBackend(b, WithLockStripes(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored.shards) != 4 {
+		t.Fatal("stripe count mismatch")
+	}
+	var act int
+	if _, err := restored.Get("a", &act); err != nil || act != 1 {
+		t.Fatal("value mismatch")
+	}
+}
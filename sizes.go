@@ -0,0 +1,65 @@
+package crud
+
+import (
+	"sort"
+	"strings"
+)
+
+// SizeBucket counts the documents whose encoded size is at most Max bytes
+// and greater than the Max of the previous power of two
+type SizeBucket struct {
+	Max   int
+	Count int
+}
+
+// SizeReport describes the distribution of encoded document sizes in the store
+type SizeReport struct {
+	// Buckets holds the non-empty power of two size buckets in ascending order
+	Buckets []SizeBucket
+	// PrefixBytes holds the total encoded size of the documents sharing each key prefix
+	PrefixBytes map[string]int64
+	Count       int
+	TotalBytes  int64
+}
+
+// sizeBucket returns the smallest power of two greater than or equal to size
+func sizeBucket(size int) int {
+	max := 1
+	for max < size {
+		max <<= 1
+	}
+	return max
+}
+
+// SizeHistogram reports the distribution of encoded sizes of the live documents in the store.
+// Key prefixes are the part of the key before the first occurrence of prefixSep; keys without it are
+// accounted under the empty prefix.
+func (crud *CRUD) SizeHistogram(prefixSep string) SizeReport {
+	report := SizeReport{PrefixBytes: make(map[string]int64)}
+	counts := make(map[int]int)
+
+	now := crud.getTime()
+	crud.rangeDocs(func(key string, doc *document) {
+		if doc.expired(now) {
+			return
+		}
+		size := doc.size()
+		counts[sizeBucket(size)]++
+		report.Count++
+		report.TotalBytes += int64(size)
+
+		prefix := ""
+		if i := strings.Index(key, prefixSep); prefixSep != "" && i >= 0 {
+			prefix = key[:i]
+		}
+		report.PrefixBytes[prefix] += int64(size)
+	})
+
+	for max, count := range counts {
+		report.Buckets = append(report.Buckets, SizeBucket{Max: max, Count: count})
+	}
+	sort.Slice(report.Buckets, func(i, j int) bool {
+		return report.Buckets[i].Max < report.Buckets[j].Max
+	})
+	return report
+}
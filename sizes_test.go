@@ -0,0 +1,33 @@
+package crud
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSizeHistogram(t *testing.T) {
+	client := New()
+	_, _ = client.Insert("user::1", "a", 0)                      // 3 bytes
+	_, _ = client.Insert("user::2", strings.Repeat("a", 6), 0)   // 8 bytes
+	_, _ = client.Insert("order::1", strings.Repeat("a", 98), 0) // 100 bytes
+	_, _ = client.Insert("config", 1, 0)                         // 1 byte
+	_, _ = client.Insert("order::2", strings.Repeat("a", 98), 0) // expired
+	expireKey(client, "order::2")
+
+	report := client.SizeHistogram("::")
+
+	expBuckets := []SizeBucket{{Max: 1, Count: 1}, {Max: 4, Count: 1}, {Max: 8, Count: 1}, {Max: 128, Count: 1}}
+	if !reflect.DeepEqual(report.Buckets, expBuckets) {
+		t.Fatalf("unexpected buckets %+v", report.Buckets)
+	}
+
+	expPrefixes := map[string]int64{"user": 11, "order": 100, "": 1}
+	if !reflect.DeepEqual(report.PrefixBytes, expPrefixes) {
+		t.Fatalf("unexpected prefix bytes %+v", report.PrefixBytes)
+	}
+
+	if report.Count != 4 || report.TotalBytes != 112 {
+		t.Fatalf("unexpected totals %d documents, %d bytes", report.Count, report.TotalBytes)
+	}
+}
@@ -0,0 +1,199 @@
+package crud
+
+import (
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ErrInvalidSnapshot defines the error value returned when Load is given a malformed snapshot
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// SnapshotFormat selects the encoding of a snapshot written by SaveAs and read by LoadAs
+type SnapshotFormat int
+
+const (
+	// SnapshotJSON encodes snapshots as JSON, readable by other languages, see Save
+	SnapshotJSON SnapshotFormat = iota
+	// SnapshotGob encodes snapshots with encoding/gob, which is more compact and faster to write and read
+	// than JSON for large datasets but only readable by Go
+	SnapshotGob
+)
+
+// snapshotVersion is the version of the snapshot format written by Save
+const snapshotVersion = 1
+
+// snapshot is the content of a snapshot written by Save
+type snapshot struct {
+	Version   int           `json:"version"`
+	Documents []snapshotDoc `json:"documents"`
+}
+
+// snapshotDoc is a document of a snapshot. JSON values are embedded as is, binary values and keys are
+// base64 encoded, see encodeKey. Times are Unix times in nanoseconds.
+type snapshotDoc struct {
+	Key       string          `json:"key"`
+	KeyBase64 []byte          `json:"key_base64,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	Binary    []byte          `json:"binary,omitempty"`
+	Cas       uint64          `json:"cas"`
+	Expiry    int64           `json:"expiry,omitempty"`
+	Created   int64           `json:"created,omitempty"`
+	Updated   int64           `json:"updated,omitempty"`
+}
+
+// snapshotOf returns the snapshot of the document stored under key
+func snapshotOf(key string, doc *document) snapshotDoc {
+	sd := snapshotDoc{Cas: doc.Cas, Expiry: doc.TTL, Created: doc.Created, Updated: doc.Updated}
+	sd.Key, sd.KeyBase64 = encodeKey(key)
+	if doc.Datatype == DatatypeBinary {
+		sd.Binary = doc.value()
+	} else {
+		sd.Value = doc.value()
+	}
+	return sd
+}
+
+// Save writes the live documents of the store, in key order, to w as a JSON snapshot which Load can restore:
+//
+//	{"version": 1, "documents": [{"key": "user::1", "value": {"name": "jo"}, "cas": 3, "expiry": 1700000000000000000}]}
+//
+// JSON values are embedded as is, binary values are base64 encoded under "binary" instead of "value"
+// and omitted when empty, keys which aren't valid UTF-8 are base64 encoded under "key_base64" instead
+// of "key", and the expiry and the created and updated times are Unix times in nanoseconds, omitted when
+// zero. Locks aren't saved.
+func (crud *CRUD) Save(w io.Writer) error {
+	return crud.SaveAs(w, SnapshotJSON)
+}
+
+// SaveAs writes the live documents of the store to w as a snapshot in the given format, see Save
+func (crud *CRUD) SaveAs(w io.Writer, format SnapshotFormat) error {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	now := crud.getTime()
+	snap := snapshot{Version: snapshotVersion, Documents: []snapshotDoc{}}
+	crud.rangeDocs(func(key string, doc *document) {
+		if doc.expired(now) {
+			return
+		}
+		snap.Documents = append(snap.Documents, snapshotOf(key, doc))
+	})
+	sort.Slice(snap.Documents, func(i, j int) bool {
+		return snap.Documents[i].Key < snap.Documents[j].Key
+	})
+	if format == SnapshotGob {
+		return gob.NewEncoder(w).Encode(snap)
+	}
+	return json.NewEncoder(w).Encode(snap)
+}
+
+// Load replaces every document of the store with those of a snapshot written by Save, keeping their
+// CAS values and expiries. Documents which expired since the snapshot was saved aren't restored.
+// The snapshot is read in full before the store is modified, so a malformed snapshot returns
+// ErrInvalidSnapshot and leaves the store untouched. Like Flush, no events are published and the
+// change log retained for ResumeFrom is discarded.
+func (crud *CRUD) Load(r io.Reader) error {
+	return crud.LoadAs(r, SnapshotJSON)
+}
+
+// LoadAs replaces every document of the store with those of a snapshot in the given format, see Load
+func (crud *CRUD) LoadAs(r io.Reader, format SnapshotFormat) error {
+	var snap snapshot
+	var err error
+	if format == SnapshotGob {
+		err = gob.NewDecoder(r).Decode(&snap)
+	} else {
+		err = decodeJSONSnapshot(r, &snap)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, snap.Version)
+	}
+	return crud.restore(snap.Documents)
+}
+
+// decodeJSONSnapshot decodes a JSON snapshot, checking that every document names a key, which may be
+// the empty key written by Save
+func decodeJSONSnapshot(r io.Reader, snap *snapshot) error {
+	var data json.RawMessage
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, snap); err != nil {
+		return err
+	}
+	var keys struct {
+		Documents []struct {
+			Key       *string `json:"key"`
+			KeyBase64 []byte  `json:"key_base64"`
+		} `json:"documents"`
+	}
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	for i, doc := range keys.Documents {
+		if doc.Key == nil && doc.KeyBase64 == nil {
+			return fmt.Errorf("document %d needs a key", i)
+		}
+	}
+	return nil
+}
+
+// restore validates the documents of a snapshot and replaces the documents of the store with them
+func (crud *CRUD) restore(saved []snapshotDoc) error {
+	release, err := crud.admitWrite(context.Background())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	now := crud.getTime()
+	docs := make(map[string]*document, len(saved))
+	for i, sd := range saved {
+		value := []byte(sd.Value)
+		if sd.Binary != nil {
+			value = sd.Binary
+		}
+		key := decodeKey(sd.Key, sd.KeyBase64)
+		if sd.Value != nil && sd.Binary != nil {
+			return fmt.Errorf("%w: document %d has both a value and binary data", ErrInvalidSnapshot, i)
+		}
+		if value == nil {
+			// empty values are binary, and omitted by Save
+			value = []byte{}
+		}
+		if _, ok := docs[key]; ok {
+			return fmt.Errorf("%w: duplicate key %q", ErrInvalidSnapshot, key)
+		}
+		doc := &document{
+			Cas:      sd.Cas,
+			TTL:      sd.Expiry,
+			Value:    value,
+			Datatype: detectDatatype(value),
+			Checksum: checksum(value),
+			Created:  sd.Created,
+			Updated:  sd.Updated,
+		}
+		docs[key] = doc
+	}
+	for key, doc := range docs {
+		if doc.expired(now) {
+			delete(docs, key)
+		}
+	}
+
+	unlock := crud.lockAll()
+	defer unlock()
+	crud.replaceDocs(docs)
+	return nil
+}
@@ -0,0 +1,146 @@
+package crud
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	clock := newManualClock()
+	client := New(WithClock(clock))
+	_ = client.CreateIndex("byName", "name")
+	cas, _ := client.Insert("a", map[string]string{"name": "alice"}, 0)
+	cas, _ = client.Replace("a", map[string]string{"name": "alice"}, cas, 0)
+	_, _ = client.InsertRaw("bin", []byte{0xff, 0x00}, 0)
+	_, _ = client.InsertRaw("empty", nil, 0)
+	_, _ = client.Insert("ttl", 1, 60)
+	_, _ = client.Insert("gone", 1, 1)
+	clock.add(time.Second)
+
+	var buf bytes.Buffer
+	if err := client.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"value":{"name":"alice"}`) {
+		t.Fatal("snapshot mismatch")
+	}
+
+	restored := New(WithClock(clock))
+	_ = restored.CreateIndex("byName", "name")
+	_, _ = restored.Insert("other", 1, 0)
+	if err := restored.Load(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if keys := restored.Keys(); !reflect.DeepEqual(keys, []string{"a", "bin", "empty", "ttl"}) {
+		t.Fatal("keys mismatch")
+	}
+	if diff, _ := client.Diff(restored); !diff.Empty() {
+		t.Fatal("value mismatch")
+	}
+	if meta, err := restored.GetMeta("a"); err != nil || meta.Cas != cas {
+		t.Fatal("cas mismatch")
+	}
+	if meta, _ := restored.GetMeta("bin"); meta.Datatype != DatatypeBinary {
+		t.Fatal("datatype mismatch")
+	}
+	if expiresIn(restored, "ttl") != expiresIn(client, "ttl") {
+		t.Fatal("expiry mismatch")
+	}
+	if docs, _ := restored.LookupIndex("byName", "alice"); len(docs) != 1 {
+		t.Fatal("index mismatch")
+	}
+
+	// documents which expired since the snapshot are dropped
+	clock.add(time.Minute)
+	_ = restored.Load(bytes.NewReader(buf.Bytes()))
+	if restored.Count() != 3 {
+		t.Fatal("count mismatch")
+	}
+}
+
+func TestLoadInvalidSnapshot(t *testing.T) {
+	for _, in := range []string{
+		`not json`,
+		`{"version": 2, "documents": []}`,
+		`{"version": 1, "documents": [{"value": 1}]}`,
+		`{"version": 1, "documents": [{"key": "a", "value": 1, "binary": "AA=="}]}`,
+		`{"version": 1, "documents": [{"key": "a", "value": 1}, {"key": "a", "value": 2}]}`,
+	} {
+		client := New()
+		_, _ = client.Insert("kept", 1, 0)
+		if err := client.Load(strings.NewReader(in)); !errors.Is(err, ErrInvalidSnapshot) {
+			t.Fatalf("error mismatch for %s", in)
+		}
+		if client.Count() != 1 {
+			t.Fatal("count mismatch")
+		}
+	}
+}
+
+func TestSnapshotEmptyKey(t *testing.T) {
+	for _, format := range []SnapshotFormat{SnapshotJSON, SnapshotGob} {
+		client := New()
+		_, _ = client.Insert("", "val", 0)
+		var buf bytes.Buffer
+		if err := client.SaveAs(&buf, format); err != nil {
+			t.Fatal(err)
+		}
+
+		restored := New()
+		if err := restored.LoadAs(&buf, format); err != nil {
+			t.Fatal(err)
+		}
+		var act string
+		if _, err := restored.Get("", &act); err != nil || act != "val" {
+			t.Fatal("results mismatch")
+		}
+	}
+}
+
+func TestGobSnapshot(t *testing.T) {
+	client := New()
+	_ = client.Seeder().Insert(100)
+	cas, _ := client.Upsert("doc::1", "updated", 0)
+	_, _ = client.InsertRaw("bin", []byte{0xff}, 0)
+
+	var buf bytes.Buffer
+	if err := client.SaveAs(&buf, SnapshotGob); err != nil {
+		t.Fatal(err)
+	}
+	if err := New().Load(bytes.NewReader(buf.Bytes())); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatal("error mismatch")
+	}
+
+	restored := New()
+	if err := restored.LoadAs(&buf, SnapshotGob); err != nil {
+		t.Fatal(err)
+	}
+	if diff, _ := client.Diff(restored); !diff.Empty() || restored.Count() != 101 {
+		t.Fatal("value mismatch")
+	}
+	if meta, err := restored.GetMeta("doc::1"); err != nil || meta.Cas != cas {
+		t.Fatal("cas mismatch")
+	}
+}
+
+func benchmarkSnapshot(b *testing.B, format SnapshotFormat) {
+	client := New()
+	_ = client.Seeder().Insert(10000)
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		_ = client.SaveAs(&buf, format)
+		_ = New().LoadAs(&buf, format)
+	}
+}
+
+func BenchmarkSnapshotJSON(b *testing.B) {
+	benchmarkSnapshot(b, SnapshotJSON)
+}
+
+func BenchmarkSnapshotGob(b *testing.B) {
+	benchmarkSnapshot(b, SnapshotGob)
+}
@@ -0,0 +1,44 @@
+package crud
+
+// SnapshotView returns a read-only view of the documents of crud as they are now, which later writes don't
+// change, so that long running scans and exports see a consistent state while the store keeps being mutated.
+// The view's time is frozen when it is taken, so documents expiring afterwards are still live in it.
+//
+// Taking a view briefly locks every shard but copies no document: each shard copies its documents the first
+// time it is written to afterwards, whether or not the view is still in use. Views cost little on stores
+// with many shards, see WithLockStripes, where writes only copy the shards they touch.
+//
+// The view has the client options, views and search indexes of crud. It has no key index, so Range sorts the
+// keys, and no revision history or audit log, so History and the revision reads return ErrHistoryDisabled.
+// Values ejected to the backend, see WithMemoryLimit, are read from it, so reading one whose document was
+// since written to or removed returns ErrValueUnavailable.
+func (crud *CRUD) SnapshotView() *Reader {
+	unlock := crud.lockAll()
+	defer unlock()
+
+	src := crud.db
+	db := &db{
+		shards:     make([]*shard, len(src.shards)),
+		keygen:     src.keygen,
+		casgen:     src.casgen,
+		feed:       newChangeFeed(0),
+		queue:      newOpQueue(0, QueueBlock),
+		indexes:    newIndexSet(),
+		views:      src.views.definitions(),
+		search:     src.search.definitions(),
+		hooks:      newHookSet(),
+		clock:      src.clock,
+		transcoder: src.transcoder,
+		threshold:  src.threshold,
+		defaultTTL: src.defaultTTL,
+		maxTTL:     src.maxTTL,
+		keyRules:   src.keyRules,
+		latency:    src.latency,
+	}
+	db.shift.Store(&timeShift{frozen: true, at: crud.now()})
+	for i, s := range src.shards {
+		s.shared = true
+		db.shards[i] = &shard{docs: s.docs}
+	}
+	return &Reader{crud: &CRUD{db: db, limiter: crud.limiter, reader: true, label: crud.label}}
+}
@@ -0,0 +1,103 @@
+package crud
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSnapshotView(t *testing.T) {
+	clock := newManualClock()
+	client := New(WithClock(clock))
+	casA, _ := client.Insert("a", "one", 0)
+	casB, _ := client.Insert("b", "two", 10)
+
+	view := client.SnapshotView()
+
+	_, _ = client.Replace("a", "uno", casA, 0)
+	_, _ = client.GetAndLock("a", 5, new(string))
+	_, _ = client.Remove("b", casB)
+	_, _ = client.Insert("c", "three", 0)
+	clock.add(20 * time.Second)
+
+	var act string
+	cas, err := view.Get("a", &act)
+	if err != nil || act != "one" || cas != casA {
+		t.Fatal("value mismatch")
+	}
+	// the view's time is frozen, so b hasn't expired in it
+	if _, err := view.Get("b", &act); err != nil || act != "two" {
+		t.Fatal("value mismatch")
+	}
+	if _, err := view.Get("c", &act); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+	if !reflect.DeepEqual(view.Keys(), []string{"a", "b"}) {
+		t.Fatal("keys mismatch")
+	}
+
+	if _, err := client.Get("a", &act); err != nil || act != "uno" {
+		t.Fatal("value mismatch")
+	}
+	if !reflect.DeepEqual(client.Keys(), []string{"a", "c"}) {
+		t.Fatal("keys mismatch")
+	}
+	if _, err := view.History("a"); !reflect.DeepEqual(err, ErrHistoryDisabled) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestSnapshotViewFlush(t *testing.T) {
+	client := NewSharded(4)
+	_, _ = client.Insert("a", 1, 0)
+	view := client.SnapshotView()
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = client.Insert("b", 2, 0)
+	if view.Count() != 1 || client.Count() != 1 {
+		t.Fatal("count mismatch")
+	}
+	if ok, _, _ := view.Exists("b"); ok {
+		t.Fatal("b is visible in the view")
+	}
+}
+
+func TestSnapshotViewConcurrentWrites(t *testing.T) {
+	client := New(WithLockStripes(8))
+	for i := 0; i < 100; i++ {
+		_, _ = client.Insert(strconv.Itoa(i), i, 0)
+	}
+	view := client.SnapshotView()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				key := strconv.Itoa(i)
+				_, _ = client.Upsert(key, -i, 0)
+				_, _ = client.Insert(strconv.Itoa(w*1000+i+1000), i, 0)
+			}
+		}(w)
+	}
+
+	for n := 0; n < 10; n++ {
+		count := 0
+		err := view.ForEach(func(key string, value json.RawMessage) error {
+			if i, _ := strconv.Atoi(key); string(value) != strconv.Itoa(i) {
+				t.Errorf("value of %s changed to %s", key, value)
+			}
+			count++
+			return nil
+		})
+		if err != nil || count != 100 {
+			t.Fatal("count mismatch")
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,105 @@
+// Package sqlitestore provides a crud.Backend persisting documents in a SQLite database, using the
+// pure Go modernc.org/sqlite driver so that no cgo toolchain is needed:
+//
+//	b, err := sqlitestore.Open("crud.sqlite")
+//	...
+//	store, err := crud.NewWithBackend(b)
+package sqlitestore
+
+import (
+	"database/sql"
+
+	"github.com/jacygao/crud"
+	_ "modernc.org/sqlite"
+)
+
+// schema creates the table holding the records. CAS values are stored as their two's complement,
+// since SQLite integers are signed.
+const schema = `CREATE TABLE IF NOT EXISTS documents (
+	key     TEXT PRIMARY KEY,
+	value   BLOB NOT NULL,
+	cas     INTEGER NOT NULL,
+	expiry  INTEGER NOT NULL,
+	created INTEGER NOT NULL,
+	updated INTEGER NOT NULL
+)`
+
+// Backend persists documents in a SQLite database
+type Backend struct {
+	db *sql.DB
+}
+
+// Open opens, creating it when it doesn't exist, the SQLite database at path
+func Open(path string) (*Backend, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// a single connection serialises writes and keeps in-memory databases alive
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Backend{db: db}, nil
+}
+
+// Records calls fn with every persisted record, in key order
+func (b *Backend) Records(fn func(crud.Record) error) error {
+	rows, err := b.db.Query(`SELECT key, value, cas, expiry, created, updated FROM documents ORDER BY key`)
+	if err != nil {
+		return err
+	}
+	var records []crud.Record
+	for rows.Next() {
+		var r crud.Record
+		var cas int64
+		if err := rows.Scan(&r.Key, &r.Value, &cas, &r.Expiry, &r.Created, &r.Updated); err != nil {
+			rows.Close()
+			return err
+		}
+		r.Cas = uint64(cas)
+		records = append(records, r)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	// the rows are read in full first so that fn may use the database
+	for _, r := range records {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Put persists r
+func (b *Backend) Put(r crud.Record) error {
+	value := r.Value
+	if value == nil {
+		value = []byte{}
+	}
+	_, err := b.db.Exec(`INSERT OR REPLACE INTO documents (key, value, cas, expiry, created, updated) VALUES (?, ?, ?, ?, ?, ?)`,
+		r.Key, value, int64(r.Cas), r.Expiry, r.Created, r.Updated)
+	return err
+}
+
+// Delete removes the record of key
+func (b *Backend) Delete(key string) error {
+	_, err := b.db.Exec(`DELETE FROM documents WHERE key = ?`, key)
+	return err
+}
+
+// Clear removes every record
+func (b *Backend) Clear() error {
+	_, err := b.db.Exec(`DELETE FROM documents`)
+	return err
+}
+
+// Close closes the database
+func (b *Backend) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,72 @@
+package sqlitestore
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jacygao/crud"
+)
+
+func TestBackend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crud.sqlite")
+	open := func() *crud.CRUD {
+		b, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store, err := crud.NewWithBackend(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}
+
+	store := open()
+	cas, _ := store.Insert("a", map[string]string{"name": "alice"}, 0)
+	cas, _ = store.Replace("a", map[string]string{"name": "alicia"}, cas, 0)
+	_, _ = store.InsertRaw("bin", []byte{0xff, 0x00}, 0)
+	_, _ = store.Insert("gone", 1, 0)
+	_, _ = store.Remove("gone", 1)
+	if err := store.CloseBackend(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the documents survive reopening the database
+	store = open()
+	if keys := store.Keys(); !reflect.DeepEqual(keys, []string{"a", "bin"}) {
+		t.Fatal("keys mismatch")
+	}
+	var act map[string]string
+	if got, err := store.Get("a", &act); err != nil || got != cas || act["name"] != "alicia" {
+		t.Fatal("value mismatch")
+	}
+	if raw, _, _ := store.GetRaw("bin"); !reflect.DeepEqual(raw, []byte{0xff, 0x00}) {
+		t.Fatal("value mismatch")
+	}
+
+	_ = store.Flush()
+	_ = store.CloseBackend()
+	store = open()
+	defer store.CloseBackend()
+	if store.Count() != 0 {
+		t.Fatal("count mismatch")
+	}
+}
+
+func TestLargeCas(t *testing.T) {
+	b, err := Open(filepath.Join(t.TempDir(), "crud.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	_ = b.Put(crud.Record{Key: "a", Cas: 1<<64 - 1})
+	var records []crud.Record
+	_ = b.Records(func(r crud.Record) error {
+		records = append(records, r)
+		return nil
+	})
+	if len(records) != 1 || records[0].Cas != 1<<64-1 {
+		t.Fatal("record mismatch")
+	}
+}
@@ -0,0 +1,100 @@
+package crud
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Stats describes the documents held by a store and counts the operations applied to it since it was
+// created or last flushed
+type Stats struct {
+	// Documents is the number of live documents
+	Documents int
+	// Expired is the number of expired documents not yet purged
+	Expired int
+	// KeyBytes and ValueBytes are the total sizes of the keys and stored values of every stored document,
+	// including expired ones not yet purged
+	KeyBytes   int64
+	ValueBytes int64
+	// StoredValueBytes is the size of the values as held in memory, which is smaller than ValueBytes
+	// when they are compressed, see WithCompression, or left to the backend, see WithMemoryLimit
+	StoredValueBytes int64
+
+	// Reads counts the documents read by Get and its variants
+	Reads uint64
+	// Inserts, Updates and Removes count the documents stored, changed and removed by writes
+	Inserts uint64
+	Updates uint64
+	Removes uint64
+	// Expirations counts the expired documents purged
+	Expirations uint64
+	// Locks counts the documents locked by GetAndLock
+	Locks uint64
+	// Evictions counts the documents evicted to keep the store within its memory limit, see WithMemoryLimit
+	Evictions uint64
+}
+
+// opCounters counts the operations applied to a store, see Stats
+type opCounters struct {
+	reads       atomic.Uint64
+	inserts     atomic.Uint64
+	updates     atomic.Uint64
+	removes     atomic.Uint64
+	expirations atomic.Uint64
+	locks       atomic.Uint64
+	evictions   atomic.Uint64
+}
+
+// count notes a mutation of type typ
+func (c *opCounters) count(typ EventType) {
+	switch typ {
+	case EventInsert:
+		c.inserts.Add(1)
+	case EventUpdate:
+		c.updates.Add(1)
+	case EventRemove:
+		c.removes.Add(1)
+	case EventExpire:
+		c.expirations.Add(1)
+	case EventEvict:
+		c.evictions.Add(1)
+	}
+}
+
+func (c *opCounters) reset() {
+	for _, n := range []*atomic.Uint64{&c.reads, &c.inserts, &c.updates, &c.removes, &c.expirations, &c.locks, &c.evictions} {
+		n.Store(0)
+	}
+}
+
+// Stats returns the number and size of the documents held by the store and the operation counters.
+// Shards are read in turn, so the sizes aren't a point in time view across shards.
+func (crud *CRUD) Stats() (Stats, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return Stats{}, err
+	}
+	defer release()
+
+	var stats Stats
+	now := crud.getTime()
+	crud.rangeDocs(func(key string, doc *document) {
+		if doc.expired(now) {
+			stats.Expired++
+		} else {
+			stats.Documents++
+		}
+		stats.KeyBytes += int64(len(key))
+		stats.ValueBytes += int64(doc.size())
+		stats.StoredValueBytes += int64(doc.storedSize())
+	})
+	c := &crud.counters
+	stats.Reads = c.reads.Load()
+	stats.Inserts = c.inserts.Load()
+	stats.Updates = c.updates.Load()
+	stats.Removes = c.removes.Load()
+	stats.Expirations = c.expirations.Load()
+	stats.Locks = c.locks.Load()
+	stats.Evictions = c.evictions.Load()
+	return stats, nil
+}
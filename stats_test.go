@@ -0,0 +1,56 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	clock := newManualClock()
+	client := New(WithClock(clock))
+	cas, _ := client.Insert("a", "one", 0)
+	_, _ = client.Insert("bb", "two", 10)
+	_, _ = client.Insert("c", "three", 0)
+	_, _ = client.Replace("a", "uno", cas, 0)
+	cas, _ = client.Get("c", new(string))
+	_, _ = client.Remove("c", cas)
+	_, _ = client.GetAndLock("a", 5, new(string))
+	clock.add(20 * time.Second)
+
+	stats, err := client.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Stats{
+		Documents:        1,
+		Expired:          1,
+		KeyBytes:         3,
+		ValueBytes:       10,
+		StoredValueBytes: 10,
+		Reads:            2,
+		Inserts:          3,
+		Updates:          1,
+		Removes:          1,
+		Locks:            1,
+	}
+	if !reflect.DeepEqual(stats, exp) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	if _, err := client.PurgeExpired(); err != nil {
+		t.Fatal(err)
+	}
+	stats, _ = client.Stats()
+	if stats.Documents != 1 || stats.Expired != 0 || stats.Expirations != 1 || stats.KeyBytes != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	stats, _ = client.Stats()
+	if !reflect.DeepEqual(stats, Stats{}) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
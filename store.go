@@ -0,0 +1,102 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"regexp"
+	"time"
+)
+
+// ReadStore is the set of read operations of a store, implemented by *CRUD and *Reader.
+// Code which only reads can depend on it so that any store, handle or wrapper can be given to it.
+type ReadStore interface {
+	Get(key string, valuePtr interface{}) (uint64, error)
+	GetWithMeta(key string, valuePtr interface{}) (Meta, error)
+	GetIfChanged(key string, knownCas uint64, valuePtr interface{}) (uint64, error)
+	GetWithProjection(key string, valuePtr interface{}, paths ...string) (uint64, error)
+	GetProject(key string, paths []string, valuePtr interface{}) (uint64, error)
+	GetRaw(key string) ([]byte, uint64, error)
+	GetWithExpiry(key string, valuePtr interface{}) (uint64, time.Time, error)
+	Exists(key string) (bool, uint64, error)
+	GetMeta(key string) (Meta, error)
+	LookupIn(key string, specs ...LookupInSpec) (*LookupInResult, error)
+
+	Keys() []string
+	KeysWithPrefix(prefix string) []string
+	KeysMatching(pattern string) ([]string, error)
+	KeysMatchingRegexp(re *regexp.Regexp) []string
+	Count() int
+	All() iter.Seq2[string, json.RawMessage]
+	ForEach(fn func(key string, value json.RawMessage) error) error
+	Find(match func(key string, value json.RawMessage) bool) ([]Document, error)
+	ScanPrefix(prefix string) ([]Document, error)
+	ScanPrefixPage(prefix string, pageSize int, cursor Cursor) (Page, error)
+	Range(startKey, endKey string, limit int) ([]Document, error)
+	RangePage(startKey, endKey string, pageSize int, cursor Cursor) (Page, error)
+
+	QueryField(path string, value interface{}) ([]Document, error)
+	Query(statement string, params ...interface{}) (*QueryResult, error)
+	QueryView(name string, opts ViewOptions) (*ViewResult, error)
+	Search(name string, query SearchQuery, limit int) ([]SearchHit, error)
+	Aggregate(path string, opts AggregateOptions) ([]AggregateResult, error)
+
+	ListRevisions(key string) ([]Revision, error)
+	GetRevision(key string, cas uint64, valuePtr interface{}) error
+	GetAsOf(key string, t time.Time, valuePtr interface{}) (uint64, error)
+	History(key string) ([]HistoryEntry, error)
+}
+
+// Store is the set of document operations of a store, implemented by *CRUD. Code can depend on it
+// rather than on *CRUD so that decorators, such as metrics, logging or fault injection wrappers embedding
+// a Store and overriding some of its methods, and alternate implementations can stand in for the store.
+// Methods configuring the store, such as index and view definitions, hooks, time control and persistence,
+// aren't part of it.
+type Store interface {
+	ReadStore
+
+	GetCtx(ctx context.Context, key string, valuePtr interface{}) (uint64, error)
+	GetMulti(keys []string) []MultiResult
+	GetAndTouch(key string, expiry uint32, valuePtr interface{}) (uint64, error)
+	GetAndTouchWith(key string, valuePtr interface{}, opts ...WriteOption) (uint64, error)
+	GetAndLock(key string, lockTime uint32, valuePtr interface{}) (uint64, error)
+	Unlock(key string, cas uint64) error
+	LookupIndex(name string, value interface{}) ([]Document, error)
+	LookupCompositeIndex(name string, values ...interface{}) ([]Document, error)
+
+	Insert(key string, value interface{}, expiry uint32) (uint64, error)
+	InsertCtx(ctx context.Context, key string, value interface{}, expiry uint32) (uint64, error)
+	InsertWith(key string, value interface{}, opts ...WriteOption) (uint64, error)
+	InsertRaw(key string, value []byte, expiry uint32) (uint64, error)
+	InsertAuto(value interface{}, expiry uint32) (string, uint64, error)
+	Upsert(key string, value interface{}, expiry uint32) (uint64, error)
+	UpsertCtx(ctx context.Context, key string, value interface{}, expiry uint32) (uint64, error)
+	UpsertWith(key string, value interface{}, opts ...WriteOption) (uint64, error)
+	UpsertWithResult(key string, value interface{}, opts ...WriteOption) (UpsertResult, error)
+	UpsertRaw(key string, value []byte, expiry uint32) (uint64, error)
+	UpsertMulti(docs []MultiDoc) []MultiResult
+	Replace(key string, value interface{}, cas uint64, expiry uint32) (uint64, error)
+	ReplaceCtx(ctx context.Context, key string, value interface{}, cas uint64, expiry uint32) (uint64, error)
+	ReplaceWith(key string, value interface{}, cas uint64, opts ...WriteOption) (uint64, error)
+	Remove(key string, cas uint64) (uint64, error)
+	RemoveCtx(ctx context.Context, key string, cas uint64) (uint64, error)
+	RemoveMulti(items []MultiRemove) []MultiResult
+	Touch(key string, cas uint64, expiry uint32) (uint64, error)
+	TouchCtx(ctx context.Context, key string, cas uint64, expiry uint32) (uint64, error)
+	TouchWith(key string, cas uint64, opts ...WriteOption) (uint64, error)
+	Append(key string, data []byte) (uint64, error)
+	Prepend(key string, data []byte) (uint64, error)
+	Increment(key string, delta uint64, initial int64, expiry uint32) (uint64, uint64, error)
+	Decrement(key string, delta uint64, initial int64, expiry uint32) (uint64, uint64, error)
+	MutateIn(key string, cas uint64, specs ...MutateInSpec) (*MutateInResult, error)
+	Begin() *Txn
+
+	Watch(opts ...WatchOption) (*Subscription, error)
+	PurgeExpired() (int, error)
+	Flush() error
+}
+
+var (
+	_ Store     = (*CRUD)(nil)
+	_ ReadStore = (*Reader)(nil)
+)
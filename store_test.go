@@ -0,0 +1,37 @@
+package crud
+
+import (
+	"errors"
+	"testing"
+)
+
+// faultyStore is a Store decorator failing every Get
+type faultyStore struct {
+	Store
+	gets int
+}
+
+func (s *faultyStore) Get(key string, valuePtr interface{}) (uint64, error) {
+	s.gets++
+	return 0, errors.New("injected fault")
+}
+
+func TestStore(t *testing.T) {
+	client := New()
+	var store Store = &faultyStore{Store: client}
+	if _, err := store.Insert("key", "value", 0); err != nil {
+		t.Fatal(err)
+	}
+	var act string
+	if _, err := store.Get("key", &act); err == nil || store.(*faultyStore).gets != 1 {
+		t.Fatal("decorator mismatch")
+	}
+	if _, err := client.Get("key", &act); err != nil || act != "value" {
+		t.Fatal("value mismatch")
+	}
+
+	var reader ReadStore = client.Reader()
+	if reader.Count() != 1 {
+		t.Fatal("count mismatch")
+	}
+}
@@ -0,0 +1,479 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+)
+
+var (
+	// ErrPathNotFound defines the error value returned when a sub-document path doesn't exist in the document
+	ErrPathNotFound = errors.New("sub-document path not found")
+	// ErrPathExists defines the error value returned when a sub-document insert targets a path which already exists
+	ErrPathExists = errors.New("sub-document path already exists")
+	// ErrPathMismatch defines the error value returned when a sub-document path crosses a value of the wrong type
+	ErrPathMismatch = errors.New("sub-document path mismatch")
+)
+
+type lookupOp int
+
+const (
+	lookupGet lookupOp = iota
+	lookupExists
+)
+
+// LookupInSpec is a sub-document read performed by LookupIn
+type LookupInSpec struct {
+	op   lookupOp
+	path string
+}
+
+// GetSpec reads the value at path. An empty path reads the whole document.
+func GetSpec(path string) LookupInSpec {
+	return LookupInSpec{op: lookupGet, path: path}
+}
+
+// ExistsSpec checks whether path exists
+func ExistsSpec(path string) LookupInSpec {
+	return LookupInSpec{op: lookupExists, path: path}
+}
+
+// subdocResult is the outcome of a single sub-document spec
+type subdocResult struct {
+	value json.RawMessage
+	err   error
+}
+
+// LookupInResult holds the outcome of each spec of a LookupIn, in the order of the specs
+type LookupInResult struct {
+	Cas     uint64
+	results []subdocResult
+}
+
+// ContentAt decodes the value read by the spec at idx into valuePtr.
+// It returns ErrPathNotFound when the path doesn't exist.
+func (r *LookupInResult) ContentAt(idx int, valuePtr interface{}) error {
+	res := r.results[idx]
+	if res.err != nil {
+		return res.err
+	}
+	return json.Unmarshal(res.value, valuePtr)
+}
+
+// Exists reports whether the path of the spec at idx exists
+func (r *LookupInResult) Exists(idx int) bool {
+	return r.results[idx].err == nil
+}
+
+// parseSubdocPath parses a sub-document path, where the empty path refers to the whole document
+func parseSubdocPath(path string) ([]pathSegment, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return parsePath(path)
+}
+
+// LookupIn reads several paths of a JSON document in one operation. Missing paths are reported per spec
+// by the result rather than failing the whole lookup.
+func (crud *CRUD) LookupIn(key string, specs ...LookupInSpec) (*LookupInResult, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpLookupIn); err != nil {
+		return nil, err
+	}
+
+	parsed := make([][]pathSegment, len(specs))
+	for i, spec := range specs {
+		if parsed[i], err = parseSubdocPath(spec.path); err != nil {
+			return nil, err
+		}
+	}
+
+	doc, err := crud.fetch(key)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Datatype != DatatypeJSON {
+		return nil, ErrNotJSON
+	}
+	root, err := decodeJSON(doc.value())
+	if err != nil {
+		return nil, err
+	}
+
+	res := &LookupInResult{Cas: doc.reportedCas(crud.getTime()), results: make([]subdocResult, len(specs))}
+	for i, spec := range specs {
+		value, ok := getPath(root, parsed[i])
+		if !ok {
+			res.results[i].err = ErrPathNotFound
+			continue
+		}
+		if spec.op == lookupGet {
+			if res.results[i].value, err = json.Marshal(value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	crud.recordRead(key, doc.size(), doc.Cas)
+	return res, nil
+}
+
+// MutateInSpec is a sub-document mutation performed by MutateIn
+type MutateInSpec struct {
+	path       string
+	value      interface{}
+	createPath bool
+	// insert makes the spec's path name a position within an array rather than a value
+	insert bool
+	// content makes MutateIn return the new value at the path
+	content bool
+	// apply returns the new value at the path given the current one, keep is false to remove it
+	apply func(cur interface{}, exists bool, value interface{}, createPath bool) (next interface{}, keep bool, err error)
+}
+
+// WithCreatePath makes the mutation create the missing objects leading to its path
+func (s MutateInSpec) WithCreatePath() MutateInSpec {
+	s.createPath = true
+	return s
+}
+
+// UpsertSpec sets the value at path, whether or not it exists
+func UpsertSpec(path string, value interface{}) MutateInSpec {
+	return MutateInSpec{path: path, value: value, apply: func(cur interface{}, exists bool, value interface{}, createPath bool) (interface{}, bool, error) {
+		return value, true, nil
+	}}
+}
+
+// InsertSpec sets the value at path, failing with ErrPathExists when it exists
+func InsertSpec(path string, value interface{}) MutateInSpec {
+	return MutateInSpec{path: path, value: value, apply: func(cur interface{}, exists bool, value interface{}, createPath bool) (interface{}, bool, error) {
+		if exists {
+			return nil, false, ErrPathExists
+		}
+		return value, true, nil
+	}}
+}
+
+// ReplaceSpec sets the value at path, failing with ErrPathNotFound when it doesn't exist
+func ReplaceSpec(path string, value interface{}) MutateInSpec {
+	return MutateInSpec{path: path, value: value, apply: func(cur interface{}, exists bool, value interface{}, createPath bool) (interface{}, bool, error) {
+		if !exists {
+			return nil, false, ErrPathNotFound
+		}
+		return value, true, nil
+	}}
+}
+
+// RemoveSpec removes the value at path, failing with ErrPathNotFound when it doesn't exist
+func RemoveSpec(path string) MutateInSpec {
+	return MutateInSpec{path: path, apply: func(cur interface{}, exists bool, value interface{}, createPath bool) (interface{}, bool, error) {
+		if !exists {
+			return nil, false, ErrPathNotFound
+		}
+		return nil, false, nil
+	}}
+}
+
+// updatePath replaces the value at segs within node by the result of apply and returns the updated node.
+// Missing objects leading to the path are created when createPath is set.
+func updatePath(node interface{}, segs []pathSegment, createPath bool,
+	apply func(cur interface{}, exists bool) (interface{}, bool, error)) (interface{}, error) {
+	if len(segs) == 0 {
+		next, keep, err := apply(node, true)
+		if err == nil && !keep {
+			// the document itself can't be removed
+			err = ErrInvalidPath
+		}
+		return next, err
+	}
+
+	seg, last := segs[0], len(segs) == 1
+	if seg.isIndex {
+		arr, ok := node.([]interface{})
+		if !ok {
+			return nil, ErrPathMismatch
+		}
+		i, ok := resolveIndex(seg.index, len(arr))
+		if !last {
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			child, err := updatePath(arr[i], segs[1:], createPath, apply)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = child
+			return arr, nil
+		}
+
+		var cur interface{}
+		if ok {
+			cur = arr[i]
+		}
+		next, keep, err := apply(cur, ok)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// elements can only be added by the array mutations
+			return nil, ErrPathNotFound
+		}
+		if !keep {
+			return append(arr[:i:i], arr[i+1:]...), nil
+		}
+		arr[i] = next
+		return arr, nil
+	}
+
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, ErrPathMismatch
+	}
+	cur, exists := obj[seg.field]
+	if !last {
+		if !exists {
+			if !createPath {
+				return nil, ErrPathNotFound
+			}
+			cur = map[string]interface{}{}
+		}
+		child, err := updatePath(cur, segs[1:], createPath, apply)
+		if err != nil {
+			return nil, err
+		}
+		obj[seg.field] = child
+		return obj, nil
+	}
+
+	next, keep, err := apply(cur, exists)
+	if err != nil {
+		return nil, err
+	}
+	if !keep {
+		delete(obj, seg.field)
+		return obj, nil
+	}
+	obj[seg.field] = next
+	return obj, nil
+}
+
+// MutateIn applies several mutations to paths of a JSON document atomically: either every spec is applied
+// or, when one fails, none is and its error is returned. A cas of zero applies the mutations whatever the
+// document's CAS; otherwise it must match, and only the lock holder may mutate a locked document.
+// The document keeps its expiry.
+func (crud *CRUD) MutateIn(key string, cas uint64, specs ...MutateInSpec) (*MutateInResult, error) {
+	release, err := crud.admitWrite(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpMutateIn); err != nil {
+		return nil, err
+	}
+
+	parsed := make([][]pathSegment, len(specs))
+	values := make([]interface{}, len(specs))
+	for i, spec := range specs {
+		if spec.path == "" {
+			return nil, ErrInvalidPath
+		}
+		if parsed[i], err = parsePath(spec.path); err != nil {
+			return nil, err
+		}
+		if spec.insert && !parsed[i][len(parsed[i])-1].isIndex {
+			return nil, ErrInvalidPath
+		}
+		data, err := json.Marshal(spec.value)
+		if err != nil {
+			return nil, err
+		}
+		if values[i], err = decodeJSON(data); err != nil {
+			return nil, err
+		}
+	}
+
+	s := crud.shardFor(key)
+	s.lock()
+	defer s.mu.Unlock()
+
+	doc, err := crud.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	locked := doc.locked(crud.getTime())
+	if (cas != 0 || locked) && doc.Cas != cas {
+		if locked {
+			return nil, ErrDocumentLocked
+		}
+		return nil, ErrCasMismatch
+	}
+	if doc.Datatype != DatatypeJSON {
+		return nil, ErrNotJSON
+	}
+
+	// mutate a decoded copy, so that a failing spec leaves the document untouched
+	root, err := decodeJSON(doc.value())
+	if err != nil {
+		return nil, err
+	}
+	results := make([]subdocResult, len(specs))
+	for i, spec := range specs {
+		segs, value := parsed[i], values[i]
+		if spec.insert {
+			// the mutation applies to the array holding the position, which is passed along with the values
+			value = []interface{}{segs[len(segs)-1].index, value}
+			segs = segs[:len(segs)-1]
+		}
+		root, err = updatePath(root, segs, spec.createPath, func(cur interface{}, exists bool) (interface{}, bool, error) {
+			next, keep, err := spec.apply(cur, exists, value, spec.createPath)
+			if err == nil && spec.content {
+				results[i].value, err = json.Marshal(next)
+			}
+			return next, keep, err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.Marshal(root)
+	if err != nil {
+		return nil, err
+	}
+	if err := crud.checkSize(data); err != nil {
+		return nil, err
+	}
+	unreserve, err := crud.reserveUnique(map[string][]byte{key: data})
+	if err != nil {
+		return nil, err
+	}
+	defer unreserve()
+
+	doc = doc.set(data)
+	// a mutation by the lock holder releases the lock, like Replace
+	doc.LockedUntil = 0
+	crud.put(key, doc, EventUpdate)
+
+	return &MutateInResult{Cas: doc.Cas, results: results}, nil
+}
+
+// MutateInResult holds the outcome of a MutateIn
+type MutateInResult struct {
+	Cas     uint64
+	results []subdocResult
+}
+
+// ContentAt decodes the value returned by the spec at idx into valuePtr.
+// Only counter specs return a value; the others report ErrPathNotFound.
+func (r *MutateInResult) ContentAt(idx int, valuePtr interface{}) error {
+	res := r.results[idx]
+	if res.value == nil {
+		return ErrPathNotFound
+	}
+	return json.Unmarshal(res.value, valuePtr)
+}
+
+// arrayAt returns the array at a mutated path, which is created when missing and createPath is set
+func arrayAt(cur interface{}, exists, createPath bool) ([]interface{}, error) {
+	if !exists {
+		if !createPath {
+			return nil, ErrPathNotFound
+		}
+		return []interface{}{}, nil
+	}
+	arr, ok := cur.([]interface{})
+	if !ok {
+		return nil, ErrPathMismatch
+	}
+	return arr, nil
+}
+
+// ArrayAppendSpec adds values to the end of the array at path
+func ArrayAppendSpec(path string, values ...interface{}) MutateInSpec {
+	return MutateInSpec{path: path, value: values, apply: func(cur interface{}, exists bool, value interface{}, createPath bool) (interface{}, bool, error) {
+		arr, err := arrayAt(cur, exists, createPath)
+		if err != nil {
+			return nil, false, err
+		}
+		return append(arr, value.([]interface{})...), true, nil
+	}}
+}
+
+// ArrayPrependSpec adds values to the start of the array at path
+func ArrayPrependSpec(path string, values ...interface{}) MutateInSpec {
+	return MutateInSpec{path: path, value: values, apply: func(cur interface{}, exists bool, value interface{}, createPath bool) (interface{}, bool, error) {
+		arr, err := arrayAt(cur, exists, createPath)
+		if err != nil {
+			return nil, false, err
+		}
+		return append(append([]interface{}{}, value.([]interface{})...), arr...), true, nil
+	}}
+}
+
+// ArrayInsertSpec inserts values into an array at the position path ends with, such as "tags[1]".
+// The position may be the array's length to add values to its end.
+func ArrayInsertSpec(path string, values ...interface{}) MutateInSpec {
+	return MutateInSpec{path: path, value: values, insert: true, apply: func(cur interface{}, exists bool, value interface{}, createPath bool) (interface{}, bool, error) {
+		arr, err := arrayAt(cur, exists, false)
+		if err != nil {
+			return nil, false, err
+		}
+		args := value.([]interface{})
+		pos, inserted := args[0].(int), args[1].([]interface{})
+		if pos < 0 || pos > len(arr) {
+			return nil, false, ErrPathNotFound
+		}
+		next := make([]interface{}, 0, len(arr)+len(inserted))
+		next = append(next, arr[:pos]...)
+		next = append(next, inserted...)
+		return append(next, arr[pos:]...), true, nil
+	}}
+}
+
+// ArrayAddUniqueSpec adds value to the end of the array at path unless the array already holds an equal value,
+// in which case it fails with ErrPathExists
+func ArrayAddUniqueSpec(path string, value interface{}) MutateInSpec {
+	return MutateInSpec{path: path, value: value, apply: func(cur interface{}, exists bool, value interface{}, createPath bool) (interface{}, bool, error) {
+		arr, err := arrayAt(cur, exists, createPath)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, elem := range arr {
+			if jsonEqual(elem, value) {
+				return nil, false, ErrPathExists
+			}
+		}
+		return append(arr, value), true, nil
+	}}
+}
+
+// CounterSpec adds delta to the integer at path, which is created with the value delta when missing.
+// The new value is returned by the result's ContentAt. A delta of zero or a result overflowing an
+// int64 fails with ErrInvalidCounter; a value which isn't an integer fails with ErrPathMismatch.
+func CounterSpec(path string, delta int64) MutateInSpec {
+	return MutateInSpec{path: path, value: delta, content: true, apply: func(cur interface{}, exists bool, value interface{}, createPath bool) (interface{}, bool, error) {
+		if delta == 0 {
+			return nil, false, ErrInvalidCounter
+		}
+		if !exists {
+			return json.Number(strconv.FormatInt(delta, 10)), true, nil
+		}
+		num, ok := cur.(json.Number)
+		if !ok {
+			return nil, false, ErrPathMismatch
+		}
+		n, err := strconv.ParseInt(string(num), 10, 64)
+		if err != nil {
+			return nil, false, ErrPathMismatch
+		}
+		if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+			return nil, false, ErrInvalidCounter
+		}
+		return json.Number(strconv.FormatInt(n+delta, 10)), true, nil
+	}}
+}
@@ -0,0 +1,261 @@
+package crud
+
+import (
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestLookupIn(t *testing.T) {
+	client := New()
+	cas, _ := client.Insert("key", map[string]interface{}{
+		"name": "bob",
+		"tags": []string{"a", "b"},
+		"addr": map[string]string{"city": "paris"},
+	}, 0)
+
+	res, err := client.LookupIn("key", GetSpec("addr.city"), ExistsSpec("tags[1]"), GetSpec("age"), ExistsSpec("tags[5]"), GetSpec(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Cas != cas {
+		t.Fatal("cas mismatch")
+	}
+
+	var city string
+	if err := res.ContentAt(0, &city); err != nil || city != "paris" {
+		t.Fatal("content mismatch")
+	}
+	if !res.Exists(1) || res.Exists(2) || res.Exists(3) {
+		t.Fatal("exists mismatch")
+	}
+	var age int
+	if !reflect.DeepEqual(res.ContentAt(2, &age), ErrPathNotFound) {
+		t.Fatal("error mismatch")
+	}
+	var whole map[string]interface{}
+	if err := res.ContentAt(4, &whole); err != nil || whole["name"] != "bob" {
+		t.Fatal("content mismatch")
+	}
+
+	if _, err := client.LookupIn("missing", GetSpec("name")); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.LookupIn("key", GetSpec("a..b")); !reflect.DeepEqual(err, ErrInvalidPath) {
+		t.Fatal("error mismatch")
+	}
+	client.UpsertRaw("raw", []byte("not json"), 0)
+	if _, err := client.LookupIn("raw", GetSpec("name")); !reflect.DeepEqual(err, ErrNotJSON) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestMutateIn(t *testing.T) {
+	client := New()
+	cas, _ := client.Insert("key", map[string]interface{}{
+		"name": "bob",
+		"tags": []string{"a", "b", "c"},
+	}, 0)
+
+	res, err := client.MutateIn("key", cas,
+		UpsertSpec("name", "alice"),
+		InsertSpec("age", 30),
+		ReplaceSpec("tags[0]", "z"),
+		RemoveSpec("tags[-1]"),
+		UpsertSpec("addr.city", "paris").WithCreatePath(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Cas == cas {
+		t.Fatal("cas mismatch")
+	}
+
+	var act map[string]interface{}
+	client.Get("key", &act)
+	exp := map[string]interface{}{
+		"name": "alice",
+		"age":  float64(30),
+		"tags": []interface{}{"z", "b"},
+		"addr": map[string]interface{}{"city": "paris"},
+	}
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatal("value mismatch")
+	}
+
+	// a stale cas is rejected, while zero skips the check
+	if _, err := client.MutateIn("key", cas, UpsertSpec("name", "carol")); !reflect.DeepEqual(err, ErrCasMismatch) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.MutateIn("key", 0, UpsertSpec("name", "carol")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMutateInErrors(t *testing.T) {
+	client := New()
+	cas, _ := client.Insert("key", map[string]interface{}{"name": "bob", "tags": []string{"a"}}, 0)
+
+	tests := []struct {
+		spec MutateInSpec
+		err  error
+	}{
+		{InsertSpec("name", "x"), ErrPathExists},
+		{ReplaceSpec("age", 1), ErrPathNotFound},
+		{RemoveSpec("age"), ErrPathNotFound},
+		{UpsertSpec("addr.city", "paris"), ErrPathNotFound},
+		{UpsertSpec("name.first", "x"), ErrPathMismatch},
+		{UpsertSpec("tags[3]", "x"), ErrPathNotFound},
+		{UpsertSpec("", "x"), ErrInvalidPath},
+	}
+	for _, test := range tests {
+		// the failing spec rolls back the mutation before it
+		_, err := client.MutateIn("key", 0, UpsertSpec("name", "alice"), test.spec)
+		if !reflect.DeepEqual(err, test.err) {
+			t.Fatal("error mismatch")
+		}
+	}
+
+	var act map[string]interface{}
+	actCas, _ := client.Get("key", &act)
+	if actCas != cas || act["name"] != "bob" {
+		t.Fatal("value mismatch")
+	}
+
+	if _, err := client.MutateIn("missing", 0, UpsertSpec("name", "x")); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestMutateInLocked(t *testing.T) {
+	client := New()
+	client.Insert("key", map[string]string{"name": "bob"}, 0)
+
+	var act map[string]string
+	lockCas, _ := client.GetAndLock("key", 10, &act)
+
+	if _, err := client.MutateIn("key", 0, UpsertSpec("name", "alice")); !reflect.DeepEqual(err, ErrDocumentLocked) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.MutateIn("key", lockCas, UpsertSpec("name", "alice")); err != nil {
+		t.Fatal(err)
+	}
+	// the lock holder's mutation releases the lock
+	if _, err := client.MutateIn("key", 0, UpsertSpec("name", "carol")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMutateInArrays(t *testing.T) {
+	client := New()
+	client.Insert("key", map[string]interface{}{"tags": []string{"b", "c"}, "name": "bob"}, 0)
+
+	_, err := client.MutateIn("key", 0,
+		ArrayAppendSpec("tags", "d", "e"),
+		ArrayPrependSpec("tags", "a"),
+		ArrayInsertSpec("tags[2]", "x"),
+		ArrayAddUniqueSpec("tags", "f"),
+		ArrayAppendSpec("nums", 1).WithCreatePath(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var act struct {
+		Tags []string `json:"tags"`
+		Nums []int    `json:"nums"`
+	}
+	client.Get("key", &act)
+	if !reflect.DeepEqual(act.Tags, []string{"a", "b", "x", "c", "d", "e", "f"}) || !reflect.DeepEqual(act.Nums, []int{1}) {
+		t.Fatal("value mismatch")
+	}
+
+	tests := []struct {
+		spec MutateInSpec
+		err  error
+	}{
+		{ArrayAddUniqueSpec("tags", "a"), ErrPathExists},
+		{ArrayAppendSpec("name", "x"), ErrPathMismatch},
+		{ArrayAppendSpec("missing", "x"), ErrPathNotFound},
+		{ArrayInsertSpec("tags[9]", "x"), ErrPathNotFound},
+		{ArrayInsertSpec("tags", "x"), ErrInvalidPath},
+	}
+	for _, test := range tests {
+		if _, err := client.MutateIn("key", 0, test.spec); !reflect.DeepEqual(err, test.err) {
+			t.Fatal("error mismatch")
+		}
+	}
+}
+
+func TestMutateInArrayConcurrent(t *testing.T) {
+	client := New()
+	client.Insert("key", map[string]interface{}{"nums": []int{}}, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := client.MutateIn("key", 0, ArrayAddUniqueSpec("nums", i)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var act struct {
+		Nums []int `json:"nums"`
+	}
+	client.Get("key", &act)
+	if len(act.Nums) != 50 {
+		t.Fatal("length mismatch")
+	}
+}
+
+func TestMutateInCounter(t *testing.T) {
+	client := New()
+	cas, _ := client.Insert("key", map[string]interface{}{"hits": 5, "name": "bob", "ratio": 0.5}, 0)
+
+	res, err := client.MutateIn("key", cas, CounterSpec("hits", 3), UpsertSpec("name", "alice"), CounterSpec("stats.views", -2).WithCreatePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hits, views int64
+	if err := res.ContentAt(0, &hits); err != nil || hits != 8 {
+		t.Fatal("content mismatch")
+	}
+	if err := res.ContentAt(2, &views); err != nil || views != -2 {
+		t.Fatal("content mismatch")
+	}
+	if !reflect.DeepEqual(res.ContentAt(1, &hits), ErrPathNotFound) {
+		t.Fatal("error mismatch")
+	}
+
+	var act struct {
+		Hits  int64 `json:"hits"`
+		Stats struct {
+			Views int64 `json:"views"`
+		} `json:"stats"`
+	}
+	actCas, _ := client.Get("key", &act)
+	if actCas != res.Cas || act.Hits != 8 || act.Stats.Views != -2 {
+		t.Fatal("value mismatch")
+	}
+
+	client.MutateIn("key", 0, UpsertSpec("max", int64(math.MaxInt64)))
+	tests := []struct {
+		spec MutateInSpec
+		err  error
+	}{
+		{CounterSpec("name", 1), ErrPathMismatch},
+		{CounterSpec("ratio", 1), ErrPathMismatch},
+		{CounterSpec("hits", 0), ErrInvalidCounter},
+		{CounterSpec("max", 1), ErrInvalidCounter},
+	}
+	for _, test := range tests {
+		if _, err := client.MutateIn("key", 0, test.spec); !reflect.DeepEqual(err, test.err) {
+			t.Fatal("error mismatch")
+		}
+	}
+}
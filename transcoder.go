@@ -0,0 +1,32 @@
+package crud
+
+import "encoding/json"
+
+// Transcoder encodes values into the bytes stored in a document and decodes them back
+type Transcoder interface {
+	Marshal(value interface{}) ([]byte, error)
+	Unmarshal(data []byte, valuePtr interface{}) error
+}
+
+// JSONTranscoder is the default Transcoder, encoding values with encoding/json
+type JSONTranscoder struct{}
+
+// Marshal encodes value as JSON
+func (JSONTranscoder) Marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// Unmarshal decodes JSON data into valuePtr
+func (JSONTranscoder) Unmarshal(data []byte, valuePtr interface{}) error {
+	return json.Unmarshal(data, valuePtr)
+}
+
+// WithTranscoder sets the Transcoder used to encode and decode document values.
+// Documents which the transcoder doesn't encode as JSON are stored as DatatypeBinary, so path based
+// operations such as GetWithProjection reject them with ErrNotJSON. Serve requires the JSON transcoder,
+// returning ErrUnsupportedTranscoder otherwise.
+func WithTranscoder(t Transcoder) Option {
+	return func(crud *CRUD) {
+		crud.transcoder = t
+	}
+}
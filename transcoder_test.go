@@ -0,0 +1,53 @@
+package crud
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"testing"
+)
+
+type gobTranscoder struct{}
+
+func (gobTranscoder) Marshal(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobTranscoder) Unmarshal(data []byte, valuePtr interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(valuePtr)
+}
+
+func TestTranscoder(t *testing.T) {
+	client := New(WithTranscoder(gobTranscoder{}))
+	exp := testUser{Name: "Alice", Age: 30}
+
+	if _, err := client.Insert("key", exp, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := gobTranscoder{}.Marshal(exp)
+	if !bytes.Equal(stored(client, "key").Value, want) {
+		t.Fatal("value was not encoded by the transcoder")
+	}
+
+	var act testUser
+	meta, err := client.GetWithMeta("key", &act)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if act != exp {
+		t.Fatal("value mismatch")
+	}
+	if meta.Datatype != DatatypeBinary {
+		t.Fatal("datatype mismatch")
+	}
+
+	var projected map[string]interface{}
+	if _, err := client.GetWithProjection("key", &projected, "Name"); !reflect.DeepEqual(err, ErrNotJSON) {
+		t.Fatal("error mismatch")
+	}
+}
@@ -0,0 +1,320 @@
+package crud
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrTransactionDone defines the error value returned when a transaction is used after Commit or Rollback
+	ErrTransactionDone = errors.New("transaction already committed or rolled back")
+	// ErrWriteConflict defines the error value returned by Commit when a document read by the transaction
+	// was changed by another writer since
+	ErrWriteConflict = errors.New("write conflict")
+)
+
+// Txn stages Insert, Replace and Remove operations which are applied atomically by Commit
+// or discarded by Rollback. A Txn is not safe for concurrent use.
+//
+// Transactions are optimistic: Get reads the documents as they were at the first read of the transaction,
+// recording the CAS of every document it reads, and Commit fails with ErrWriteConflict if another writer
+// changed, created or removed one of them since, so that of two concurrent transactions reading and writing
+// the same document the first to commit wins. Staged writes are checked against their CAS as usual, so
+// documents which are written without being read never conflict.
+type Txn struct {
+	crud *CRUD
+	ops  []txnOp
+	done bool
+	// view is the state of the store read by Get, taken at the first read
+	view *Reader
+	// reads holds the CAS of the documents read by Get, zero for documents which didn't exist
+	reads map[string]uint64
+}
+
+// txnOp is an operation staged by a transaction
+type txnOp struct {
+	typ  EventType
+	key  string
+	data []byte
+	cas  uint64
+	opts writeOptions
+}
+
+// Begin starts a transaction on the store
+func (crud *CRUD) Begin() *Txn {
+	return &Txn{crud: crud}
+}
+
+// stage encodes value and records an operation
+func (txn *Txn) stage(op txnOp, value interface{}) error {
+	if txn.done {
+		return ErrTransactionDone
+	}
+	if op.typ != EventRemove {
+		data, err := txn.crud.encode(value)
+		if err != nil {
+			return err
+		}
+		op.data = data
+	}
+	txn.ops = append(txn.ops, op)
+	return nil
+}
+
+// Insert stages the Insert Database Operation
+func (txn *Txn) Insert(key string, value interface{}, expiry uint32) error {
+	return txn.InsertWith(key, value, WithExpiry(expiry))
+}
+
+// InsertWith is Insert configured by WriteOptions
+func (txn *Txn) InsertWith(key string, value interface{}, opts ...WriteOption) error {
+	return txn.stage(txnOp{typ: EventInsert, key: key, opts: newWriteOptions(opts)}, value)
+}
+
+// Replace stages the Replace Database Operation. cas is checked against the document as it is
+// when Commit is called, including the changes of earlier operations of the transaction.
+func (txn *Txn) Replace(key string, value interface{}, cas uint64, expiry uint32) error {
+	return txn.ReplaceWith(key, value, cas, WithExpiry(expiry))
+}
+
+// ReplaceWith is Replace configured by WriteOptions
+func (txn *Txn) ReplaceWith(key string, value interface{}, cas uint64, opts ...WriteOption) error {
+	return txn.stage(txnOp{typ: EventUpdate, key: key, cas: cas, opts: newWriteOptions(opts)}, value)
+}
+
+// Remove stages the Remove Database Operation
+func (txn *Txn) Remove(key string, cas uint64) error {
+	return txn.stage(txnOp{typ: EventRemove, key: key, cas: cas}, nil)
+}
+
+// Get decodes the value of the document stored under key into valuePtr, recording its CAS so that Commit fails
+// with ErrWriteConflict if the document changes before the transaction commits. Every read of the transaction
+// sees the store as it was at its first read, see SnapshotView, so that the documents read are consistent.
+//
+// Get sees the operations staged by the transaction. The CAS it returns for a staged value is the one Commit
+// checks later staged operations against, which is also the CAS the value is stored with under the default
+// SequentialCas. Other CasGenerators store the value with the CAS they generate instead.
+func (txn *Txn) Get(key string, valuePtr interface{}) (uint64, error) {
+	if txn.done {
+		return 0, ErrTransactionDone
+	}
+	var staged *txnOp
+	for i := range txn.ops {
+		if txn.ops[i].key == key {
+			staged = &txn.ops[i]
+		}
+	}
+	if staged == nil {
+		return txn.read(key, valuePtr)
+	}
+	if staged.typ == EventRemove {
+		return 0, ErrKeyNotExist
+	}
+	if err := txn.crud.transcoder.Unmarshal(staged.data, valuePtr); err != nil {
+		return 0, err
+	}
+	// a staged Replace only commits against the CAS it was given, see Commit
+	if staged.typ == EventInsert {
+		return 1, nil
+	}
+	return staged.cas + 1, nil
+}
+
+// read reads key from the view of the transaction and records its CAS in the read set.
+// The real CAS of locked documents is recorded, so that Commit compares it with the stored one.
+func (txn *Txn) read(key string, valuePtr interface{}) (uint64, error) {
+	crud := txn.crud
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpGet); err != nil {
+		return 0, err
+	}
+
+	if txn.view == nil {
+		txn.view = crud.SnapshotView()
+	}
+	view := txn.view.crud
+	doc, err := view.fetch(key)
+	if err == ErrValueUnavailable {
+		// the document was written since the view was taken
+		return 0, ErrWriteConflict
+	}
+	if err != nil && err != ErrKeyNotExist {
+		return 0, err
+	}
+	if txn.reads == nil {
+		txn.reads = make(map[string]uint64)
+	}
+	txn.reads[key] = doc.Cas
+	if err != nil {
+		return 0, err
+	}
+	if err := view.transcoder.Unmarshal(doc.value(), valuePtr); err != nil {
+		return 0, err
+	}
+	crud.recordRead(key, doc.size(), doc.Cas)
+	return doc.reportedCas(view.getTime()), nil
+}
+
+// validateReads returns ErrWriteConflict when a document read by the transaction has changed since.
+// It is called by Commit with the keys of the read set locked.
+func (txn *Txn) validateReads() error {
+	for key, cas := range txn.reads {
+		doc, err := txn.crud.lookup(key)
+		if err != nil && err != ErrKeyNotExist {
+			return err
+		}
+		var cur uint64
+		if doc != nil {
+			cur = doc.Cas
+		}
+		if cur != cas {
+			return ErrWriteConflict
+		}
+	}
+	return nil
+}
+
+// Rollback discards the staged operations
+func (txn *Txn) Rollback() error {
+	if txn.done {
+		return ErrTransactionDone
+	}
+	txn.done = true
+	txn.ops, txn.view = nil, nil
+	return nil
+}
+
+// Commit applies the staged operations in order. If any operation fails, none are applied and
+// its error is returned. Either way the transaction is finished.
+func (txn *Txn) Commit() error {
+	if txn.done {
+		return ErrTransactionDone
+	}
+	txn.done = true
+	txn.view = nil
+
+	crud := txn.crud
+	release, err := crud.admitWrite(context.Background())
+	if err != nil {
+		return err
+	}
+	defer release()
+	if err := txn.crud.delay(context.Background(), OpCommit); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(txn.ops)+len(txn.reads))
+	for _, op := range txn.ops {
+		keys = append(keys, op.key)
+	}
+	for key := range txn.reads {
+		keys = append(keys, key)
+	}
+	unlock := crud.lockKeys(keys)
+	defer unlock()
+
+	if err := txn.validateReads(); err != nil {
+		return err
+	}
+
+	// validate every operation against the documents as earlier operations leave them
+	now := crud.getTime()
+	working := make(map[string]*document)
+	docs := make([]*document, len(txn.ops))
+	for i, op := range txn.ops {
+		cur, seen := working[op.key]
+		if !seen {
+			cur, err = crud.lookup(op.key)
+			if err != nil && err != ErrKeyNotExist {
+				return err
+			}
+		}
+
+		switch op.typ {
+		case EventInsert:
+			if err := crud.validateKey(op.key); err != nil {
+				return err
+			}
+			if cur != nil {
+				if cur.locked(now) {
+					return ErrDocumentLocked
+				}
+				return ErrKeyExist
+			}
+		default:
+			if cur == nil {
+				return ErrKeyNotExist
+			}
+			// only the lock holder may change a locked document
+			if cur.Cas != op.cas {
+				if cur.locked(now) {
+					return ErrDocumentLocked
+				}
+				return ErrCasMismatch
+			}
+			if err := op.opts.precondition(cur); err != nil {
+				return err
+			}
+		}
+
+		var next *document
+		if op.typ != EventRemove {
+			ttl, err := crud.resolveExpiry(op.opts.expiry)
+			if err != nil {
+				return err
+			}
+			if cur != nil && op.opts.preserveExpiry {
+				ttl = cur.TTL
+			}
+			// later operations of the transaction are checked against this CAS, the one put assigns
+			// with the default SequentialCas; other generators store the document with their own
+			next = newDoc(op.data, ttl)
+			next.Cas = 1
+			if cur != nil {
+				next.Cas = cur.Cas + 1
+			}
+		}
+		working[op.key] = next
+		docs[i] = next
+	}
+
+	changes := make(map[string][]byte, len(working))
+	added := 0
+	for key, doc := range working {
+		var value []byte
+		if doc != nil {
+			value = doc.value()
+		}
+		changes[key] = value
+		_, existed := crud.shardFor(key).docs[key]
+		switch {
+		case doc != nil && !existed:
+			added++
+		case doc == nil && existed:
+			added--
+		}
+	}
+	unreserve, err := crud.reserveUnique(changes)
+	if err != nil {
+		return err
+	}
+	defer unreserve()
+	releaseQuota, err := crud.reserveQuota(added)
+	if err != nil {
+		return err
+	}
+	defer releaseQuota()
+
+	for i, op := range txn.ops {
+		if op.typ == EventRemove {
+			crud.del(op.key, EventRemove)
+			continue
+		}
+		crud.put(op.key, docs[i], op.typ)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTxnCommit(t *testing.T) {
+	client := New()
+	_, _ = client.Insert("a", "val-a", 0)
+	_, _ = client.Insert("b", "val-b", 0)
+
+	txn := client.Begin()
+	_ = txn.Insert("c", "val-c", 0)
+	_ = txn.Replace("a", "new-a", 1, 0)
+	_ = txn.Remove("b", 1)
+	// operations see the changes of earlier operations in the transaction
+	_ = txn.Replace("c", "new-c", 1, 0)
+
+	var act string
+	if _, err := client.Get("c", &act); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("staged operation was applied before commit")
+	}
+
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	cas, err := client.Get("a", &act)
+	if err != nil || act != "new-a" || cas != 2 {
+		t.Fatal("replace mismatch")
+	}
+	if _, err := client.Get("b", &act); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("remove mismatch")
+	}
+	cas, err = client.Get("c", &act)
+	if err != nil || act != "new-c" || cas != 2 {
+		t.Fatal("insert mismatch")
+	}
+
+	if err := txn.Commit(); !reflect.DeepEqual(err, ErrTransactionDone) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestTxnCommitFailure(t *testing.T) {
+	client := NewSharded(4)
+	_, _ = client.Insert("a", "val-a", 0)
+
+	txn := client.Begin()
+	_ = txn.Insert("b", "val-b", 0)
+	_ = txn.Replace("a", "new-a", 7, 0)
+
+	if err := txn.Commit(); !reflect.DeepEqual(err, ErrCasMismatch) {
+		t.Fatal("error mismatch")
+	}
+
+	// nothing is applied when an operation fails
+	var act string
+	if _, err := client.Get("b", &act); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("failed transaction was partially applied")
+	}
+	if _, err := client.Get("a", &act); err != nil || act != "val-a" {
+		t.Fatal("value mismatch")
+	}
+}
+
+func TestTxnRollback(t *testing.T) {
+	client := New()
+
+	txn := client.Begin()
+	_ = txn.Insert("key", "val", 0)
+	if err := txn.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	var act string
+	if _, err := client.Get("key", &act); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("rolled back operation was applied")
+	}
+	if err := txn.Insert("key", "val", 0); !reflect.DeepEqual(err, ErrTransactionDone) {
+		t.Fatal("error mismatch")
+	}
+	if err := txn.Commit(); !reflect.DeepEqual(err, ErrTransactionDone) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestTxnWriteOptions(t *testing.T) {
+	client := New()
+	_, _ = client.Insert("a", "val-a", 0)
+
+	at := client.now().Add(time.Minute)
+	txn := client.Begin()
+	_ = txn.InsertWith("b", "val-b", WithRelativeExpiry(45*24*time.Hour))
+	_ = txn.ReplaceWith("a", "new-a", 1, WithAbsoluteExpiry(at))
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if expiresIn(client, "b") < 45*24*time.Hour-time.Second {
+		t.Fatal("expiry mismatch")
+	}
+	if stored(client, "a").TTL != at.UnixNano() {
+		t.Fatal("expiry mismatch")
+	}
+}
+
+func TestTxnReads(t *testing.T) {
+	client := New()
+	_, _ = client.Insert("a", "val-a", 0)
+	casB, _ := client.Insert("b", "val-b", 0)
+
+	txn := client.Begin()
+	var act string
+	if cas, err := txn.Get("b", &act); err != nil || act != "val-b" || cas != casB {
+		t.Fatal("value mismatch")
+	}
+	if _, err := txn.Get("c", &act); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+	if !reflect.DeepEqual(txn.reads, map[string]uint64{"b": casB, "c": 0}) {
+		t.Fatal("read set mismatch")
+	}
+
+	// staged operations are seen
+	_ = txn.Replace("a", "new-a", 1, 0)
+	_ = txn.Insert("d", "val-d", 0)
+	if cas, err := txn.Get("a", &act); err != nil || act != "new-a" || cas != 2 {
+		t.Fatal("value mismatch")
+	}
+	if cas, err := txn.Get("d", &act); err != nil || act != "val-d" || cas != 1 {
+		t.Fatal("value mismatch")
+	}
+	_ = txn.Remove("d", 1)
+	if _, err := txn.Get("d", &act); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if cas, err := client.Get("a", &act); err != nil || act != "new-a" || cas != 2 {
+		t.Fatal("value mismatch")
+	}
+
+	// reads see the store as it was at the first read, and conflict at Commit once it changed
+	txn = client.Begin()
+	_, _ = txn.Get("b", &act)
+	casB, _ = client.Replace("b", "new-b", casB, 0)
+	_, _ = client.Replace("a", "newer-a", 2, 0)
+	if cas, err := txn.Get("a", &act); err != nil || act != "new-a" || cas != 2 {
+		t.Fatal("value mismatch")
+	}
+	if _, err := txn.Get("b", &act); err != nil || act != "val-b" {
+		t.Fatal("value mismatch")
+	}
+	if err := txn.Commit(); !reflect.DeepEqual(err, ErrWriteConflict) {
+		t.Fatal("error mismatch")
+	}
+
+	// locked documents are read with the CAS hidden, but don't conflict
+	lockCas, _ := client.GetAndLock("b", 10, &act)
+	txn = client.Begin()
+	if cas, err := txn.Get("b", &act); err != nil || act != "new-b" || cas != LockedCas {
+		t.Fatal("value mismatch")
+	}
+	if !reflect.DeepEqual(txn.reads, map[string]uint64{"b": lockCas}) {
+		t.Fatal("read set mismatch")
+	}
+	_ = txn.Insert("e", "val-e", 0)
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTxnWriteConflict(t *testing.T) {
+	client := New()
+	_, _ = client.Insert("a", 1, 0)
+
+	first := client.Begin()
+	second := client.Begin()
+	var act int
+	_, _ = first.Get("a", &act)
+	cas, _ := second.Get("a", &act)
+	_ = first.Replace("a", act+1, cas, 0)
+	_ = second.Replace("a", act+1, cas, 0)
+
+	if err := first.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Commit(); !reflect.DeepEqual(err, ErrWriteConflict) {
+		t.Fatal("error mismatch")
+	}
+
+	// a document read as missing conflicts once created, even if the transaction only writes others
+	txn := client.Begin()
+	if _, err := txn.Get("b", &act); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+	_, _ = client.Insert("b", 1, 0)
+	_ = txn.Insert("c", 2, 0)
+	if err := txn.Commit(); !reflect.DeepEqual(err, ErrWriteConflict) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.Get("c", &act); !reflect.DeepEqual(err, ErrKeyNotExist) {
+		t.Fatal("error mismatch")
+	}
+
+	// as does a removed document
+	txn = client.Begin()
+	cas, _ = txn.Get("b", &act)
+	_, _ = client.Remove("b", cas)
+	_ = txn.Replace("a", 5, 2, 0)
+	if err := txn.Commit(); !reflect.DeepEqual(err, ErrWriteConflict) {
+		t.Fatal("error mismatch")
+	}
+
+	// transactions without reads keep checking CAS values only
+	txn = client.Begin()
+	_ = txn.Replace("a", 5, 2, 0)
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTxnStagedCasOtherGenerator(t *testing.T) {
+	client := New(WithHLCCas())
+	cas, _ := client.Insert("a", 1, 0)
+
+	// the CAS Get returns for staged values chains later operations of the transaction
+	txn := client.Begin()
+	_ = txn.Replace("a", 2, cas, 0)
+	var act int
+	staged, _ := txn.Get("a", &act)
+	_ = txn.Replace("a", 3, staged, 0)
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// while the stored CAS comes from the generator
+	stored, err := client.Get("a", &act)
+	if err != nil || act != 3 || stored <= cas {
+		t.Fatal("value mismatch")
+	}
+}
@@ -0,0 +1,212 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrViewNotFound defines the error value returned when querying a view which wasn't defined
+var ErrViewNotFound = errors.New("view not found")
+
+// ViewRow is a row emitted by a view's map function, or a reduced row of a view result.
+// ID is the key of the document which emitted the row, and is empty for reduced rows.
+type ViewRow struct {
+	ID    string
+	Key   interface{}
+	Value interface{}
+}
+
+// ViewMapFunc emits the rows of a view for a live document. doc holds the value produced by the transcoder.
+type ViewMapFunc func(key string, doc json.RawMessage) []ViewRow
+
+// ViewReduceFunc reduces the rows of a view, or of a group of a view, into a single value
+type ViewReduceFunc func(rows []ViewRow) interface{}
+
+// ReduceCount is a ViewReduceFunc counting the rows, like the built-in _count reduce
+func ReduceCount(rows []ViewRow) interface{} {
+	return len(rows)
+}
+
+// ReduceSum is a ViewReduceFunc adding up the numeric values of the rows, like the built-in _sum reduce.
+// Values which aren't numbers are ignored.
+func ReduceSum(rows []ViewRow) interface{} {
+	var sum float64
+	for _, row := range rows {
+		if n, ok := row.Value.(json.Number); ok {
+			f, _ := n.Float64()
+			sum += f
+		}
+	}
+	return sum
+}
+
+// ViewOptions controls the rows returned by QueryView.
+// Keys are compared in JSON collation order: null, booleans, numbers, strings, arrays then objects.
+type ViewOptions struct {
+	// StartKey and EndKey bound the keys of the returned rows, nil leaves that end unbounded.
+	// With Descending, StartKey is the upper bound and EndKey the lower one.
+	StartKey interface{}
+	EndKey   interface{}
+	// ExclusiveEnd leaves rows whose key equals EndKey out of the result
+	ExclusiveEnd bool
+	// Descending returns the rows from the highest key to the lowest
+	Descending bool
+	// Skip and Limit page through the rows, a Limit of zero returns every row
+	Skip  int
+	Limit int
+	// Reduce applies the view's reduce function, when it has one, to the rows in range
+	Reduce bool
+	// Group reduces the rows with equal keys separately rather than all together
+	Group bool
+}
+
+// ViewResult holds the rows returned by QueryView.
+// Keys and values are decoded JSON values: numbers are json.Number, objects map[string]interface{}.
+type ViewResult struct {
+	Rows []ViewRow
+	// TotalRows is the number of rows emitted by the map function before the range was applied
+	TotalRows int
+}
+
+// view is a defined map/reduce view
+type view struct {
+	mapFn    ViewMapFunc
+	reduceFn ViewReduceFunc
+}
+
+// viewSet holds the views defined on the store
+type viewSet struct {
+	mu    sync.RWMutex
+	views map[string]view
+}
+
+func newViewSet() *viewSet {
+	return &viewSet{views: make(map[string]view)}
+}
+
+// DefineView defines, or redefines, a map/reduce view emulating the views of legacy design documents.
+// reduceFn may be nil. A view is computed from the live documents each time it is queried.
+func (crud *CRUD) DefineView(name string, mapFn ViewMapFunc, reduceFn ViewReduceFunc) {
+	crud.views.mu.Lock()
+	defer crud.views.mu.Unlock()
+	crud.views.views[name] = view{mapFn: mapFn, reduceFn: reduceFn}
+}
+
+// normalizeJSON converts a value into the form produced by decodeJSON so it can be collated
+func normalizeJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSON(data)
+}
+
+// QueryView returns the rows of a view within the range of opts, sorted by key and then document key.
+// The map function is called without any lock held, so it may use the store.
+func (crud *CRUD) QueryView(name string, opts ViewOptions) (*ViewResult, error) {
+	release, err := crud.admit(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if err := crud.delay(context.Background(), OpQuery); err != nil {
+		return nil, err
+	}
+
+	crud.views.mu.RLock()
+	v, ok := crud.views.views[name]
+	crud.views.mu.RUnlock()
+	if !ok {
+		return nil, ErrViewNotFound
+	}
+
+	var start, end interface{}
+	if opts.StartKey != nil {
+		if start, err = normalizeJSON(opts.StartKey); err != nil {
+			return nil, err
+		}
+	}
+	if opts.EndKey != nil {
+		if end, err = normalizeJSON(opts.EndKey); err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []ViewRow
+	for _, doc := range crud.scan(nil) {
+		for _, row := range v.mapFn(doc.Key, doc.Value) {
+			if row.ID == "" {
+				row.ID = doc.Key
+			}
+			if row.Key, err = normalizeJSON(row.Key); err != nil {
+				return nil, err
+			}
+			if row.Value, err = normalizeJSON(row.Value); err != nil {
+				return nil, err
+			}
+			rows = append(rows, row)
+		}
+	}
+	res := &ViewResult{TotalRows: len(rows)}
+
+	// order by key then document key, reversing both for a descending query
+	dir := 1
+	if opts.Descending {
+		dir = -1
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if c := jsonCompare(rows[i].Key, rows[j].Key); c != 0 {
+			return c*dir < 0
+		}
+		return (rows[i].ID < rows[j].ID) != opts.Descending
+	})
+
+	inRange := rows[:0]
+	for _, row := range rows {
+		if opts.StartKey != nil && jsonCompare(row.Key, start)*dir < 0 {
+			continue
+		}
+		if opts.EndKey != nil {
+			if c := jsonCompare(row.Key, end) * dir; c > 0 || (c == 0 && opts.ExclusiveEnd) {
+				continue
+			}
+		}
+		inRange = append(inRange, row)
+	}
+	rows = inRange
+
+	if opts.Reduce && v.reduceFn != nil {
+		rows = reduceRows(rows, v.reduceFn, opts.Group)
+	}
+
+	if opts.Skip >= len(rows) {
+		rows = nil
+	} else {
+		rows = rows[opts.Skip:]
+	}
+	if opts.Limit > 0 && opts.Limit < len(rows) {
+		rows = rows[:opts.Limit]
+	}
+	res.Rows = append([]ViewRow{}, rows...)
+	return res, nil
+}
+
+// reduceRows reduces sorted rows into a single row, or one row per key when group is set
+func reduceRows(rows []ViewRow, reduceFn ViewReduceFunc, group bool) []ViewRow {
+	if !group {
+		return []ViewRow{{Value: reduceFn(rows)}}
+	}
+	var reduced []ViewRow
+	for i := 0; i < len(rows); {
+		j := i + 1
+		for j < len(rows) && jsonCompare(rows[i].Key, rows[j].Key) == 0 {
+			j++
+		}
+		reduced = append(reduced, ViewRow{Key: rows[i].Key, Value: reduceFn(rows[i:j])})
+		i = j
+	}
+	return reduced
+}
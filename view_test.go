@@ -0,0 +1,94 @@
+package crud
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// byCity emits a row keyed by city with the age as value for every user document
+func byCity(key string, doc json.RawMessage) []ViewRow {
+	var user struct {
+		City string `json:"city"`
+		Age  int    `json:"age"`
+	}
+	if err := json.Unmarshal(doc, &user); err != nil || user.City == "" {
+		return nil
+	}
+	return []ViewRow{{Key: user.City, Value: user.Age}}
+}
+
+func viewIDs(res *ViewResult) []string {
+	ids := []string{}
+	for _, row := range res.Rows {
+		ids = append(ids, row.ID)
+	}
+	return ids
+}
+
+func TestQueryView(t *testing.T) {
+	client := New()
+	seedQuery(client)
+	client.DefineView("by_city", byCity, ReduceSum)
+
+	tests := []struct {
+		opts ViewOptions
+		exp  []string
+	}{
+		{ViewOptions{}, []string{"u4", "u2", "u1", "u3"}},
+		{ViewOptions{StartKey: "l", EndKey: "paris"}, []string{"u2", "u1", "u3"}},
+		{ViewOptions{StartKey: "l", EndKey: "paris", ExclusiveEnd: true}, []string{"u2"}},
+		{ViewOptions{Descending: true}, []string{"u3", "u1", "u2", "u4"}},
+		{ViewOptions{Descending: true, StartKey: "london", EndKey: "berlin"}, []string{"u2", "u4"}},
+		{ViewOptions{Skip: 1, Limit: 2}, []string{"u2", "u1"}},
+		{ViewOptions{Skip: 9}, []string{}},
+	}
+	for _, test := range tests {
+		res, err := client.QueryView("by_city", test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if act := viewIDs(res); !reflect.DeepEqual(act, test.exp) {
+			t.Fatalf("rows mismatch: %v", act)
+		}
+		if res.TotalRows != 4 {
+			t.Fatal("total mismatch")
+		}
+	}
+
+	res, _ := client.QueryView("by_city", ViewOptions{})
+	if res.Rows[0].Key != "berlin" || res.Rows[1].Value != json.Number("25") {
+		t.Fatal("row mismatch")
+	}
+
+	if _, err := client.QueryView("missing", ViewOptions{}); !reflect.DeepEqual(err, ErrViewNotFound) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestQueryViewReduce(t *testing.T) {
+	client := New()
+	seedQuery(client)
+	client.DefineView("by_city", byCity, ReduceSum)
+
+	res, err := client.QueryView("by_city", ViewOptions{Reduce: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.Rows, []ViewRow{{Value: float64(90)}}) {
+		t.Fatal("rows mismatch")
+	}
+
+	res, _ = client.QueryView("by_city", ViewOptions{Reduce: true, Group: true, StartKey: "london"})
+	exp := []ViewRow{{Key: "london", Value: float64(25)}, {Key: "paris", Value: float64(65)}}
+	if !reflect.DeepEqual(res.Rows, exp) {
+		t.Fatal("rows mismatch")
+	}
+
+	// redefining the view replaces its functions
+	client.DefineView("by_city", byCity, ReduceCount)
+	res, _ = client.QueryView("by_city", ViewOptions{Reduce: true, Group: true})
+	if len(res.Rows) != 3 || res.Rows[2].Value != 2 {
+		t.Fatal("rows mismatch")
+	}
+}
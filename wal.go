@@ -0,0 +1,171 @@
+package crud
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// ErrCorruptWAL defines the error value returned when NewFromWAL reads a write-ahead log it can't replay
+var ErrCorruptWAL = errors.New("corrupt write-ahead log")
+
+// operations of a write-ahead log
+const (
+	walPut   = "put"
+	walDel   = "del"
+	walFlush = "flush"
+)
+
+// walEntry is a line of a write-ahead log. Puts carry the stored document, dels its key.
+type walEntry struct {
+	Op string `json:"op"`
+	snapshotDoc
+}
+
+// wal appends entries to a writer, stopping at the first error
+type wal struct {
+	mu     sync.Mutex
+	w      io.Writer
+	err    error
+	closed bool
+}
+
+func (l *wal) append(e walEntry) {
+	line, err := json.Marshal(e)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed || l.err != nil {
+		return
+	}
+	if err == nil {
+		// a single write keeps entries whole when writes race
+		_, err = l.w.Write(append(line, '\n'))
+	}
+	l.err = err
+}
+
+// WithWAL appends every mutation applied to the store to w as a line of JSON, forming a write-ahead log
+// which NewFromWAL replays. Entries are written unbuffered before each operation returns. Logging stops
+// at the first write error, which SyncWAL and CloseWAL return. Locks aren't logged.
+func WithWAL(w io.Writer) Option {
+	return func(crud *CRUD) {
+		crud.wal = &wal{w: w}
+	}
+}
+
+// NewFromWAL creates a crud database holding the documents of the write-ahead log at path, creating the file
+// when it doesn't exist, and keeps appending its mutations to the file, replacing any WithWAL in opts.
+// An incomplete last entry, left by a process which crashed while writing it, is discarded; any other entry
+// which can't be read returns ErrCorruptWAL. Documents which expired since being logged aren't restored.
+// The log isn't compacted, so it grows with every mutation. Call CloseWAL to close the file.
+func NewFromWAL(path string, opts ...Option) (*CRUD, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	crud, err := replayWAL(f, opts)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return crud, nil
+}
+
+// replayWAL restores the documents of the write-ahead log f into a new store and attaches f as its log
+func replayWAL(f *os.File, opts []Option) (*CRUD, error) {
+	docs := make(map[string]snapshotDoc)
+	var offset int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// line holds the incomplete last entry, if any
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var e walEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("%w: entry at offset %d: %v", ErrCorruptWAL, offset, err)
+		}
+		switch {
+		case e.Op == walPut && (e.Key != "" || e.KeyBase64 != nil || walHasKey(line)):
+			docs[decodeKey(e.Key, e.KeyBase64)] = e.snapshotDoc
+		case e.Op == walDel:
+			delete(docs, decodeKey(e.Key, e.KeyBase64))
+		case e.Op == walFlush:
+			docs = make(map[string]snapshotDoc)
+		default:
+			return nil, fmt.Errorf("%w: invalid entry at offset %d", ErrCorruptWAL, offset)
+		}
+		offset += int64(len(line))
+	}
+	// new entries are appended after the last complete one
+	if err := f.Truncate(offset); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	saved := make([]snapshotDoc, 0, len(docs))
+	for _, sd := range docs {
+		saved = append(saved, sd)
+	}
+	crud := New(opts...)
+	crud.wal = nil
+	if err := crud.restore(saved); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptWAL, err)
+	}
+	crud.wal = &wal{w: f}
+	return crud, nil
+}
+
+// walHasKey reports whether the entry on line names a key, which distinguishes the empty key
+// from a missing one
+func walHasKey(line []byte) bool {
+	var e struct {
+		Key *string `json:"key"`
+	}
+	return json.Unmarshal(line, &e) == nil && e.Key != nil
+}
+
+// SyncWAL commits the write-ahead log to stable storage when its writer has a Sync method, like *os.File,
+// and returns the first error met writing the log. It does nothing without a write-ahead log.
+func (crud *CRUD) SyncWAL() error {
+	if crud.wal == nil {
+		return nil
+	}
+	crud.wal.mu.Lock()
+	defer crud.wal.mu.Unlock()
+	if s, ok := crud.wal.w.(interface{ Sync() error }); ok && crud.wal.err == nil && !crud.wal.closed {
+		crud.wal.err = s.Sync()
+	}
+	return crud.wal.err
+}
+
+// CloseWAL stops logging mutations and closes the write-ahead log when its writer is an io.Closer,
+// like the file opened by NewFromWAL, returning the first error met writing or closing the log.
+// It does nothing without a write-ahead log.
+func (crud *CRUD) CloseWAL() error {
+	if crud.wal == nil {
+		return nil
+	}
+	crud.wal.mu.Lock()
+	defer crud.wal.mu.Unlock()
+	if crud.wal.closed {
+		return crud.wal.err
+	}
+	crud.wal.closed = true
+	if c, ok := crud.wal.w.(io.Closer); ok {
+		if err := c.Close(); crud.wal.err == nil {
+			crud.wal.err = err
+		}
+	}
+	return crud.wal.err
+}
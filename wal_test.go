@@ -0,0 +1,103 @@
+package crud
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWAL(t *testing.T) {
+	var buf bytes.Buffer
+	client := New(WithWAL(&buf))
+	cas, _ := client.Insert("a", 1, 0)
+	cas, _ = client.Replace("a", 2, cas, 0)
+	_, _ = client.Remove("a", cas)
+	_ = client.Flush()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[1], `{"op":"put","key":"a","value":2,"cas":2`) || lines[3] != `{"op":"flush","key":"","cas":0}` {
+		t.Fatal("log mismatch")
+	}
+	if err := client.CloseWAL(); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = client.Insert("b", 1, 0)
+	if len(strings.Split(strings.TrimSpace(buf.String()), "\n")) != 4 {
+		t.Fatal("log mismatch")
+	}
+}
+
+func TestNewFromWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crud.wal")
+	client, err := NewFromWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = client.Seeder().Insert(3)
+	_, _ = client.Remove("doc::0", 1)
+	_, _ = client.UpsertRaw("bin", []byte{0xff}, 0)
+	var v interface{}
+	lockCas, _ := client.GetAndLock("doc::1", 5, &v)
+	if err := client.CloseWAL(); err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate a crash while an entry was written
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	_, _ = f.WriteString(`{"op":"put","key":"torn"`)
+	f.Close()
+
+	restored, err := NewFromWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := restored.Keys(); !reflect.DeepEqual(keys, []string{"bin", "doc::1", "doc::2"}) {
+		t.Fatal("keys mismatch")
+	}
+	if diff, _ := client.Diff(restored); !diff.Empty() {
+		t.Fatal("value mismatch")
+	}
+	// the CAS handed out by the lock survives, the lock doesn't
+	if meta, _ := restored.GetMeta("doc::1"); meta.Cas != lockCas || meta.Locked {
+		t.Fatal("meta mismatch")
+	}
+
+	// mutations keep being appended after the discarded entry
+	_, _ = restored.Insert("c", 1, 0)
+	_ = restored.CloseWAL()
+	again, err := NewFromWAL(path)
+	if err != nil || again.Count() != 4 {
+		t.Fatal("count mismatch")
+	}
+	_ = again.CloseWAL()
+
+	_ = os.WriteFile(path, []byte("{\"op\":\"put\"}\n"), 0o644)
+	if _, err := NewFromWAL(path); !errors.Is(err, ErrCorruptWAL) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestNewFromWALEmptyKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crud.wal")
+	client, err := NewFromWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = client.Insert("", "val", 0)
+	if err := client.CloseWAL(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := NewFromWAL(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.CloseWAL()
+	var act string
+	if _, err := restored.Get("", &act); err != nil || act != "val" {
+		t.Fatal("results mismatch")
+	}
+}
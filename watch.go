@@ -0,0 +1,351 @@
+package crud
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrInvalidResumeToken defines the error value returned when a resume token was not issued by this store
+	ErrInvalidResumeToken = errors.New("invalid resume token")
+	// ErrResumeTokenExpired defines the error value returned when events after a resume token are no longer retained
+	ErrResumeTokenExpired = errors.New("resume token expired")
+)
+
+// defaultChangeLogLimit is the number of events retained for resuming subscriptions
+const defaultChangeLogLimit = 10000
+
+// EventType describes the kind of mutation carried by an Event
+type EventType int
+
+const (
+	// EventInsert is published when a new document is stored
+	EventInsert EventType = iota + 1
+	// EventUpdate is published when an existing document's value or expiry changes
+	EventUpdate
+	// EventRemove is published when a document is removed
+	EventRemove
+	// EventExpire is published when an expired document is purged
+	EventExpire
+	// EventEvict is published when a document is evicted to keep the store within its memory limit
+	EventEvict
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventInsert:
+		return "insert"
+	case EventUpdate:
+		return "update"
+	case EventRemove:
+		return "remove"
+	case EventExpire:
+		return "expire"
+	case EventEvict:
+		return "evict"
+	}
+	return "unknown"
+}
+
+// ResumeToken identifies a position in the change feed.
+// Tokens are opaque and only valid for the store that issued them.
+type ResumeToken string
+
+// Event describes a single mutation of the store
+type Event struct {
+	// Seq is the position of the event in the change feed, starting at 1
+	Seq  uint64
+	Type EventType
+	Key  string
+	Cas  uint64
+	// Value holds the encoded document after the mutation, nil for removals
+	Value []byte
+}
+
+// Token returns a ResumeToken which resumes a subscription immediately after this event
+func (e Event) Token() ResumeToken {
+	return ResumeToken(strconv.FormatUint(e.Seq, 10))
+}
+
+// WatchOption configures a subscription created by Watch
+type WatchOption func(*watchOptions)
+
+type watchOptions struct {
+	from   ResumeToken
+	resume bool
+	// match selects the keys whose events are delivered, nil selects every key
+	match func(key string) bool
+	err   error
+}
+
+// filter narrows the keys selected by the options to those also satisfying match
+func (o *watchOptions) filter(match func(key string) bool) {
+	if prev := o.match; prev != nil {
+		o.match = func(key string) bool {
+			return prev(key) && match(key)
+		}
+		return
+	}
+	o.match = match
+}
+
+// ResumeFrom replays every retained event published after token before following live changes.
+// An empty token replays the whole retained change log.
+func ResumeFrom(token ResumeToken) WatchOption {
+	return func(o *watchOptions) {
+		o.from = token
+		o.resume = true
+	}
+}
+
+// WatchPrefix only delivers the events of keys starting with prefix
+func WatchPrefix(prefix string) WatchOption {
+	return func(o *watchOptions) {
+		o.filter(func(key string) bool {
+			return strings.HasPrefix(key, prefix)
+		})
+	}
+}
+
+// WatchPattern only delivers the events of keys matching the glob pattern, see KeysMatching.
+// A malformed pattern makes Watch return ErrInvalidPattern.
+func WatchPattern(pattern string) WatchOption {
+	return func(o *watchOptions) {
+		re, err := globRegexp(pattern)
+		if err != nil {
+			o.err = err
+			return
+		}
+		o.filter(re.MatchString)
+	}
+}
+
+// WithChangeLogLimit sets the number of events retained for resuming subscriptions
+func WithChangeLogLimit(limit int) Option {
+	return func(crud *CRUD) {
+		crud.feed.limit = limit
+	}
+}
+
+// Subscription delivers events published by the store in order
+type Subscription struct {
+	feed    *changeFeed
+	match   func(key string) bool
+	events  chan Event
+	done    chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	pending []Event
+	notify  chan struct{}
+	// draining is set by drain once no further events can be enqueued
+	draining bool
+}
+
+// Events returns the channel events are delivered on. The channel is closed by Close.
+func (sub *Subscription) Events() <-chan Event {
+	return sub.events
+}
+
+// Close stops the subscription. Events not yet received are discarded.
+func (sub *Subscription) Close() {
+	sub.once.Do(func() {
+		sub.feed.unsubscribe(sub)
+		close(sub.done)
+	})
+}
+
+// drain stops the subscription once the events already published have been delivered,
+// closing the events channel after them
+func (sub *Subscription) drain() {
+	sub.feed.unsubscribe(sub)
+	sub.mu.Lock()
+	sub.draining = true
+	sub.mu.Unlock()
+
+	select {
+	case sub.notify <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue buffers events for delivery without blocking the publisher
+func (sub *Subscription) enqueue(events ...Event) {
+	if sub.match != nil {
+		var matched []Event
+		for _, e := range events {
+			if sub.match(e.Key) {
+				matched = append(matched, e)
+			}
+		}
+		if events = matched; len(events) == 0 {
+			return
+		}
+	}
+
+	sub.mu.Lock()
+	sub.pending = append(sub.pending, events...)
+	sub.mu.Unlock()
+
+	select {
+	case sub.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers buffered events until the subscription is closed
+func (sub *Subscription) run() {
+	defer close(sub.events)
+	for {
+		sub.mu.Lock()
+		batch := sub.pending
+		sub.pending = nil
+		draining := sub.draining
+		sub.mu.Unlock()
+
+		for _, e := range batch {
+			select {
+			case sub.events <- e:
+			case <-sub.done:
+				return
+			}
+		}
+		if draining {
+			return
+		}
+
+		select {
+		case <-sub.notify:
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+// changeFeed sequences mutations and fans them out to subscriptions
+type changeFeed struct {
+	mu    sync.Mutex
+	seq   uint64
+	log   []Event
+	limit int
+	subs  map[*Subscription]struct{}
+}
+
+func newChangeFeed(limit int) *changeFeed {
+	return &changeFeed{
+		limit: limit,
+		subs:  make(map[*Subscription]struct{}),
+	}
+}
+
+// publish assigns the next sequence number to a mutation and delivers it to every subscription
+func (f *changeFeed) publish(typ EventType, key string, cas uint64, value []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.seq++
+	e := Event{Seq: f.seq, Type: typ, Key: key, Cas: cas, Value: value}
+
+	if f.limit > 0 {
+		f.log = append(f.log, e)
+		if len(f.log) > f.limit {
+			// trimmed events are released once append next reallocates the backing array
+			f.log = f.log[len(f.log)-f.limit:]
+		}
+	}
+
+	for sub := range f.subs {
+		sub.enqueue(e)
+	}
+}
+
+// subscribe registers a new subscription, replaying retained events after the resume token when requested
+func (f *changeFeed) subscribe(o watchOptions) (*Subscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sub := &Subscription{
+		feed:   f,
+		match:  o.match,
+		events: make(chan Event),
+		done:   make(chan struct{}),
+		notify: make(chan struct{}, 1),
+	}
+
+	if o.resume {
+		after := uint64(0)
+		if o.from != "" {
+			seq, err := strconv.ParseUint(string(o.from), 10, 64)
+			if err != nil || seq > f.seq {
+				return nil, ErrInvalidResumeToken
+			}
+
+			oldest := f.seq + 1
+			if len(f.log) > 0 {
+				oldest = f.log[0].Seq
+			}
+			if seq+1 < oldest {
+				return nil, ErrResumeTokenExpired
+			}
+			after = seq
+		}
+
+		for _, e := range f.log {
+			if e.Seq > after && (o.match == nil || o.match(e.Key)) {
+				sub.pending = append(sub.pending, e)
+			}
+		}
+	}
+
+	f.subs[sub] = struct{}{}
+	go sub.run()
+	return sub, nil
+}
+
+// truncate discards the retained events. The sequence is advanced past them so that every token
+// issued before the truncation reports ErrResumeTokenExpired.
+func (f *changeFeed) truncate() {
+	f.mu.Lock()
+	f.log = nil
+	f.seq++
+	f.mu.Unlock()
+}
+
+func (f *changeFeed) unsubscribe(sub *Subscription) {
+	f.mu.Lock()
+	delete(f.subs, sub)
+	f.mu.Unlock()
+}
+
+// Watch subscribes to mutations of the store. By default only changes made after the call are delivered;
+// use ResumeFrom with the Token of the last processed event to continue a previous subscription
+// without missing or duplicating events. WatchPrefix and WatchPattern restrict the events to some keys,
+// which lets a cache invalidate the entries it holds. Close unsubscribes.
+func (crud *CRUD) Watch(opts ...WatchOption) (*Subscription, error) {
+	var o watchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.err != nil {
+		return nil, o.err
+	}
+	return crud.feed.subscribe(o)
+}
+
+// StreamFrom subscribes to mutations like a DCP stream: it replays the retained events whose sequence number
+// is above seqno, then follows live changes. Every mutation is assigned the next sequence number, so a
+// consumer restarting from the Seq of the last event it processed neither misses nor repeats any.
+// A seqno older than the retained change log returns ErrResumeTokenExpired, and one above HighSeqno
+// returns ErrInvalidResumeToken.
+func (crud *CRUD) StreamFrom(seqno uint64, opts ...WatchOption) (*Subscription, error) {
+	from := ResumeFrom(ResumeToken(strconv.FormatUint(seqno, 10)))
+	return crud.Watch(append([]WatchOption{from}, opts...)...)
+}
+
+// HighSeqno returns the sequence number of the latest mutation, zero before the first one
+func (crud *CRUD) HighSeqno() uint64 {
+	crud.feed.mu.Lock()
+	defer crud.feed.mu.Unlock()
+	return crud.feed.seq
+}
@@ -0,0 +1,186 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func nextEvent(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case e := <-sub.Events():
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return Event{}
+}
+
+func TestWatch(t *testing.T) {
+	client := New()
+	sub, err := client.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	cas, _ := client.Insert("key", "val", 0)
+	_, _ = client.Upsert("key", "val2", 0)
+	_, _ = client.Remove("key", cas+1)
+
+	for _, exp := range []EventType{EventInsert, EventUpdate, EventRemove} {
+		e := nextEvent(t, sub)
+		if e.Type != exp {
+			t.Fatalf("expected %s event, got %s", exp, e.Type)
+		}
+		if e.Key != "key" {
+			t.Fatal("key mismatch")
+		}
+	}
+}
+
+func TestWatchResume(t *testing.T) {
+	client := New()
+	sub, _ := client.Watch()
+
+	_, _ = client.Insert("a", 1, 0)
+	_, _ = client.Insert("b", 2, 0)
+	last := nextEvent(t, sub)
+	sub.Close()
+
+	// mutations made while disconnected must be delivered on resume
+	_, _ = client.Insert("c", 3, 0)
+
+	resumed, err := client.Watch(ResumeFrom(last.Token()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close()
+
+	for _, exp := range []string{"b", "c"} {
+		if e := nextEvent(t, resumed); e.Key != exp {
+			t.Fatalf("expected event for %q, got %q", exp, e.Key)
+		}
+	}
+
+	_, _ = client.Insert("d", 4, 0)
+	if e := nextEvent(t, resumed); e.Key != "d" {
+		t.Fatalf("expected event for %q, got %q", "d", e.Key)
+	}
+}
+
+func TestWatchResumeTokenErrors(t *testing.T) {
+	client := New(WithChangeLogLimit(2))
+	sub, _ := client.Watch()
+	defer sub.Close()
+
+	_, _ = client.Insert("a", 1, 0)
+	first := nextEvent(t, sub)
+	_, _ = client.Insert("b", 2, 0)
+	_, _ = client.Insert("c", 3, 0)
+	_, _ = client.Insert("d", 4, 0)
+
+	_, err := client.Watch(ResumeFrom(first.Token()))
+	if !reflect.DeepEqual(err, ErrResumeTokenExpired) {
+		t.Fatal("error mismatch")
+	}
+
+	_, err = client.Watch(ResumeFrom("garbage"))
+	if !reflect.DeepEqual(err, ErrInvalidResumeToken) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestWatchFilter(t *testing.T) {
+	client := New()
+	cas, _ := client.Insert("user::0", "old", 0)
+
+	sub, err := client.Watch(WatchPrefix("user::"), ResumeFrom(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	pattern, err := client.Watch(WatchPattern("*::1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pattern.Close()
+
+	client.Insert("order::1", "o", 0)
+	client.Upsert("user::1", "u", 0)
+	client.Remove("user::0", cas)
+
+	// the replayed event is filtered like the live ones
+	if e := nextEvent(t, sub); e.Key != "user::0" || e.Type != EventInsert {
+		t.Fatal("event mismatch")
+	}
+	if e := nextEvent(t, sub); e.Key != "user::1" || e.Type != EventInsert || string(e.Value) != `"u"` {
+		t.Fatal("event mismatch")
+	}
+	if e := nextEvent(t, sub); e.Key != "user::0" || e.Type != EventRemove {
+		t.Fatal("event mismatch")
+	}
+
+	if e := nextEvent(t, pattern); e.Key != "order::1" {
+		t.Fatal("event mismatch")
+	}
+	if e := nextEvent(t, pattern); e.Key != "user::1" {
+		t.Fatal("event mismatch")
+	}
+
+	// closing unsubscribes
+	pattern.Close()
+	client.Upsert("user::1", "u2", 0)
+	if _, open := <-pattern.Events(); open {
+		t.Fatal("event mismatch")
+	}
+
+	if _, err := client.Watch(WatchPattern("[oops")); !reflect.DeepEqual(err, ErrInvalidPattern) {
+		t.Fatal("error mismatch")
+	}
+}
+
+func TestStreamFrom(t *testing.T) {
+	client := New(WithChangeLogLimit(3))
+	if client.HighSeqno() != 0 {
+		t.Fatal("seqno mismatch")
+	}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		client.Insert(key, "v", 0)
+	}
+	if client.HighSeqno() != 4 {
+		t.Fatal("seqno mismatch")
+	}
+
+	stream, err := client.StreamFrom(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	client.Insert("e", "v", 0)
+
+	for _, exp := range []string{"c", "d", "e"} {
+		e := nextEvent(t, stream)
+		if e.Key != exp {
+			t.Fatal("event mismatch")
+		}
+		if e.Seq != uint64(exp[0]-'a'+1) {
+			t.Fatal("seqno mismatch")
+		}
+	}
+
+	// the first event is no longer retained
+	if _, err := client.StreamFrom(0); !reflect.DeepEqual(err, ErrResumeTokenExpired) {
+		t.Fatal("error mismatch")
+	}
+	if _, err := client.StreamFrom(9); !reflect.DeepEqual(err, ErrInvalidResumeToken) {
+		t.Fatal("error mismatch")
+	}
+
+	filtered, _ := client.StreamFrom(3, WatchPrefix("e"))
+	defer filtered.Close()
+	if e := nextEvent(t, filtered); e.Key != "e" {
+		t.Fatal("event mismatch")
+	}
+}
@@ -0,0 +1,185 @@
+package crud
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrWebhookURL defines the error value returned when a webhook is started without a URL
+var ErrWebhookURL = errors.New("webhook URL required")
+
+const (
+	defaultWebhookQueueSize  = 1000
+	defaultWebhookRetries    = 3
+	defaultWebhookRetryDelay = 100 * time.Millisecond
+)
+
+// WebhookOptions configures a webhook started by StartWebhook
+type WebhookOptions struct {
+	// URL is the endpoint mutation events are POSTed to
+	URL string
+	// Client sends the requests, http.DefaultClient when nil
+	Client *http.Client
+	// QueueSize is the number of events buffered while the endpoint is slow, 1000 when zero.
+	// Events arriving while the queue is full are dropped.
+	QueueSize int
+	// MaxRetries is the number of times a failed delivery is retried, 3 when zero and none when negative
+	MaxRetries int
+	// RetryDelay is the wait before the first retry, doubled for each further one, 100ms when zero
+	RetryDelay time.Duration
+	// Watch restricts the events sent, for instance with WatchPrefix
+	Watch []WatchOption
+}
+
+// WebhookEvent is the JSON body POSTed for each mutation
+type WebhookEvent struct {
+	Seq  uint64 `json:"seq"`
+	Type string `json:"type"`
+	Key  string `json:"key"`
+	// KeyBase64 holds the key in place of Key when it isn't valid UTF-8
+	KeyBase64 []byte `json:"key_base64,omitempty"`
+	Cas       uint64 `json:"cas"`
+	// Value holds the document after the mutation when it is JSON
+	Value json.RawMessage `json:"value,omitempty"`
+	// Binary holds the document after the mutation when it isn't JSON
+	Binary []byte `json:"binary,omitempty"`
+}
+
+// WebhookStats counts the events handled by a webhook
+type WebhookStats struct {
+	Delivered uint64
+	// Failed counts the events still failing after every retry
+	Failed uint64
+	// Dropped counts the events which arrived while the queue was full
+	Dropped uint64
+}
+
+// Webhook POSTs the store's mutation events to an HTTP endpoint, in order, until closed
+type Webhook struct {
+	opts  WebhookOptions
+	sub   *Subscription
+	queue chan Event
+	done  sync.WaitGroup
+
+	delivered atomic.Uint64
+	failed    atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// StartWebhook starts POSTing every mutation made from now on to opts.URL as a WebhookEvent.
+// A response other than 2xx counts as a failed delivery.
+func (crud *CRUD) StartWebhook(opts WebhookOptions) (*Webhook, error) {
+	if opts.URL == "" {
+		return nil, ErrWebhookURL
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultWebhookQueueSize
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = defaultWebhookRetries
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = defaultWebhookRetryDelay
+	}
+
+	sub, err := crud.Watch(opts.Watch...)
+	if err != nil {
+		return nil, err
+	}
+	w := &Webhook{opts: opts, sub: sub, queue: make(chan Event, opts.QueueSize)}
+	w.done.Add(2)
+	go w.receive()
+	go w.send()
+	return w, nil
+}
+
+// receive moves events from the subscription into the queue until the subscription is closed
+func (w *Webhook) receive() {
+	defer w.done.Done()
+	defer close(w.queue)
+	for e := range w.sub.Events() {
+		select {
+		case w.queue <- e:
+		default:
+			w.dropped.Add(1)
+		}
+	}
+}
+
+// send delivers the queued events until the queue is closed and drained
+func (w *Webhook) send() {
+	defer w.done.Done()
+	for e := range w.queue {
+		if w.deliver(e) {
+			w.delivered.Add(1)
+		} else {
+			w.failed.Add(1)
+		}
+	}
+}
+
+// deliver POSTs an event, retrying with exponential backoff, and reports whether it succeeded
+func (w *Webhook) deliver(e Event) bool {
+	body := WebhookEvent{Seq: e.Seq, Type: e.Type.String(), Cas: e.Cas}
+	body.Key, body.KeyBase64 = encodeKey(e.Key)
+	if e.Value != nil {
+		if json.Valid(e.Value) {
+			body.Value = e.Value
+		} else {
+			body.Binary = e.Value
+		}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return false
+	}
+
+	delay := w.opts.RetryDelay
+	for attempt := 0; ; attempt++ {
+		if w.post(data) == nil {
+			return true
+		}
+		if attempt >= w.opts.MaxRetries {
+			return false
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (w *Webhook) post(data []byte) error {
+	resp, err := w.opts.Client.Post(w.opts.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// Stats returns the number of events delivered, failed and dropped so far
+func (w *Webhook) Stats() WebhookStats {
+	return WebhookStats{
+		Delivered: w.delivered.Load(),
+		Failed:    w.failed.Load(),
+		Dropped:   w.dropped.Load(),
+	}
+}
+
+// Close stops following mutations and returns once the events of the mutations made before it
+// have been delivered or failed
+func (w *Webhook) Close() {
+	w.sub.drain()
+	w.done.Wait()
+}
@@ -0,0 +1,88 @@
+package crud
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookServer records the events POSTed to it, failing the first failures requests
+func webhookServer(failures int) (*httptest.Server, func() []WebhookEvent) {
+	var (
+		mu     sync.Mutex
+		events []WebhookEvent
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var e WebhookEvent
+		json.NewDecoder(r.Body).Decode(&e)
+		events = append(events, e)
+	}))
+	return srv, func() []WebhookEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]WebhookEvent(nil), events...)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	srv, received := webhookServer(2)
+	defer srv.Close()
+
+	client := New()
+	hook, err := client.StartWebhook(WebhookOptions{URL: srv.URL, RetryDelay: time.Millisecond, Watch: []WatchOption{WatchPrefix("user::")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cas, _ := client.Insert("user::1", map[string]string{"name": "bob"}, 0)
+	client.Insert("order::1", "ignored", 0)
+	client.UpsertRaw("user::2", []byte{0xff}, 0)
+	client.Remove("user::1", cas)
+	hook.Close()
+
+	exp := []WebhookEvent{
+		{Seq: 1, Type: "insert", Key: "user::1", Cas: 1, Value: json.RawMessage(`{"name":"bob"}`)},
+		{Seq: 3, Type: "insert", Key: "user::2", Cas: 1, Binary: []byte{0xff}},
+		{Seq: 4, Type: "remove", Key: "user::1", Cas: 1},
+	}
+	if act := received(); !reflect.DeepEqual(act, exp) {
+		t.Fatalf("events mismatch: %+v", act)
+	}
+	if hook.Stats() != (WebhookStats{Delivered: 3}) {
+		t.Fatal("stats mismatch")
+	}
+}
+
+func TestWebhookFailures(t *testing.T) {
+	srv, received := webhookServer(3)
+	defer srv.Close()
+
+	client := New()
+	hook, _ := client.StartWebhook(WebhookOptions{URL: srv.URL, RetryDelay: time.Millisecond, MaxRetries: 1})
+	client.Insert("a", "v", 0)
+	client.Insert("b", "v", 0)
+	hook.Close()
+
+	// the first event fails twice, the second fails once then succeeds on retry
+	if act := received(); len(act) != 1 || act[0].Key != "b" {
+		t.Fatal("events mismatch")
+	}
+	if hook.Stats() != (WebhookStats{Delivered: 1, Failed: 1}) {
+		t.Fatal("stats mismatch")
+	}
+
+	if _, err := client.StartWebhook(WebhookOptions{}); !reflect.DeepEqual(err, ErrWebhookURL) {
+		t.Fatal("error mismatch")
+	}
+}